@@ -0,0 +1,330 @@
+package cereal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The tags of the maps with a single entry that stand for the values of MessagePack and CBOR
+// that a document has no type for, as in {$bin:"AQI=}. Integers that are not written with the
+// smallest width, or that do not fit in an int, are tagged with their width, as in {$uint16:i5}.
+const (
+	// binaryBlobTag marks a binary blob, written in base64.
+	binaryBlobTag = "$bin"
+	// binaryRootTag holds a root value other than a map, since a document is always a map.
+	binaryRootTag = "$value"
+)
+
+// binaryIntTag matches the tags of integers, capturing whether they are unsigned and their
+// width in bits.
+var binaryIntTag = regexp.MustCompile(`^\$(u?)int(8|16|32|64)$`)
+
+// maxBinaryDepth limits how deeply decoded values may be nested.
+const maxBinaryDepth = 10000
+
+// binaryReader reads the bytes of a MessagePack or CBOR value.
+type binaryReader struct {
+	data []byte
+	pos  int
+}
+
+// next returns the following n bytes.
+func (r *binaryReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.pos) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+func (r *binaryReader) byte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+// uint reads a big-endian unsigned integer of the given number of bytes.
+func (r *binaryReader) uint(size int) (uint64, error) {
+	b, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n, nil
+}
+
+// length checks that a collection with n entries of at least size bytes each fits in the
+// rest of the data, so that a corrupt length is not allocated.
+func (r *binaryReader) length(n uint64, size uint64) (int, error) {
+	if n > uint64(len(r.data)-r.pos)/size {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+// binaryInt returns a decoded integer, given as decimal text, as an int if it was written
+// with the smallest width and fits in an int, and tagged with its width otherwise.
+func binaryInt(text string, tag string, smallest bool) any {
+	i, err := strconv.ParseInt(text, 10, 32)
+	if err == nil && smallest {
+		return int(i)
+	}
+	if err == nil {
+		return singleEntryMap(tag, int(i))
+	}
+	return singleEntryMap(tag, text)
+}
+
+// wideInt returns a TOML or YAML integer as an int if it fits in one, and otherwise tagged as
+// a 64 bit integer, as in {$int64:"5000000000}.
+func wideInt(i int64) any {
+	return binaryInt(strconv.FormatInt(i, 10), "$int64", true)
+}
+
+// taggedInt64 returns the integer of a value tagged by wideInt.
+func taggedInt64(value any) (int64, bool) {
+	tag, inner, ok := binaryTag(value)
+	if !ok || tag != "$int64" {
+		return 0, false
+	}
+
+	n, err := binaryTagInt(inner, nil)
+	if err != nil || !n.IsInt64() {
+		return 0, false
+	}
+	return n.Int64(), true
+}
+
+// binaryMap wraps a decoded map whose only key looks like a tag, so that it is not mistaken
+// for one.
+func binaryMap(m *OrderedMap) *OrderedMap {
+	if m.Len() == 1 && strings.HasPrefix(m.Keys()[0], "$") {
+		return singleEntryMap(typedJSONMapTag, m)
+	}
+	return m
+}
+
+// binaryDocument returns a decoded value as a serialized document.
+func binaryDocument(value any) ([]byte, error) {
+	doc, ok := value.(*OrderedMap)
+	if tag, _, isTag := binaryTag(value); !ok || (isTag && tag != typedJSONMapTag) {
+		doc = singleEntryMap(binaryRootTag, value)
+	}
+
+	return serializeParsed(doc)
+}
+
+// binaryKey returns the key of a decoded map entry, which must be a string.
+func binaryKey(key any, path []string) (string, error) {
+	s, ok := key.(string)
+	if !ok {
+		return "", fmt.Errorf("%v: map keys must be strings, not %v", strings.Join(path, "."), binaryTypeName(key))
+	}
+	return s, nil
+}
+
+func binaryTypeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case *OrderedMap:
+		return "a tagged value or a map"
+	case []any:
+		return "an array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// binaryEncoder writes the values of MessagePack or CBOR.
+type binaryEncoder interface {
+	writeNull()
+	writeBool(b bool)
+	// writeInt writes an integer with the smallest width.
+	writeInt(i int64)
+	// writeSizedInt writes an integer with the given width, if it fits.
+	writeSizedInt(n *big.Int, unsigned bool, bits int) error
+	writeFloat32(f float32)
+	writeFloat64(f float64)
+	writeString(s string)
+	writeBytes(b []byte)
+	writeArrayHeader(n int)
+	writeMapHeader(n int)
+	// writeTagged writes a tagged value that only one of the formats has.
+	writeTagged(tag string, value any, path []string) error
+}
+
+// encodeBinaryDocument writes a document, unwrapping a root value other than a map.
+func encodeBinaryDocument(e binaryEncoder, doc any) error {
+	valueType, _ := valueTypeOf(doc)
+	if valueType != Map {
+		return fmt.Errorf("<root>: expected a map")
+	}
+
+	if tag, value, ok := binaryTag(doc); ok && tag == binaryRootTag {
+		return encodeBinary(e, value, []string{"<root>", tag})
+	}
+	return encodeBinary(e, doc, []string{"<root>"})
+}
+
+// binaryTag returns the tag and the value of a map with a single entry whose key starts
+// with $.
+func binaryTag(value any) (string, any, bool) {
+	valueType, _ := valueTypeOf(value)
+	if valueType != Map {
+		return "", nil, false
+	}
+
+	children := queryChildren(value)
+	if len(children) != 1 || !strings.HasPrefix(children[0].key, "$") {
+		return "", nil, false
+	}
+	return children[0].key, children[0].value, true
+}
+
+func encodeBinary(e binaryEncoder, value any, path []string) error {
+	switch v := value.(type) {
+	case nil:
+		e.writeNull()
+		return nil
+	case bool:
+		e.writeBool(v)
+		return nil
+	case int:
+		e.writeInt(int64(v))
+		return nil
+	case float32:
+		e.writeFloat32(v)
+		return nil
+	case float64:
+		e.writeFloat64(v)
+		return nil
+	case string:
+		e.writeString(v)
+		return nil
+	case []byte:
+		e.writeBytes(v)
+		return nil
+	case RawValue:
+		if len(v) == 1 && v[0] == typeMarkers[Null] {
+			e.writeNull()
+			return nil
+		}
+	}
+
+	valueType, _ := valueTypeOf(value)
+	switch valueType {
+	case Array:
+		children := queryChildren(value)
+		e.writeArrayHeader(len(children))
+		for _, c := range children {
+			err := encodeBinary(e, c.value, appendPath(path, c.key))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case Map:
+		if tag, tagged, ok := binaryTag(value); ok {
+			return encodeBinaryTag(e, tag, tagged, appendPath(path, tag))
+		}
+		return encodeBinaryMap(e, value, path)
+	}
+
+	return fmt.Errorf("%v: unsupported value of type %T", strings.Join(path, "."), value)
+}
+
+func encodeBinaryMap(e binaryEncoder, value any, path []string) error {
+	children := queryChildren(value)
+	e.writeMapHeader(len(children))
+	for _, c := range children {
+		e.writeString(c.key)
+		err := encodeBinary(e, c.value, appendPath(path, c.key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func encodeBinaryTag(e binaryEncoder, tag string, value any, path []string) error {
+	if tag == typedJSONMapTag {
+		valueType, _ := valueTypeOf(value)
+		if valueType != Map {
+			return fmt.Errorf("%v: expected a map", strings.Join(path, "."))
+		}
+		return encodeBinaryMap(e, value, path)
+	}
+
+	if tag == binaryBlobTag {
+		b, err := binaryBlob(value, path)
+		if err != nil {
+			return err
+		}
+		e.writeBytes(b)
+		return nil
+	}
+
+	if m := binaryIntTag.FindStringSubmatch(tag); m != nil {
+		n, err := binaryTagInt(value, path)
+		if err != nil {
+			return err
+		}
+
+		bits, _ := strconv.Atoi(m[2])
+		err = e.writeSizedInt(n, m[1] == "u", bits)
+		if err != nil {
+			return fmt.Errorf("%v: %w", strings.Join(path, "."), err)
+		}
+		return nil
+	}
+
+	return e.writeTagged(tag, value, path)
+}
+
+// binaryBlob decodes the base64 text of a blob.
+func binaryBlob(value any, path []string) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%v: expected a base64 string", strings.Join(path, "."))
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", strings.Join(path, "."), err)
+	}
+	return b, nil
+}
+
+// binaryTagInt returns the integer of a tagged value, which is an int or the decimal text of
+// an integer that does not fit in an int.
+func binaryTagInt(value any, path []string) (*big.Int, error) {
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v)), nil
+	case string:
+		n, ok := new(big.Int).SetString(v, 10)
+		if ok {
+			return n, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%v: expected an integer but got %v", strings.Join(path, "."), value)
+}
+
+// blobValue returns a tagged blob for decoded bytes.
+func blobValue(b []byte) *OrderedMap {
+	return singleEntryMap(binaryBlobTag, base64.StdEncoding.EncodeToString(b))
+}
@@ -0,0 +1,497 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The tags of the CBOR values that MessagePack has no equivalent for.
+const (
+	// cborTagTag marks a tagged data item, written as the tag number and the item, as in
+	// {$tag:[i1,i1700000000]}.
+	cborTagTag = "$tag"
+	// cborSimpleTag marks a simple value other than false, true and null, such as undefined.
+	cborSimpleTag = "$simple"
+	// cborHalfTag marks a half-precision float, written as a float32.
+	cborHalfTag = "$f16"
+)
+
+// ConvertCBOR reads a single CBOR data item from the provided io.Reader and returns it as a
+// serialized document, using the same tags as ConvertMsgPack. A single-precision float
+// becomes a float32, a double-precision float a float64, and a half-precision float is tagged
+// as {$f16:f1.5}. An integer becomes an int if its argument was written in the smallest form
+// and it fits in an int. Any other integer is tagged with the width of its argument, as in
+// {$uint32:i5} or {$int64:"-5000000000}, where $int stands for a negative integer.
+//
+// Tagged data items become {$tag:[<number>,<item>]} and simple values other than false, true
+// and null, such as undefined, become {$simple:<number>}. Strings and collections of
+// indefinite length are read in full, and written back with a definite length. Keys must be
+// strings.
+func ConvertCBOR(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &binaryReader{data: data}
+	value, err := decodeCBOR(r, []string{"<root>"}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if value == cborBreak {
+		return nil, fmt.Errorf("<root>: unexpected break")
+	}
+	if r.pos < len(data) {
+		return nil, fmt.Errorf("unexpected data after the CBOR data item")
+	}
+
+	return binaryDocument(value)
+}
+
+// cborBreak is returned by decodeCBOR for the break that ends an item of indefinite length.
+var cborBreak = &struct{}{}
+
+func decodeCBOR(r *binaryReader, path []string, depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("%v: values are nested too deeply", strings.Join(path, "."))
+	}
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	major, info := b>>5, b&0x1f
+	if major == 7 {
+		return decodeCBORSimple(r, info, path)
+	}
+	if info == 31 {
+		return decodeCBORIndefinite(r, major, path, depth)
+	}
+
+	arg, size, err := cborArgument(r, info, path)
+	if err != nil {
+		return nil, err
+	}
+	smallest := size == 0 || arg >= 24 && arg >= 1<<(8*size/2)
+
+	switch major {
+	case 0:
+		return binaryInt(strconv.FormatUint(arg, 10), fmt.Sprintf("$uint%v", 8*size), smallest), nil
+	case 1:
+		n := new(big.Int).SetUint64(arg)
+		n.Neg(n.Add(n, big.NewInt(1)))
+		return binaryInt(n.String(), fmt.Sprintf("$int%v", 8*size), smallest), nil
+	case 2:
+		blob, err := r.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return blobValue(blob), nil
+	case 3:
+		s, err := r.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.Valid(s) {
+			return nil, fmt.Errorf("%v: invalid UTF-8 in a text string", strings.Join(path, "."))
+		}
+		return string(s), nil
+	case 4:
+		length, err := r.length(arg, 1)
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]any, length)
+		for i := range result {
+			result[i], err = decodeCBORItem(r, appendPath(path, strconv.Itoa(i)), depth)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	case 5:
+		length, err := r.length(arg, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		m := NewOrderedMap()
+		for range length {
+			err = decodeCBOREntry(r, m, path, depth)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return binaryMap(m), nil
+	}
+
+	// major type 6
+	item, err := decodeCBORItem(r, path, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	var number any = strconv.FormatUint(arg, 10)
+	if arg <= math.MaxInt32 {
+		number = int(arg)
+	}
+	return singleEntryMap(cborTagTag, []any{number, item}), nil
+}
+
+// decodeCBORItem decodes a data item that is not a break.
+func decodeCBORItem(r *binaryReader, path []string, depth int) (any, error) {
+	value, err := decodeCBOR(r, path, depth+1)
+	if err == nil && value == cborBreak {
+		return nil, fmt.Errorf("%v: unexpected break", strings.Join(path, "."))
+	}
+	return value, err
+}
+
+func decodeCBOREntry(r *binaryReader, m *OrderedMap, path []string, depth int) error {
+	key, err := decodeCBORItem(r, path, depth)
+	if err != nil {
+		return err
+	}
+	keyString, err := binaryKey(key, path)
+	if err != nil {
+		return err
+	}
+
+	value, err := decodeCBORItem(r, appendPath(path, keyString), depth)
+	if err != nil {
+		return err
+	}
+	m.Set(keyString, value)
+	return nil
+}
+
+// cborArgument reads the argument of an initial byte, returning the number of bytes it took
+// after the initial byte.
+func cborArgument(r *binaryReader, info byte, path []string) (uint64, int, error) {
+	if info < 24 {
+		return uint64(info), 0, nil
+	}
+	if info > 27 {
+		return 0, 0, fmt.Errorf("%v: invalid additional information %v", strings.Join(path, "."), info)
+	}
+
+	size := 1 << (info - 24)
+	arg, err := r.uint(size)
+	return arg, size, err
+}
+
+func decodeCBORSimple(r *binaryReader, info byte, path []string) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22:
+		return nil, nil
+	case 24:
+		v, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		if v < 32 {
+			return nil, fmt.Errorf("%v: invalid simple value %v", strings.Join(path, "."), v)
+		}
+		return singleEntryMap(cborSimpleTag, int(v)), nil
+	case 25:
+		u, err := r.uint(2)
+		return singleEntryMap(cborHalfTag, halfToFloat32(uint16(u))), err
+	case 26:
+		u, err := r.uint(4)
+		return math.Float32frombits(uint32(u)), err
+	case 27:
+		u, err := r.uint(8)
+		return math.Float64frombits(u), err
+	case 31:
+		return cborBreak, nil
+	}
+
+	if info < 24 {
+		return singleEntryMap(cborSimpleTag, int(info)), nil
+	}
+	return nil, fmt.Errorf("%v: invalid additional information %v", strings.Join(path, "."), info)
+}
+
+// decodeCBORIndefinite decodes a string or a collection of indefinite length, which ends
+// with a break.
+func decodeCBORIndefinite(r *binaryReader, major byte, path []string, depth int) (any, error) {
+	switch major {
+	case 2, 3:
+		buf := bytes.Buffer{}
+		for {
+			b, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			if b == 0xff {
+				break
+			}
+			if b>>5 != major || b&0x1f == 31 {
+				return nil, fmt.Errorf("%v: invalid chunk of a string of indefinite length", strings.Join(path, "."))
+			}
+
+			n, _, err := cborArgument(r, b&0x1f, path)
+			if err != nil {
+				return nil, err
+			}
+			chunk, err := r.next(n)
+			if err != nil {
+				return nil, err
+			}
+			if major == 3 && !utf8.Valid(chunk) {
+				return nil, fmt.Errorf("%v: invalid UTF-8 in a text string", strings.Join(path, "."))
+			}
+			buf.Write(chunk)
+		}
+
+		if major == 2 {
+			return blobValue(buf.Bytes()), nil
+		}
+		return buf.String(), nil
+	case 4:
+		result := []any{}
+		for {
+			value, err := decodeCBOR(r, appendPath(path, strconv.Itoa(len(result))), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if value == cborBreak {
+				return result, nil
+			}
+			result = append(result, value)
+		}
+	case 5:
+		m := NewOrderedMap()
+		for {
+			if r.pos < len(r.data) && r.data[r.pos] == 0xff {
+				r.pos++
+				return binaryMap(m), nil
+			}
+
+			err := decodeCBOREntry(r, m, path, depth)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%v: invalid indefinite length for major type %v", strings.Join(path, "."), major)
+}
+
+// halfToFloat32 converts a half-precision float, which a float32 represents exactly.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exponent := uint32(h>>10) & 0x1f
+	fraction := uint32(h & 0x3ff)
+
+	switch exponent {
+	case 0:
+		f := float32(math.Ldexp(float64(fraction), -24))
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | fraction<<13)
+	}
+	return math.Float32frombits(sign | (exponent+127-15)<<23 | fraction<<13)
+}
+
+// float32ToHalf converts a float32 to a half-precision float, returning false if it cannot
+// be represented exactly.
+func float32ToHalf(f float32) (uint16, bool) {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	abs := math.Abs(float64(f))
+
+	switch {
+	case math.IsNaN(float64(f)):
+		return sign | 0x7e00, true
+	case math.IsInf(float64(f), 0):
+		return sign | 0x7c00, true
+	case abs < math.Ldexp(1, -14):
+		// zero and the subnormal halves are multiples of 2^-24
+		m := math.Ldexp(abs, 24)
+		return sign | uint16(m), m == math.Trunc(m)
+	}
+
+	exponent := int(bits>>23&0xff) - 127
+	fraction := bits & 0x7fffff
+	if exponent > 15 || fraction&0x1fff != 0 {
+		return 0, false
+	}
+	return sign | uint16(exponent+15)<<10 | uint16(fraction>>13), true
+}
+
+// MarshalCBOR returns a document, such as the result of ParseOrdered, as CBOR, reading the
+// tags written by ConvertCBOR. Integers that are not tagged are written with the smallest
+// argument, float32 values as single-precision and float64 values as double-precision floats.
+func MarshalCBOR(doc any) ([]byte, error) {
+	e := &cborEncoder{}
+	err := encodeBinaryDocument(e, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+type cborEncoder struct {
+	buf bytes.Buffer
+}
+
+// writeHead writes an initial byte with the smallest form of the argument.
+func (e *cborEncoder) writeHead(major byte, arg uint64) {
+	switch {
+	case arg < 24:
+		e.buf.WriteByte(major<<5 | byte(arg))
+	case arg <= math.MaxUint8:
+		e.writeSizedHead(major, arg, 1)
+	case arg <= math.MaxUint16:
+		e.writeSizedHead(major, arg, 2)
+	case arg <= math.MaxUint32:
+		e.writeSizedHead(major, arg, 4)
+	default:
+		e.writeSizedHead(major, arg, 8)
+	}
+}
+
+// writeSizedHead writes an initial byte followed by an argument of the given number of bytes.
+func (e *cborEncoder) writeSizedHead(major byte, arg uint64, size int) {
+	info := byte(24)
+	for s := 1; s < size; s *= 2 {
+		info++
+	}
+
+	e.buf.WriteByte(major<<5 | info)
+	for i := size - 1; i >= 0; i-- {
+		e.buf.WriteByte(byte(arg >> (8 * i)))
+	}
+}
+
+func (e *cborEncoder) writeNull() {
+	e.buf.WriteByte(0xf6)
+}
+
+func (e *cborEncoder) writeBool(b bool) {
+	if b {
+		e.buf.WriteByte(0xf5)
+	} else {
+		e.buf.WriteByte(0xf4)
+	}
+}
+
+func (e *cborEncoder) writeInt(i int64) {
+	if i >= 0 {
+		e.writeHead(0, uint64(i))
+	} else {
+		e.writeHead(1, uint64(-1-i))
+	}
+}
+
+func (e *cborEncoder) writeSizedInt(n *big.Int, unsigned bool, bits int) error {
+	major := byte(0)
+	arg := new(big.Int).Set(n)
+	if n.Sign() < 0 {
+		major = 1
+		arg.Neg(arg.Add(arg, big.NewInt(1)))
+	}
+
+	if unsigned && major == 1 {
+		return fmt.Errorf("%v does not fit in a uint%v", n, bits)
+	}
+	if arg.BitLen() > bits {
+		return fmt.Errorf("%v does not fit in an argument of %v bits", n, bits)
+	}
+
+	e.writeSizedHead(major, arg.Uint64(), bits/8)
+	return nil
+}
+
+func (e *cborEncoder) writeFloat32(f float32) {
+	e.writeSizedHead(7, uint64(math.Float32bits(f)), 4)
+}
+
+func (e *cborEncoder) writeFloat64(f float64) {
+	e.writeSizedHead(7, math.Float64bits(f), 8)
+}
+
+func (e *cborEncoder) writeString(s string) {
+	e.writeHead(3, uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+func (e *cborEncoder) writeBytes(b []byte) {
+	e.writeHead(2, uint64(len(b)))
+	e.buf.Write(b)
+}
+
+func (e *cborEncoder) writeArrayHeader(n int) {
+	e.writeHead(4, uint64(n))
+}
+
+func (e *cborEncoder) writeMapHeader(n int) {
+	e.writeHead(5, uint64(n))
+}
+
+func (e *cborEncoder) writeTagged(tag string, value any, path []string) error {
+	switch tag {
+	case cborTagTag:
+		tagged, ok := value.([]any)
+		if !ok || len(tagged) != 2 {
+			return fmt.Errorf("%v: expected a tag number and a value", strings.Join(path, "."))
+		}
+		number, err := binaryTagInt(tagged[0], appendPath(path, "0"))
+		if err != nil {
+			return err
+		}
+		if number.Sign() < 0 || !number.IsUint64() {
+			return fmt.Errorf("%v: invalid tag number %v", strings.Join(path, "."), number)
+		}
+
+		e.writeHead(6, number.Uint64())
+		return encodeBinary(e, tagged[1], appendPath(path, "1"))
+	case cborSimpleTag:
+		v, ok := value.(int)
+		switch {
+		case !ok || v < 0 || v > math.MaxUint8 || (v >= 24 && v < 32):
+			return fmt.Errorf("%v: invalid simple value %v", strings.Join(path, "."), value)
+		case v < 24:
+			e.buf.WriteByte(0xe0 | byte(v))
+		default:
+			e.buf.Write([]byte{0xf8, byte(v)})
+		}
+		return nil
+	case cborHalfTag:
+		var f float32
+		switch v := value.(type) {
+		case float32:
+			f = v
+		case float64:
+			f = float32(v)
+		default:
+			return fmt.Errorf("%v: expected a float but got %v", strings.Join(path, "."), value)
+		}
+
+		h, ok := float32ToHalf(f)
+		if !ok {
+			return fmt.Errorf("%v: %v cannot be represented as a half-precision float", strings.Join(path, "."), value)
+		}
+		e.writeSizedHead(7, uint64(h), 2)
+		return nil
+	}
+
+	return fmt.Errorf("%v: unsupported tag", strings.Join(path, "."))
+}
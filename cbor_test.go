@@ -0,0 +1,163 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConvertCBOR(t *testing.T) {
+	tests := map[string]string{
+		// {"a":1,"b":-1,"c":null,"d":true,"e":"hi"}
+		"a56161016162206163f66164f56165626869": `1{a:i1,b:i-1,c:n,d:b1,e:"hi}`,
+		// {"f":1.5 as float32,"g":2.5 as float64,"h":1.5 as half}
+		"a36166fa3fc000006167fb40040000000000006168f93e00": `1{f:f1.5,g:d2.5,h:{$f16:f1.5}}`,
+		// {"u":5 with a 2 byte argument,"n":-25,"m":-1 with a 1 byte argument,"x":2^64-1}
+		"a46175190005616e3818616d380061781bffffffffffffffff": `1{u:{$uint16:i5},n:i-25,m:{$int8:i-1},x:{$uint64:"18446744073709551615}}`,
+		// {"b":h'0102',"t":1(1700000000),"s":undefined,"z":simple(255)}
+		"a461624201026174c11a6553f1006173f7617af8ff": `1{b:{$bin:"AQI=},t:{$tag:[i1,i1700000000]},s:{$simple:i23},z:{$simple:i255}}`,
+		// {_ "a":[_ 1,2],"s":(_ "ab" "c")}
+		"bf61619f0102ff61737f6261626163ffff": `1{a:[i1,i2],s:"abc}`,
+		// [1,2]
+		"820102": `1{$value:[i1,i2]}`,
+		// {"$k":1}
+		"a162246b01": `1{$map:{$k:i1}}`,
+	}
+
+	for input, expected := range tests {
+		data, _ := hex.DecodeString(input)
+		result, err := ConvertCBOR(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", input, err)
+			continue
+		}
+		if string(result) != expected {
+			t.Errorf("expected %v for %v but got %v", expected, input, string(result))
+		}
+	}
+}
+
+func TestConvertCBOR_Errors(t *testing.T) {
+	tests := map[string]string{
+		"a10102":             "<root>: map keys must be strings, not int",
+		"ff":                 "<root>: unexpected break",
+		"81ff":               "<root>.0: unexpected break",
+		"1c":                 "<root>: invalid additional information 28",
+		"a1616162c328":       "<root>.a: invalid UTF-8 in a text string",
+		"f818":               "<root>: invalid simple value 24",
+		"7f4101ff":           "<root>: invalid chunk of a string of indefinite length",
+		"0102":               "unexpected data after the CBOR data item",
+		"9bffffffffffffffff": "unexpected EOF",
+		"a161":               "unexpected EOF",
+	}
+
+	for input, expected := range tests {
+		data, _ := hex.DecodeString(input)
+		_, err := ConvertCBOR(bytes.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q for %v but got %v", expected, input, err)
+		}
+	}
+}
+
+func TestMarshalCBOR(t *testing.T) {
+	tests := map[string]string{
+		`1{a:i1,b:i-1,c:n,d:b1,e:"hi}`: "a56161016162206163f66164f56165626869",
+		`1{a:i500,b:i-500,c:i100000}`:  "a361611901f461623901f361631a000186a0",
+		`1{$value:[i1,"x]}`:            "82016178",
+		`1{h:{$f16:f-0.5}}`:            "a16168f9b800",
+		`1{t:{$tag:[i32,"http]}}`:      "a16174d8206468747470",
+		`1{u:{$simple:i23}}`:           "a16175f7",
+	}
+
+	for input, expected := range tests {
+		doc, err := ParseOrdered(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := MarshalCBOR(doc)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", input, err)
+			continue
+		}
+		if hex.EncodeToString(result) != expected {
+			t.Errorf("expected %v for %v but got %x", expected, input, result)
+		}
+	}
+}
+
+func TestMarshalCBOR_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"a56161016162206163f66164f56165626869",
+		"a36166fa3fc000006167fb40040000000000006168f93e00",
+		"a46175190005616e3818616d380061781bffffffffffffffff",
+		"a461624201026174c11a6553f1006173f7617af8ff",
+		"820102",
+		"a162246b01",
+		"f90001",
+		"f97c00",
+		"3b7fffffffffffffff",
+	}
+
+	for _, input := range inputs {
+		data, _ := hex.DecodeString(input)
+		converted, err := ConvertCBOR(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := ParseOrdered(bytes.NewReader(converted))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := MarshalCBOR(doc)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", converted, err)
+			continue
+		}
+		if hex.EncodeToString(result) != input {
+			t.Errorf("expected %v after a round trip through %s but got %x", input, converted, result)
+		}
+	}
+}
+
+func TestMarshalCBOR_Errors(t *testing.T) {
+	tests := map[string]string{
+		`1{a:{$f16:f0.1}}`:       "<root>.a.$f16: 0.1 cannot be represented as a half-precision float",
+		`1{a:{$f16:f70000}}`:     "<root>.a.$f16: 70000 cannot be represented as a half-precision float",
+		`1{a:{$simple:i24}}`:     "<root>.a.$simple: invalid simple value 24",
+		`1{a:{$uint8:i-1}}`:      "<root>.a.$uint8: -1 does not fit in a uint8",
+		`1{a:{$int8:i-300}}`:     "<root>.a.$int8: -300 does not fit in an argument of 8 bits",
+		`1{a:{$tag:[i-1,i1]}}`:   "<root>.a.$tag: invalid tag number -1",
+		`1{a:{$ext:[i1,"AA==]}}`: "<root>.a.$ext: unsupported tag",
+	}
+
+	for input, expected := range tests {
+		doc, err := ParseOrdered(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = MarshalCBOR(doc)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q for %v but got %v", expected, input, err)
+		}
+	}
+}
+
+func TestHalfFloats(t *testing.T) {
+	for h := 0; h <= math.MaxUint16; h++ {
+		f := halfToFloat32(uint16(h))
+		if math.IsNaN(float64(f)) {
+			continue
+		}
+
+		result, ok := float32ToHalf(f)
+		if !ok || result != uint16(h) {
+			t.Fatalf("expected %04x for %v but got %04x", h, f, result)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+import (
+	"os"
+
+	"github.com/snocorp/cereal/internal/cli"
+)
+
+// Format, validate, query, convert, compare and describe cereal documents
+func main() {
+	os.Exit(cli.Run(os.Args[1:]))
+}
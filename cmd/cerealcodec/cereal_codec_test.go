@@ -0,0 +1,165 @@
+// Code generated by cerealcodec. DO NOT EDIT.
+
+package main
+
+import "github.com/snocorp/cereal"
+
+// MarshalCereal implements cereal.Marshaler.
+func (v record) MarshalCereal() ([]byte, error) {
+	w := &cereal.ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *record) writeCereal(w *cereal.ValueWriter) error {
+	w.BeginMap()
+	w.Key("name")
+	w.String(v.Name)
+	w.Key("Count")
+	w.Int(v.Count)
+	w.Key("ratio")
+	w.Float32(v.Ratio)
+	w.Key("score")
+	w.Float64(v.Score)
+	w.Key("on")
+	w.Bool(v.On)
+	w.Key("tags")
+	if v.Tags == nil {
+		w.Null()
+	} else {
+		w.BeginArray()
+		for _, e := range v.Tags {
+			w.String(e)
+		}
+		w.EndArray()
+	}
+	w.Key("owner")
+	if err := v.Owner.writeCereal(w); err != nil {
+		return err
+	}
+	w.Key("owners")
+	if v.Owners == nil {
+		w.Null()
+	} else {
+		w.BeginArray()
+		for i := range v.Owners {
+			if err := v.Owners[i].writeCereal(w); err != nil {
+				return err
+			}
+		}
+		w.EndArray()
+	}
+	w.Key("labels")
+	if err := w.Value(v.Labels); err != nil {
+		return err
+	}
+	w.Key("extra")
+	if err := w.Value(v.Extra); err != nil {
+		return err
+	}
+	w.EndMap()
+	return nil
+}
+
+// UnmarshalCereal implements cereal.Unmarshaler.
+func (v *record) UnmarshalCereal(data []byte) error {
+	return v.readCereal(cereal.NewValueReader(data))
+}
+
+func (v *record) readCereal(r *cereal.ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "name":
+			err = r.String(&v.Name)
+		case "Count":
+			err = r.Int(&v.Count)
+		case "ratio":
+			err = r.Float32(&v.Ratio)
+		case "score":
+			err = r.Float64(&v.Score)
+		case "on":
+			err = r.Bool(&v.On)
+		case "tags":
+			err = cereal.ReadSlice(r, &v.Tags, r.String)
+		case "owner":
+			err = v.Owner.readCereal(r)
+		case "owners":
+			err = cereal.ReadSlice(r, &v.Owners, func(e *owner) error { return e.readCereal(r) })
+		case "labels":
+			err = r.Value(&v.Labels)
+		case "extra":
+			err = r.Value(&v.Extra)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// MarshalCereal implements cereal.Marshaler.
+func (v owner) MarshalCereal() ([]byte, error) {
+	w := &cereal.ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *owner) writeCereal(w *cereal.ValueWriter) error {
+	w.BeginMap()
+	w.Key("name")
+	w.String(v.Name)
+	w.Key("ids")
+	if v.IDs == nil {
+		w.Null()
+	} else {
+		w.BeginArray()
+		for _, e := range v.IDs {
+			w.Int(e)
+		}
+		w.EndArray()
+	}
+	w.EndMap()
+	return nil
+}
+
+// UnmarshalCereal implements cereal.Unmarshaler.
+func (v *owner) UnmarshalCereal(data []byte) error {
+	return v.readCereal(cereal.NewValueReader(data))
+}
+
+func (v *owner) readCereal(r *cereal.ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "name":
+			err = r.String(&v.Name)
+		case "ids":
+			err = cereal.ReadSlice(r, &v.IDs, r.Int)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// annotation marks the structs that methods are generated for.
+const annotation = "//cereal:codec"
+
+// scalarMethods maps the Go types with their own ValueWriter and ValueReader methods to the
+// name of those methods.
+var scalarMethods = map[string]string{
+	"bool":    "Bool",
+	"int":     "Int",
+	"float32": "Float32",
+	"float64": "Float64",
+	"string":  "String",
+}
+
+// codecType is an annotated struct.
+type codecType struct {
+	name   string
+	fields []codecField
+}
+
+// codecField is a field of an annotated struct along with its key in a document.
+type codecField struct {
+	name string
+	key  string
+	expr ast.Expr
+}
+
+// generate returns the formatted source of a Go file with the methods for the annotated
+// structs of the package in dir, ignoring tests and the output file.
+func generate(dir, outputName string) ([]byte, error) {
+	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filenames)
+
+	fset := token.NewFileSet()
+	packageName := ""
+	types := []codecType{}
+	for _, filename := range filenames {
+		base := filepath.Base(filename)
+		if base == outputName || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		packageName = file.Name.Name
+
+		fileTypes, err := annotatedTypes(fset, file)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, fileTypes...)
+	}
+
+	if len(types) == 0 {
+		return nil, fmt.Errorf("%v: no structs are annotated with %v", dir, annotation)
+	}
+
+	names := map[string]bool{}
+	for _, t := range types {
+		names[t.name] = true
+	}
+
+	g := &generator{codecs: names}
+	fmt.Fprintf(&g.buf, "// Code generated by cerealcodec. DO NOT EDIT.\n\npackage %v\n\nimport \"github.com/snocorp/cereal\"\n", packageName)
+	for _, t := range types {
+		g.writeMarshal(t)
+		g.writeUnmarshal(t)
+	}
+
+	return format.Source(g.buf.Bytes())
+}
+
+// annotatedTypes returns the structs of a file whose doc comment includes the annotation.
+func annotatedTypes(fset *token.FileSet, file *ast.File) ([]codecType, error) {
+	types := []codecType{}
+	for _, decl := range file.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range genDecl.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			doc := typeSpec.Doc
+			if doc == nil && len(genDecl.Specs) == 1 {
+				doc = genDecl.Doc
+			}
+			if !isAnnotated(doc) {
+				continue
+			}
+
+			position := fset.Position(typeSpec.Pos())
+			structType, ok := typeSpec.Type.(*ast.StructType)
+			if !ok || typeSpec.TypeParams != nil {
+				return nil, fmt.Errorf("%v: %v must be a struct without type parameters", position, typeSpec.Name.Name)
+			}
+
+			t, err := structFields(typeSpec.Name.Name, structType)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", position, err)
+			}
+			types = append(types, t)
+		}
+	}
+
+	return types, nil
+}
+
+func isAnnotated(doc *ast.CommentGroup) bool {
+	if doc == nil {
+		return false
+	}
+
+	for _, c := range doc.List {
+		if strings.TrimSpace(c.Text) == annotation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// structFields returns the fields of a struct that are encoded, using the same cereal tags
+// as Serialize and Unmarshal.
+func structFields(name string, structType *ast.StructType) (codecType, error) {
+	t := codecType{name: name}
+	keys := map[string]bool{}
+	for _, field := range structType.Fields.List {
+		if len(field.Names) == 0 {
+			return t, fmt.Errorf("embedded fields of %v are not supported", name)
+		}
+
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return t, err
+			}
+			tag = reflect.StructTag(value)
+		}
+
+		for _, fieldName := range field.Names {
+			key, ok := tag.Lookup("cereal")
+			if !ok || key == "" {
+				key = fieldName.Name
+			} else if key == "-" {
+				continue
+			}
+
+			if keys[key] {
+				return t, fmt.Errorf("%v has more than one field with key '%v'", name, key)
+			}
+			keys[key] = true
+
+			t.fields = append(t.fields, codecField{name: fieldName.Name, key: key, expr: field.Type})
+		}
+	}
+
+	return t, nil
+}
+
+// generator writes the methods of annotated structs.
+type generator struct {
+	buf    bytes.Buffer
+	codecs map[string]bool
+}
+
+// codecName returns the name of the annotated struct that expr refers to, if any.
+func (g *generator) codecName(expr ast.Expr) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || !g.codecs[ident.Name] {
+		return "", false
+	}
+
+	return ident.Name, true
+}
+
+// sliceElem returns the element type of a slice type.
+func sliceElem(expr ast.Expr) (ast.Expr, bool) {
+	array, ok := expr.(*ast.ArrayType)
+	if !ok || array.Len != nil {
+		return nil, false
+	}
+
+	return array.Elt, true
+}
+
+// scalarMethod returns the name of the ValueWriter and ValueReader methods for expr, if any.
+func scalarMethod(expr ast.Expr) (string, bool) {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", false
+	}
+
+	method, ok := scalarMethods[ident.Name]
+	return method, ok
+}
+
+func (g *generator) writeMarshal(t codecType) {
+	fmt.Fprintf(&g.buf, "\n// MarshalCereal implements cereal.Marshaler.\n")
+	fmt.Fprintf(&g.buf, "func (v %v) MarshalCereal() ([]byte, error) {\n", t.name)
+	fmt.Fprintf(&g.buf, "w := &cereal.ValueWriter{}\nerr := v.writeCereal(w)\nreturn w.Bytes(), err\n}\n")
+
+	fmt.Fprintf(&g.buf, "\nfunc (v *%v) writeCereal(w *cereal.ValueWriter) error {\n", t.name)
+	fmt.Fprintf(&g.buf, "w.BeginMap()\n")
+	for _, f := range t.fields {
+		fmt.Fprintf(&g.buf, "w.Key(%v)\n", strconv.Quote(f.key))
+		g.writeFieldValue("v."+f.name, f.expr)
+	}
+	fmt.Fprintf(&g.buf, "w.EndMap()\nreturn nil\n}\n")
+}
+
+// writeFieldValue writes the statements that encode the value of a field.
+func (g *generator) writeFieldValue(value string, expr ast.Expr) {
+	if method, ok := scalarMethod(expr); ok {
+		fmt.Fprintf(&g.buf, "w.%v(%v)\n", method, value)
+		return
+	}
+
+	if _, ok := g.codecName(expr); ok {
+		fmt.Fprintf(&g.buf, "if err := %v.writeCereal(w); err != nil {\nreturn err\n}\n", value)
+		return
+	}
+
+	if elem, ok := sliceElem(expr); ok {
+		// a nil slice is written as a null, as Serialize does
+		if method, ok := scalarMethod(elem); ok {
+			fmt.Fprintf(&g.buf, "if %v == nil {\nw.Null()\n} else {\n", value)
+			fmt.Fprintf(&g.buf, "w.BeginArray()\nfor _, e := range %v {\nw.%v(e)\n}\nw.EndArray()\n}\n", value, method)
+			return
+		}
+		if _, ok := g.codecName(elem); ok {
+			fmt.Fprintf(&g.buf, "if %v == nil {\nw.Null()\n} else {\n", value)
+			fmt.Fprintf(&g.buf, "w.BeginArray()\nfor i := range %v {\n", value)
+			fmt.Fprintf(&g.buf, "if err := %v[i].writeCereal(w); err != nil {\nreturn err\n}\n}\nw.EndArray()\n}\n", value)
+			return
+		}
+	}
+
+	// everything else is encoded by reflection
+	fmt.Fprintf(&g.buf, "if err := w.Value(%v); err != nil {\nreturn err\n}\n", value)
+}
+
+func (g *generator) writeUnmarshal(t codecType) {
+	fmt.Fprintf(&g.buf, "\n// UnmarshalCereal implements cereal.Unmarshaler.\n")
+	fmt.Fprintf(&g.buf, "func (v *%v) UnmarshalCereal(data []byte) error {\n", t.name)
+	fmt.Fprintf(&g.buf, "return v.readCereal(cereal.NewValueReader(data))\n}\n")
+
+	fmt.Fprintf(&g.buf, "\nfunc (v *%v) readCereal(r *cereal.ValueReader) error {\n", t.name)
+	fmt.Fprintf(&g.buf, "ok, err := r.BeginMap()\nif err != nil || !ok {\nreturn err\n}\n\n")
+	fmt.Fprintf(&g.buf, "for {\nkey, ok, err := r.NextKey()\nif err != nil || !ok {\nreturn err\n}\n\n")
+	fmt.Fprintf(&g.buf, "switch key {\n")
+	for _, f := range t.fields {
+		fmt.Fprintf(&g.buf, "case %v:\n", strconv.Quote(f.key))
+		g.writeFieldRead("v."+f.name, f.expr)
+	}
+	fmt.Fprintf(&g.buf, "default:\nerr = r.UnexpectedKey(key)\n}\n")
+	fmt.Fprintf(&g.buf, "if err != nil {\nreturn err\n}\n}\n}\n")
+}
+
+// writeFieldRead writes the statement that decodes the value of a field.
+func (g *generator) writeFieldRead(value string, expr ast.Expr) {
+	if method, ok := scalarMethod(expr); ok {
+		fmt.Fprintf(&g.buf, "err = r.%v(&%v)\n", method, value)
+		return
+	}
+
+	if _, ok := g.codecName(expr); ok {
+		fmt.Fprintf(&g.buf, "err = %v.readCereal(r)\n", value)
+		return
+	}
+
+	if elem, ok := sliceElem(expr); ok {
+		if method, ok := scalarMethod(elem); ok {
+			fmt.Fprintf(&g.buf, "err = cereal.ReadSlice(r, &%v, r.%v)\n", value, method)
+			return
+		}
+		if name, ok := g.codecName(elem); ok {
+			fmt.Fprintf(&g.buf, "err = cereal.ReadSlice(r, &%v, func(e *%v) error { return e.readCereal(r) })\n", value, name)
+			return
+		}
+	}
+
+	// everything else is decoded by reflection
+	fmt.Fprintf(&g.buf, "err = r.Value(&%v)\n", value)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/snocorp/cereal"
+)
+
+// generateFrom writes the files to a new directory and returns the methods generated for them.
+func generateFrom(t *testing.T, files map[string]string) ([]byte, error) {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return generate(dir, "cereal_codec.go")
+}
+
+func TestGenerate(t *testing.T) {
+	types, err := os.ReadFile("types_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := generateFrom(t, map[string]string{"types.go": string(types)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("cereal_codec_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(source) != string(expected) {
+		t.Errorf("Expected the contents of cereal_codec_test.go but got\n%s", source)
+	}
+}
+
+func TestGenerate_Example(t *testing.T) {
+	dir := filepath.Join("..", "..", "examples", "codec")
+	source, err := generate(dir, "cereal_codec.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile(filepath.Join(dir, "cereal_codec.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(source) != string(expected) {
+		t.Errorf("Expected examples/codec/cereal_codec.go to be up to date but got\n%s", source)
+	}
+}
+
+func TestGenerate_RoundTrip(t *testing.T) {
+	values := []record{
+		{},
+		{Name: "a,b}c", Count: -3, Ratio: 1.5, Score: 0.25, On: true, Tags: []string{"x", "y]"}, Extra: 7},
+		{
+			Tags:   []string{},
+			Owner:  owner{Name: "Sam", IDs: []int{1, 2}},
+			Owners: []owner{{Name: "Ann"}, {IDs: []int{3}}},
+			Labels: map[string]string{"team": "core"},
+			Extra:  map[string]any{"k": "v"},
+		},
+	}
+
+	for _, value := range values {
+		err := cereal.CheckCodec(&value)
+		if err != nil {
+			t.Errorf("Unexpected error for %+v: %v", value, err)
+		}
+
+		data, err := cereal.Serialize(value, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result record
+		err = cereal.Unmarshal(data, &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// an empty array leaves a slice nil, as with Unmarshal by reflection
+		if len(value.Tags) == 0 {
+			value.Tags = nil
+		}
+		if !reflect.DeepEqual(result, value) {
+			t.Errorf("Expected %+v after a round trip of %s but got %+v", value, data, result)
+		}
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := map[string]string{
+		"package p\n\ntype plain struct{}\n":                                                                "no structs are annotated with //cereal:codec",
+		"package p\n\n//cereal:codec\ntype list []int\n":                                                    "list must be a struct without type parameters",
+		"package p\n\ntype base struct{}\n\n//cereal:codec\ntype s struct{ base }\n":                        "embedded fields of s are not supported",
+		"package p\n\n//cereal:codec\ntype s struct {\n\tA int `cereal:\"a\"`\n\tB int `cereal:\"a\"`\n}\n": "s has more than one field with key 'a'",
+	}
+
+	for input, expected := range tests {
+		_, err := generateFrom(t, map[string]string{"types.go": input})
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected an error containing '%v' for %q but got %v", expected, input, err)
+		}
+	}
+}
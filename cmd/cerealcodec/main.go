@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Generate MarshalCereal and UnmarshalCereal methods for the structs of a package that are
+// annotated with a //cereal:codec comment. It is meant to be run by go generate:
+//
+//	//go:generate go run github.com/snocorp/cereal/cmd/cerealcodec
+func main() {
+	output := flag.String("o", "cereal_codec.go", "the file to write, relative to the package directory")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) > 1 {
+		printUsage()
+		os.Exit(1)
+	}
+
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	outputPath := filepath.Join(dir, *output)
+	source, err := generate(dir, filepath.Base(outputPath))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	err = os.WriteFile(outputPath, source, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:\n - cerealcodec [-o file] [directory]")
+	flag.PrintDefaults()
+}
@@ -0,0 +1,30 @@
+package main
+
+// The structs in this file are the input of TestGenerate, which generates the methods in
+// cereal_codec_test.go from them.
+
+//cereal:codec
+type record struct {
+	Name    string            `cereal:"name"`
+	Count   int               // encoded under its field name
+	Ratio   float32           `cereal:"ratio"`
+	Score   float64           `cereal:"score"`
+	On      bool              `cereal:"on"`
+	Tags    []string          `cereal:"tags"`
+	Owner   owner             `cereal:"owner"`
+	Owners  []owner           `cereal:"owners"`
+	Labels  map[string]string `cereal:"labels"`
+	Extra   any               `cereal:"extra"`
+	Skipped string            `cereal:"-"`
+}
+
+//cereal:codec
+type owner struct {
+	Name string `cereal:"name"`
+	IDs  []int  `cereal:"ids"`
+}
+
+// plain is not annotated, so no methods are generated for it.
+type plain struct {
+	Value int
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/snocorp/cereal"
+)
+
+// generator writes Go type definitions for the maps of a schema.
+type generator struct {
+	buf     bytes.Buffer
+	names   map[string]bool
+	pending []pendingType
+}
+
+// pendingType is a struct that has been named but not yet written.
+type pendingType struct {
+	name   string
+	schema *cereal.Schema
+}
+
+// generate returns the formatted source of a Go file declaring a struct named typeName for
+// the root of the schema, along with a struct for each nested map that lists its fields.
+func generate(schema *cereal.Schema, packageName, typeName string) ([]byte, error) {
+	if schema.Type != "map" || schema.Fields == nil {
+		return nil, fmt.Errorf("the root of the schema must be a map with fields")
+	}
+
+	g := &generator{names: map[string]bool{}}
+	fmt.Fprintf(&g.buf, "// Code generated by cerealgen. DO NOT EDIT.\n\npackage %v\n", packageName)
+
+	g.pending = append(g.pending, pendingType{name: g.typeName(typeName), schema: schema})
+	for len(g.pending) > 0 {
+		t := g.pending[0]
+		g.pending = g.pending[1:]
+		g.writeStruct(t.name, t.schema)
+	}
+
+	return format.Source(g.buf.Bytes())
+}
+
+func (g *generator) writeStruct(name string, s *cereal.Schema) {
+	g.buf.WriteString("\n")
+	writeComment(&g.buf, s.Description, "")
+	fmt.Fprintf(&g.buf, "type %v struct {\n", name)
+
+	used := map[string]bool{}
+	for _, f := range s.Fields {
+		fieldName := uniqueName(exportedName(f.Name), used)
+		used[fieldName] = true
+
+		writeComment(&g.buf, f.Schema.Description, "\t")
+		fmt.Fprintf(&g.buf, "\t%v %v `cereal:%v`\n", fieldName, g.goType(f.Schema, name+fieldName), strconv.Quote(f.Name))
+	}
+
+	g.buf.WriteString("}\n")
+}
+
+// goType returns the Go type for values matching the schema, queueing a struct named after
+// the path for a map with fields.
+func (g *generator) goType(s *cereal.Schema, name string) string {
+	switch s.Type {
+	case "bool", "int", "float32", "float64", "string":
+		return s.Type
+	case "map":
+		if len(s.Fields) > 0 {
+			structName := g.typeName(name)
+			g.pending = append(g.pending, pendingType{name: structName, schema: s})
+			return structName
+		}
+		if s.Values != nil {
+			return "map[string]" + g.goType(s.Values, name+"Value")
+		}
+		return "map[string]any"
+	case "array":
+		if s.Items != nil {
+			return "[]" + g.goType(s.Items, name+"Item")
+		}
+		return "[]any"
+	}
+
+	return "any"
+}
+
+// typeName returns a type name based on name that has not been used yet.
+func (g *generator) typeName(name string) string {
+	name = uniqueName(name, g.names)
+	g.names[name] = true
+	return name
+}
+
+func uniqueName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		candidate := name + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// exportedName turns a key into an exported Go identifier, starting a new word at each
+// character that cannot appear in an identifier.
+func exportedName(key string) string {
+	name := strings.Builder{}
+	upper := true
+	for _, r := range key {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name.WriteRune(r)
+	}
+
+	s := name.String()
+	if s == "" || !unicode.IsUpper([]rune(s)[0]) {
+		s = "F" + s
+	}
+
+	return s
+}
+
+func writeComment(buf *bytes.Buffer, text, indent string) {
+	for _, line := range strings.Split(text, "\n") {
+		if line != "" {
+			fmt.Fprintf(buf, "%v// %v\n", indent, line)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/snocorp/cereal"
+)
+
+// settingsSchema is the schema the types in settings_test.go are generated from.
+const settingsSchema = `1{type:"map,description:"Settings of a service.,fields:{
+  name:{type:"string},
+  labels:{type:"map,values:{type:"string}},
+  limits:{type:"map,values:{type:"int}},
+  hosts:{type:"map,values:{type:"map,fields:{address:{type:"string},port:{type:"int}}}},
+  backends:{type:"array,items:{type:"map,values:{type:"float64}}},
+  tls:{type:"map,description:"Transport security.,fields:{enabled:{type:"bool},ciphers:{type:"array,items:{type:"string}}}}
+}}`
+
+func TestGenerate(t *testing.T) {
+	schema, err := cereal.ParseSchema(strings.NewReader(settingsSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := generate(schema, "main", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := os.ReadFile("settings_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(source) != string(expected) {
+		t.Errorf("Expected the contents of settings_test.go but got\n%s", source)
+	}
+}
+
+func TestGenerate_RoundTrip(t *testing.T) {
+	input := `1{name:"api,labels:{team:"core,tier:"1},limits:{cpu:i2,memory:i512},` +
+		`hosts:{primary:{address:"10.0.0.1,port:i80},backup:{address:"10.0.0.2,port:i8080}},` +
+		`backends:[{weight:d0.5},{weight:d1.5}],tls:{enabled:b1,ciphers:["aes,"chacha]}}`
+	expected := settings{
+		Name:   "api",
+		Labels: map[string]string{"team": "core", "tier": "1"},
+		Limits: map[string]int{"cpu": 2, "memory": 512},
+		Hosts: map[string]settingsHostsValue{
+			"primary": {Address: "10.0.0.1", Port: 80},
+			"backup":  {Address: "10.0.0.2", Port: 8080},
+		},
+		Backends: []map[string]float64{{"weight": 0.5}, {"weight": 1.5}},
+		Tls:      settingsTls{Enabled: true, Ciphers: []string{"aes", "chacha"}},
+	}
+
+	var result settings
+	err := cereal.Unmarshal([]byte(input), &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Expected %+v but got %+v", expected, result)
+	}
+
+	data, err := cereal.Serialize(result, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var again settings
+	err = cereal.Unmarshal(data, &again)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, expected) {
+		t.Errorf("Expected %+v after a round trip of %s but got %+v", expected, data, again)
+	}
+}
+
+func TestGenerate_InvalidRoot(t *testing.T) {
+	_, err := generate(&cereal.Schema{Type: "array"}, "main", "settings")
+	if err == nil {
+		t.Error("Expected an error for a root that is not a map")
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// Generate Go struct definitions from cereal sample documents or a cereal schema
+func main() {
+	isSchema := flag.Bool("schema", false, "read a schema instead of sample documents")
+	packageName := flag.String("package", "main", "the package of the generated file")
+	typeName := flag.String("type", "Document", "the name of the root struct")
+	output := flag.String("o", "", "the file to write instead of the standard output")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 || (*isSchema && len(args) != 1) {
+		printUsage()
+		os.Exit(1)
+	}
+
+	var schema *cereal.Schema
+	if *isSchema {
+		data, err := readInput(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		schema, err = cereal.ParseSchema(bytes.NewReader(data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", args[0], err)
+			os.Exit(1)
+		}
+	} else {
+		samples := []any{}
+		for _, arg := range args {
+			data, err := readInput(arg)
+			if err == nil {
+				var sample *cereal.OrderedMap
+				sample, err = cereal.ParseOrdered(bytes.NewReader(data))
+				samples = append(samples, sample)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", arg, err)
+				os.Exit(1)
+			}
+		}
+
+		schema = cereal.InferSchema(samples...)
+	}
+
+	source, err := generate(schema, *packageName, *typeName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *output != "" {
+		err = os.WriteFile(*output, source, 0o644)
+	} else {
+		_, err = os.Stdout.Write(source)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func readInput(filename string) ([]byte, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, fmt.Errorf("input is empty")
+	}
+
+	return data, nil
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage:\n - cerealgen [-package name] [-type name] [-o file] <filename>...\n - cerealgen -schema [-package name] [-type name] [-o file] <schema>\n - cat file.cereal | cerealgen -")
+	flag.PrintDefaults()
+}
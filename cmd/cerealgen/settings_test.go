@@ -0,0 +1,25 @@
+// Code generated by cerealgen. DO NOT EDIT.
+
+package main
+
+// Settings of a service.
+type settings struct {
+	Name     string                        `cereal:"name"`
+	Labels   map[string]string             `cereal:"labels"`
+	Limits   map[string]int                `cereal:"limits"`
+	Hosts    map[string]settingsHostsValue `cereal:"hosts"`
+	Backends []map[string]float64          `cereal:"backends"`
+	// Transport security.
+	Tls settingsTls `cereal:"tls"`
+}
+
+type settingsHostsValue struct {
+	Address string `cereal:"address"`
+	Port    int    `cereal:"port"`
+}
+
+// Transport security.
+type settingsTls struct {
+	Enabled bool     `cereal:"enabled"`
+	Ciphers []string `cereal:"ciphers"`
+}
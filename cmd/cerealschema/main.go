@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os"
+
+	"github.com/snocorp/cereal/internal/cli"
+)
+
+// Infer a schema from one or more cereal files and output it as an indented cereal document,
+// or as a JSON Schema, as cereal schema does
+func main() {
+	os.Exit(cli.RunLegacy(append([]string{"schema"}, os.Args[1:]...)))
+}
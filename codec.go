@@ -0,0 +1,381 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Marshaler is implemented by types that encode themselves. MarshalCereal returns the encoded
+// value, such as a map, without a version byte. Serialize uses it in place of reflection.
+type Marshaler interface {
+	MarshalCereal() ([]byte, error)
+}
+
+// Unmarshaler is implemented by types that decode themselves. UnmarshalCereal receives the
+// encoded value without a version byte. Unmarshal uses it in place of reflection.
+type Unmarshaler interface {
+	UnmarshalCereal(data []byte) error
+}
+
+// ValueWriter builds an encoded value without reflection. It is used by the methods that
+// cerealcodec generates. Commas are written between the entries of maps and arrays as
+// needed.
+type ValueWriter struct {
+	buf   bytes.Buffer
+	comma bool
+}
+
+// Bytes returns the encoded value written so far.
+func (w *ValueWriter) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *ValueWriter) separate() {
+	if w.comma {
+		w.buf.WriteByte(',')
+	}
+}
+
+// BeginMap starts a map.
+func (w *ValueWriter) BeginMap() {
+	w.separate()
+	w.buf.WriteByte('{')
+	w.comma = false
+}
+
+// EndMap closes the current map.
+func (w *ValueWriter) EndMap() {
+	w.buf.WriteByte('}')
+	w.comma = true
+}
+
+// BeginArray starts an array.
+func (w *ValueWriter) BeginArray() {
+	w.separate()
+	w.buf.WriteByte('[')
+	w.comma = false
+}
+
+// EndArray closes the current array.
+func (w *ValueWriter) EndArray() {
+	w.buf.WriteByte(']')
+	w.comma = true
+}
+
+// Key writes the key of the next entry of the current map.
+func (w *ValueWriter) Key(key string) {
+	w.separate()
+	w.buf.WriteString(escapeKey(key))
+	w.buf.WriteByte(':')
+	w.comma = false
+}
+
+func (w *ValueWriter) Bool(value bool) {
+	w.separate()
+	writeBool(&w.buf, value)
+	w.comma = true
+}
+
+func (w *ValueWriter) Int(value int) {
+	w.separate()
+	writeInt(&w.buf, int64(value))
+	w.comma = true
+}
+
+func (w *ValueWriter) Float32(value float32) {
+	w.separate()
+	writeFloat(&w.buf, float64(value))
+	w.comma = true
+}
+
+func (w *ValueWriter) Float64(value float64) {
+	w.separate()
+	writeDouble(&w.buf, value)
+	w.comma = true
+}
+
+func (w *ValueWriter) String(value string) {
+	w.separate()
+	writeString(&w.buf, value)
+	w.comma = true
+}
+
+// Null writes a null, as for a nil slice.
+func (w *ValueWriter) Null() {
+	w.separate()
+	w.buf.WriteByte(typeMarkers[Null])
+	w.comma = true
+}
+
+// Value writes any value that Serialize supports, using reflection.
+func (w *ValueWriter) Value(value any) error {
+	w.separate()
+	w.comma = true
+	return writeValue(reflect.ValueOf(&value).Elem(), &w.buf, []string{"<root>"}, false)
+}
+
+// ValueReader reads an encoded map without reflection. It is used by the methods that
+// cerealcodec generates. A null in place of a value leaves it unchanged, as with Unmarshal.
+type ValueReader struct {
+	t      *Tokenizer
+	peeked *Token
+	null   bool
+}
+
+// NewValueReader returns a ValueReader for an encoded map, or for a null.
+func NewValueReader(data []byte) *ValueReader {
+	if len(data) == 1 && data[0] == typeMarkers[Null] {
+		return &ValueReader{null: true}
+	}
+
+	return &ValueReader{t: newTokenizerV1(bytes.NewReader(data))}
+}
+
+// Path returns the path of the value most recently read.
+func (r *ValueReader) Path() []string {
+	if r.t == nil {
+		return []string{"<root>"}
+	}
+
+	return r.t.Path()
+}
+
+func (r *ValueReader) next() (Token, error) {
+	if r.null {
+		r.null = false
+		return Token{Kind: Scalar, Type: Null, Raw: "n"}, nil
+	}
+	if r.peeked != nil {
+		tok := *r.peeked
+		r.peeked = nil
+		return tok, nil
+	}
+
+	return r.t.Next()
+}
+
+// BeginMap starts reading a map. It returns false if the value is a null instead.
+func (r *ValueReader) BeginMap() (bool, error) {
+	return r.begin(MapStart, Map)
+}
+
+// BeginArray starts reading an array. It returns false if the value is a null instead.
+func (r *ValueReader) BeginArray() (bool, error) {
+	return r.begin(ArrayStart, Array)
+}
+
+func (r *ValueReader) begin(kind TokenKind, valueType ValueType) (bool, error) {
+	tok, err := r.next()
+	if err != nil {
+		return false, err
+	}
+
+	if tok.Kind == Scalar && tok.Type == Null {
+		return false, nil
+	}
+	if tok.Kind != kind {
+		return false, r.unexpected(tok, valueType)
+	}
+
+	return true, nil
+}
+
+// NextKey reads the key of the next entry of the current map. It returns false once the
+// map has ended.
+func (r *ValueReader) NextKey() (string, bool, error) {
+	tok, err := r.next()
+	if err != nil || tok.Kind == MapEnd {
+		return "", false, err
+	}
+
+	return tok.Value, true, nil
+}
+
+// NextElement reports whether the current array has another element to read.
+func (r *ValueReader) NextElement() (bool, error) {
+	tok, err := r.next()
+	if err != nil || tok.Kind == ArrayEnd {
+		return false, err
+	}
+
+	r.peeked = &tok
+	return true, nil
+}
+
+// UnexpectedKey returns the error for a key that does not match a field.
+func (r *ValueReader) UnexpectedKey(key string) error {
+	path := r.Path()
+	return fmt.Errorf("%v: unexpected field name '%v'", strings.Join(path[:len(path)-1], "."), key)
+}
+
+func (r *ValueReader) Bool(value *bool) error {
+	result, err := r.scalar(Bool)
+	if result != nil {
+		*value = result.(bool)
+	}
+	return err
+}
+
+func (r *ValueReader) Int(value *int) error {
+	result, err := r.scalar(Int)
+	if result != nil {
+		*value = result.(int)
+	}
+	return err
+}
+
+func (r *ValueReader) Float32(value *float32) error {
+	result, err := r.scalar(Float32)
+	if result != nil {
+		*value = result.(float32)
+	}
+	return err
+}
+
+func (r *ValueReader) Float64(value *float64) error {
+	result, err := r.scalar(Float64)
+	if result != nil {
+		*value = result.(float64)
+	}
+	return err
+}
+
+func (r *ValueReader) String(value *string) error {
+	result, err := r.scalar(String)
+	if result != nil {
+		*value = result.(string)
+	}
+	return err
+}
+
+// scalar reads a scalar of the given type, returning nil for a null.
+func (r *ValueReader) scalar(valueType ValueType) (any, error) {
+	tok, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok.Kind != Scalar || (tok.Type != valueType && tok.Type != Null) {
+		return nil, r.unexpected(tok, valueType)
+	}
+
+	result, err := parseValue(tok.Value, tok.Type, r.Path())
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *ValueReader) unexpected(tok Token, valueType ValueType) error {
+	return fmt.Errorf("%v: expected %v but got %v", strings.Join(r.Path(), "."), schemaTypeName(valueType), schemaTypeName(tok.Type))
+}
+
+// Value reads a value into the variable that value points to, using reflection. The rules
+// are the same as for struct fields read by Unmarshal.
+func (r *ValueReader) Value(value any) error {
+	tok, err := r.next()
+	if err != nil {
+		return err
+	}
+
+	if tok.Kind == Scalar && tok.Type == Null {
+		return nil
+	}
+
+	return decodeElementV1(r.t, tok, reflect.ValueOf(value).Elem(), r.Path())
+}
+
+// ReadSlice reads an array into the slice that value points to, reading each element with
+// read. An empty array or a null leaves the slice unchanged, as with Unmarshal.
+func ReadSlice[T any](r *ValueReader, value *[]T, read func(*T) error) error {
+	ok, err := r.BeginArray()
+	if err != nil || !ok {
+		return err
+	}
+
+	var result []T
+	for {
+		more, err := r.NextElement()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		var elem T
+		err = read(&elem)
+		if err != nil {
+			return err
+		}
+		result = append(result, elem)
+	}
+
+	if result != nil {
+		*value = result
+	}
+	return nil
+}
+
+// CheckCodec verifies that the generated methods of a type agree with reflection. The value
+// must be a pointer to a struct that implements Marshaler and Unmarshaler. Its encoding from
+// MarshalCereal must equal the one that Serialize produces by reflection, and decoding that
+// encoding with UnmarshalCereal must give the same value as Unmarshal does by reflection.
+// Nested values are encoded and decoded by reflection too, even if they have methods of their
+// own. It is meant to be called from tests with a variety of values.
+func CheckCodec(value any) error {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a pointer to a struct but got %T", value)
+	}
+	m, ok := value.(Marshaler)
+	if !ok {
+		return fmt.Errorf("%T does not implement Marshaler", value)
+	}
+	if _, ok := value.(Unmarshaler); !ok {
+		return fmt.Errorf("%T does not implement Unmarshaler", value)
+	}
+
+	generated, err := m.MarshalCereal()
+	if err != nil {
+		return err
+	}
+
+	reflective := bytes.Buffer{}
+	// the reference ignores the generated methods of nested values too
+	err = writeStruct(rv.Elem(), &reflective, []string{"<root>"}, true)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(generated, reflective.Bytes()) {
+		return fmt.Errorf("generated encoding '%s' differs from reflective encoding '%s'", generated, reflective.Bytes())
+	}
+
+	generatedValue := reflect.New(rv.Elem().Type())
+	err = generatedValue.Interface().(Unmarshaler).UnmarshalCereal(generated)
+	if err != nil {
+		return err
+	}
+
+	reflectiveValue := reflect.New(rv.Elem().Type())
+	t := newTokenizerV1(bytes.NewReader(generated))
+	t.reflective = true
+	_, err = t.Next()
+	if err != nil {
+		return err
+	}
+	err = decodeStructV1(t, reflectiveValue.Elem(), []string{"<root>"})
+	if err != nil {
+		return err
+	}
+
+	if !reflect.DeepEqual(generatedValue.Elem().Interface(), reflectiveValue.Elem().Interface()) {
+		return fmt.Errorf("generated decoding %+v differs from reflective decoding %+v", generatedValue.Elem(), reflectiveValue.Elem())
+	}
+
+	return nil
+}
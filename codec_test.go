@@ -0,0 +1,327 @@
+package cereal
+
+import (
+	"fmt"
+	"testing"
+)
+
+// codecExample has methods like those that cerealcodec generates.
+type codecExample struct {
+	Key   string
+	Num   int
+	Ratio float32
+	Tags  []string
+	Inner codecInner `cereal:"inner"`
+	Extra any
+}
+
+type codecInner struct {
+	Flag  bool    `cereal:"flag"`
+	Score float64 `cereal:"score"`
+}
+
+func (v codecExample) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *codecExample) writeCereal(w *ValueWriter) error {
+	w.BeginMap()
+	w.Key("Key")
+	w.String(v.Key)
+	w.Key("Num")
+	w.Int(v.Num)
+	w.Key("Ratio")
+	w.Float32(v.Ratio)
+	w.Key("Tags")
+	if v.Tags == nil {
+		w.Null()
+	} else {
+		w.BeginArray()
+		for _, e := range v.Tags {
+			w.String(e)
+		}
+		w.EndArray()
+	}
+	w.Key("inner")
+	if err := v.Inner.writeCereal(w); err != nil {
+		return err
+	}
+	w.Key("Extra")
+	if err := w.Value(v.Extra); err != nil {
+		return err
+	}
+	w.EndMap()
+	return nil
+}
+
+func (v *codecExample) UnmarshalCereal(data []byte) error {
+	return v.readCereal(NewValueReader(data))
+}
+
+func (v *codecExample) readCereal(r *ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "Key":
+			err = r.String(&v.Key)
+		case "Num":
+			err = r.Int(&v.Num)
+		case "Ratio":
+			err = r.Float32(&v.Ratio)
+		case "Tags":
+			err = ReadSlice(r, &v.Tags, r.String)
+		case "inner":
+			err = v.Inner.readCereal(r)
+		case "Extra":
+			err = r.Value(&v.Extra)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (v codecInner) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *codecInner) writeCereal(w *ValueWriter) error {
+	w.BeginMap()
+	w.Key("flag")
+	w.Bool(v.Flag)
+	w.Key("score")
+	w.Float64(v.Score)
+	w.EndMap()
+	return nil
+}
+
+func (v *codecInner) UnmarshalCereal(data []byte) error {
+	return v.readCereal(NewValueReader(data))
+}
+
+func (v *codecInner) readCereal(r *ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "flag":
+			err = r.Bool(&v.Flag)
+		case "score":
+			err = r.Float64(&v.Score)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// codecMismatch encodes a key that differs from the one used by reflection.
+type codecMismatch struct {
+	Key string
+}
+
+func (v codecMismatch) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	w.BeginMap()
+	w.Key("key")
+	w.String(v.Key)
+	w.EndMap()
+	return w.Bytes(), nil
+}
+
+func (v *codecMismatch) UnmarshalCereal(data []byte) error {
+	return nil
+}
+
+// codecNested delegates to the methods of its field, which agree with each other but not with
+// reflection.
+type codecNested struct {
+	Inner codecMismatch
+}
+
+func (v codecNested) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	w.BeginMap()
+	w.Key("Inner")
+	err := w.Value(v.Inner)
+	w.EndMap()
+	return w.Bytes(), err
+}
+
+func (v *codecNested) UnmarshalCereal(data []byte) error {
+	return nil
+}
+
+// codecLossy encodes its field as reflection does, but its field drops everything it decodes.
+type codecLossy struct {
+	Inner codecLossyInner
+}
+
+type codecLossyInner struct {
+	Key string
+}
+
+func (v codecLossy) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	w.BeginMap()
+	w.Key("Inner")
+	err := w.Value(v.Inner)
+	w.EndMap()
+	return w.Bytes(), err
+}
+
+func (v *codecLossy) UnmarshalCereal(data []byte) error {
+	return v.Inner.UnmarshalCereal(nil)
+}
+
+func (v codecLossyInner) MarshalCereal() ([]byte, error) {
+	w := &ValueWriter{}
+	w.BeginMap()
+	w.Key("Key")
+	w.String(v.Key)
+	w.EndMap()
+	return w.Bytes(), nil
+}
+
+func (v *codecLossyInner) UnmarshalCereal(data []byte) error {
+	return nil
+}
+
+func TestCheckCodec(t *testing.T) {
+	values := []*codecExample{
+		{Extra: false},
+		{Key: "a,b}c", Num: -3, Ratio: 1.5, Tags: []string{"x", "y]"}, Inner: codecInner{Flag: true, Score: 0.25}, Extra: 7},
+		{Tags: []string{}, Extra: map[string]any{"k": "v"}},
+		// nil values are written as nulls
+		{},
+	}
+
+	for _, value := range values {
+		err := CheckCodec(value)
+		if err != nil {
+			t.Errorf("Unexpected error for %+v: %v", value, err)
+		}
+	}
+}
+
+func TestCheckCodec_Errors(t *testing.T) {
+	err := CheckCodec(&codecMismatch{Key: "a"})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if err.Error() != "generated encoding '{key:\"a}' differs from reflective encoding '{Key:\"a}'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// nested values are checked against reflection rather than their own methods
+	err = CheckCodec(&codecNested{Inner: codecMismatch{Key: "a"}})
+	if err == nil || err.Error() != "generated encoding '{Inner:{key:\"a}}' differs from reflective encoding '{Inner:{Key:\"a}}'" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = CheckCodec(&codecLossy{Inner: codecLossyInner{Key: "a"}})
+	if err == nil || err.Error() != "generated decoding {Inner:{Key:}} differs from reflective decoding {Inner:{Key:a}}" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	err = CheckCodec(codecExample{})
+	if err == nil || err.Error() != "expected a pointer to a struct but got cereal.codecExample" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCodec_Serialize(t *testing.T) {
+	value := codecExample{Key: "k", Num: 1, Tags: []string{"a"}, Inner: codecInner{Score: 2}, Extra: "e"}
+
+	result, err := Serialize(value, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{Key:\"k,Num:i1,Ratio:f0,Tags:[\"a],inner:{flag:b0,score:d2},Extra:\"e}"
+	if string(result) != expected {
+		t.Errorf("Expected %v but got %v", expected, string(result))
+	}
+}
+
+func TestCodec_Unmarshal(t *testing.T) {
+	value := codecExample{Key: "unchanged", Tags: []string{"kept"}}
+
+	err := Unmarshal([]byte("1{Key:n,Num:i4,Tags:[],inner:{score:d1.5},Extra:[i1,i2]}"), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := codecExample{Key: "unchanged", Num: 4, Tags: []string{"kept"}, Inner: codecInner{Score: 1.5}, Extra: []any{1, 2}}
+	if fmt.Sprintf("%+v", value) != fmt.Sprintf("%+v", expected) {
+		t.Errorf("Expected %+v but got %+v", expected, value)
+	}
+}
+
+func TestCodec_UnmarshalErrors(t *testing.T) {
+	cases := map[string]string{
+		"1{Num:\"x}":          "<root>.Num: expected int but got string",
+		"1{inner:{flag:i1}}":  "<root>.inner.flag: expected bool but got int",
+		"1{inner:{other:b1}}": "<root>.inner: unexpected field name 'other'",
+		"1{Tags:i1}":          "<root>.Tags: expected array but got int",
+		"1{inner:[]}":         "<root>.inner: expected map but got array",
+	}
+
+	for input, expected := range cases {
+		value := codecExample{}
+		err := Unmarshal([]byte(input), &value)
+		if err == nil {
+			t.Errorf("Expected an error for %v", input)
+		} else if err.Error() != expected {
+			t.Errorf("Expected '%v' for %v but got '%v'", expected, input, err)
+		}
+	}
+}
+
+func TestCodec_NestedField(t *testing.T) {
+	type outer struct {
+		Inner codecInner
+	}
+
+	value := outer{}
+	err := Unmarshal([]byte("1{Inner:{flag:b1}}"), &value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !value.Inner.Flag {
+		t.Errorf("Expected the flag to be set")
+	}
+
+	result, err := Serialize(value, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1{Inner:{flag:b1,score:d0}}" {
+		t.Errorf("Unexpected result %v", string(result))
+	}
+}
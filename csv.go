@@ -0,0 +1,417 @@
+package cereal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CSVOptions controls the conversion between records and CSV.
+type CSVOptions struct {
+	// Comma is the field delimiter. It defaults to ','.
+	Comma rune
+	// ArraySeparator joins the elements of an array in a single cell. It defaults to ";".
+	ArraySeparator string
+	// Columns, if set, are the columns written by ConvertCerealCSV, in order, and any other
+	// columns are left out. Otherwise they are the columns of the first record.
+	Columns []string
+	// Schema, if set, decides the types of the values read by ConvertCSV at the paths it
+	// describes. Elsewhere types are inferred from each cell.
+	Schema *Schema
+}
+
+// csvArraySuffix marks a column whose cells hold arrays.
+const csvArraySuffix = "[]"
+
+func (o CSVOptions) comma() rune {
+	if o.Comma == 0 {
+		return ','
+	}
+	return o.Comma
+}
+
+func (o CSVOptions) arraySeparator() string {
+	if o.ArraySeparator == "" {
+		return ";"
+	}
+	return o.ArraySeparator
+}
+
+// ConvertCSV reads CSV with a header row and writes each row as a serialized document on a
+// line of its own. A column named with dots, such as "server.port", is a key of a nested map,
+// and a column whose name ends with [] holds an array, with its elements joined by the array
+// separator. The type of each cell is inferred: an empty cell is a null, true and false are
+// bool values, integers that fit in 32 bits are int values, other numbers are float64 values
+// and anything else is a string, unless the schema says otherwise. Rows are read and written
+// one at a time.
+func ConvertCSV(reader io.Reader, writer io.Writer, options CSVOptions) error {
+	r := csv.NewReader(reader)
+	r.Comma = options.comma()
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	columns, err := parseCSVHeader(header)
+	if err != nil {
+		return &LineError{Line: 1, Err: err}
+	}
+
+	out := bufio.NewWriter(writer)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		line, _ := r.FieldPos(0)
+		doc, err := csvRecord(record, columns, options)
+		if err != nil {
+			return &LineError{Line: line, Err: err}
+		}
+
+		data, err := serializeParsed(doc)
+		if err != nil {
+			return &LineError{Line: line, Err: err}
+		}
+
+		out.Write(data)
+		err = out.WriteByte('\n')
+		if err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// csvColumn is a column of CSV read by ConvertCSV.
+type csvColumn struct {
+	path  []string
+	array bool
+}
+
+func parseCSVHeader(header []string) ([]csvColumn, error) {
+	columns := make([]csvColumn, len(header))
+	leaves := map[string]string{}
+	parents := map[string]string{}
+	for i, name := range header {
+		key := strings.TrimSuffix(name, csvArraySuffix)
+		if key == "" {
+			return nil, fmt.Errorf("column %v has no name", i+1)
+		}
+		if other, ok := leaves[key]; ok {
+			return nil, fmt.Errorf("duplicate column '%v'", other)
+		}
+		if other, ok := parents[key]; ok {
+			return nil, fmt.Errorf("column '%v' conflicts with column '%v'", name, other)
+		}
+
+		path := strings.Split(key, ".")
+		for j := 1; j < len(path); j++ {
+			parent := strings.Join(path[:j], ".")
+			if other, ok := leaves[parent]; ok {
+				return nil, fmt.Errorf("column '%v' conflicts with column '%v'", name, other)
+			}
+			parents[parent] = name
+		}
+
+		leaves[key] = name
+		columns[i] = csvColumn{path: path, array: strings.HasSuffix(name, csvArraySuffix)}
+	}
+
+	return columns, nil
+}
+
+// csvRecord builds the document for a row.
+func csvRecord(record []string, columns []csvColumn, options CSVOptions) (*OrderedMap, error) {
+	doc := NewOrderedMap()
+	for i, column := range columns {
+		m := doc
+		s := options.Schema
+		for _, key := range column.path[:len(column.path)-1] {
+			child, ok := m.Get(key)
+			if !ok {
+				child = NewOrderedMap()
+				m.Set(key, child)
+			}
+			m = child.(*OrderedMap)
+			s = csvFieldSchema(s, key)
+		}
+
+		key := column.path[len(column.path)-1]
+		s = csvFieldSchema(s, key)
+		path := append([]string{"<root>"}, column.path...)
+
+		var value any
+		var err error
+		if column.array || (s != nil && s.Type == "array") {
+			value, err = csvArray(record[i], s, path, options)
+		} else {
+			value, err = csvScalar(record[i], s, path)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m.Set(key, value)
+	}
+
+	return doc, nil
+}
+
+func csvFieldSchema(s *Schema, key string) *Schema {
+	if s == nil {
+		return nil
+	}
+
+	field := s.Field(key)
+	if field == nil {
+		return s.Values
+	}
+	return field
+}
+
+func csvArray(cell string, s *Schema, path []string, options CSVOptions) ([]any, error) {
+	result := []any{}
+	if cell == "" {
+		return result, nil
+	}
+
+	var itemSchema *Schema
+	if s != nil {
+		itemSchema = s.Items
+	}
+
+	for i, part := range strings.Split(cell, options.arraySeparator()) {
+		value, err := csvScalar(part, itemSchema, appendPath(path, strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+var (
+	csvIntPattern   = regexp.MustCompile(`^-?(0|[1-9][0-9]*)$`)
+	csvFloatPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// csvScalar returns the value of a cell, with the type given by the schema or inferred from
+// the cell. Integers with leading zeros, such as postal codes, are kept as strings.
+func csvScalar(cell string, s *Schema, path []string) (any, error) {
+	schemaType := "any"
+	if s != nil {
+		schemaType = s.Type
+	}
+
+	switch schemaType {
+	case "string":
+		return cell, nil
+	case "bool":
+		if cell == "" {
+			return nil, nil
+		}
+		b, err := strconv.ParseBool(cell)
+		if err != nil {
+			return nil, fmt.Errorf("%v: invalid bool '%v'", strings.Join(path, "."), cell)
+		}
+		return b, nil
+	case "int", "float32", "float64", "null":
+		if cell == "" {
+			return nil, nil
+		}
+		return parseValue(cell, schemaTypes[schemaType], path)
+	}
+
+	switch {
+	case cell == "":
+		return nil, nil
+	case cell == "true":
+		return true, nil
+	case cell == "false":
+		return false, nil
+	case csvIntPattern.MatchString(cell):
+		i, err := strconv.ParseInt(cell, 10, 32)
+		if err == nil {
+			return int(i), nil
+		}
+		// integers outside the 32 bit range of an int are read as floats
+	}
+
+	if csvFloatPattern.MatchString(cell) {
+		return strconv.ParseFloat(cell, 64)
+	}
+	return cell, nil
+}
+
+// ConvertCerealCSV reads one document per line and writes each as a row of CSV, after a
+// header row naming the columns. Nested maps are flattened into columns with dotted names,
+// and arrays of scalars are joined by the array separator in a column whose name ends with
+// []. Nulls are empty cells. The options may name the columns to write, leaving out the
+// others. Otherwise the columns are those of the first record, and a later record with a
+// column that is not among them is an error. It is the reverse of ConvertCSV.
+func ConvertCerealCSV(reader io.Reader, writer io.Writer, options CSVOptions) error {
+	w := csv.NewWriter(writer)
+	w.Comma = options.comma()
+
+	rw := &csvRecordWriter{writer: w, options: options}
+	var lineErr error
+	err := readLines(reader, func(number int, line []byte) bool {
+		err := rw.write(line)
+		if err != nil {
+			lineErr = &LineError{Line: number, Err: err}
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	if lineErr != nil {
+		return lineErr
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// csvRecordWriter writes documents as rows, writing the header before the first one.
+type csvRecordWriter struct {
+	writer  *csv.Writer
+	options CSVOptions
+	// columns maps the names of the columns, without the array suffix, to their indexes.
+	columns map[string]int
+}
+
+func (rw *csvRecordWriter) write(line []byte) error {
+	doc, err := ParseOrdered(bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+
+	cells := []csvCell{}
+	err = flattenCSV(doc, "", []string{"<root>"}, rw.options, &cells)
+	if err != nil {
+		return err
+	}
+
+	if rw.columns == nil {
+		header := rw.options.Columns
+		if header == nil {
+			for _, c := range cells {
+				header = append(header, c.name)
+			}
+		}
+
+		rw.columns = map[string]int{}
+		for i, name := range header {
+			rw.columns[strings.TrimSuffix(name, csvArraySuffix)] = i
+		}
+
+		err = rw.writer.Write(header)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(rw.columns))
+	for _, c := range cells {
+		i, ok := rw.columns[strings.TrimSuffix(c.name, csvArraySuffix)]
+		if !ok && rw.options.Columns == nil {
+			return fmt.Errorf("unexpected column '%v'", c.name)
+		} else if !ok {
+			continue
+		}
+		record[i] = c.value
+	}
+
+	return rw.writer.Write(record)
+}
+
+// csvCell is a column of a flattened record and its text.
+type csvCell struct {
+	name  string
+	value string
+}
+
+func flattenCSV(value any, prefix string, path []string, options CSVOptions, cells *[]csvCell) error {
+	for _, c := range queryChildren(value) {
+		childPath := appendPath(path, c.key)
+		if strings.Contains(c.key, ".") {
+			return fmt.Errorf("%v: a key with a dot cannot be a column", strings.Join(childPath, "."))
+		}
+
+		name := prefix + c.key
+		switch v := c.value.(type) {
+		case *OrderedMap:
+			err := flattenCSV(v, name+".", childPath, options, cells)
+			if err != nil {
+				return err
+			}
+		case []any:
+			parts := make([]string, len(v))
+			for i, elem := range v {
+				elemPath := appendPath(childPath, strconv.Itoa(i))
+				s, ok := formatCSVScalar(elem)
+				if !ok {
+					return fmt.Errorf("%v: only scalars can be joined in a cell", strings.Join(elemPath, "."))
+				}
+				if strings.Contains(s, options.arraySeparator()) {
+					return fmt.Errorf("%v: value contains the array separator '%v'", strings.Join(elemPath, "."), options.arraySeparator())
+				}
+				parts[i] = s
+			}
+			*cells = append(*cells, csvCell{name: name + csvArraySuffix, value: strings.Join(parts, options.arraySeparator())})
+		default:
+			s, ok := formatCSVScalar(v)
+			if !ok {
+				return fmt.Errorf("%v: unsupported value of type %T", strings.Join(childPath, "."), v)
+			}
+			*cells = append(*cells, csvCell{name: name, value: s})
+		}
+	}
+
+	return nil
+}
+
+// formatCSVScalar returns the text of a scalar as read by ParseOrdered. Floats always have a
+// fraction or an exponent, so that they are not read back as integers.
+func formatCSVScalar(value any) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "", true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int:
+		return strconv.Itoa(v), true
+	case float32:
+		return formatCSVFloat(float64(v), 32), true
+	case float64:
+		return formatCSVFloat(v, 64), true
+	case string:
+		return v, true
+	}
+
+	return "", false
+}
+
+func formatCSVFloat(f float64, bitSize int) string {
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !math.IsNaN(f) && !math.IsInf(f, 0) && !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
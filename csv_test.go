@@ -0,0 +1,141 @@
+package cereal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestConvertCSV(t *testing.T) {
+	input := "id,name,zip,score,server.host,server.port,tags[],ok\n" +
+		"1,alice,02134,1.5,a.example,8080,x;2,true\n" +
+		"2,\"bob, jr\",,3000000000,b,,,false\n"
+
+	out := bytes.Buffer{}
+	err := ConvertCSV(strings.NewReader(input), &out, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{id:i1,name:\"alice,zip:\"02134,score:d1.5,server:{host:\"a.example,port:i8080},tags:[\"x,i2],ok:b1}\n" +
+		"1{id:i2,name:\"bob\\, jr,zip:n,score:d3e+09,server:{host:\"b,port:n},tags:[],ok:b0}\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
+
+func TestConvertCSV_Options(t *testing.T) {
+	schema, err := ParseSchema(strings.NewReader(`1{type:"map,fields:{
+		id:{type:"string},
+		score:{type:"float32},
+		nums:{type:"array,items:{type:"float64}},
+		flag:{type:"bool},
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := "id\tscore\tnums\tflag\n7\t2\t1|2\t1\n"
+
+	out := bytes.Buffer{}
+	err = ConvertCSV(strings.NewReader(input), &out, CSVOptions{Comma: '\t', ArraySeparator: "|", Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "1{id:\"7,score:f2,nums:[d1,d2],flag:b1}\n" {
+		t.Errorf("unexpected result %q", out.String())
+	}
+}
+
+func TestConvertCSV_Errors(t *testing.T) {
+	schema := &Schema{Type: "map", Fields: []SchemaField{{Name: "a", Schema: &Schema{Type: "int"}}}}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a,a\n1,2\n", "line 1: duplicate column 'a'"},
+		{"a,a.b\n1,2\n", "line 1: column 'a.b' conflicts with column 'a'"},
+		{"a.b,a[]\n1,2\n", "line 1: column 'a[]' conflicts with column 'a.b'"},
+		{"a,\n1,2\n", "line 1: column 2 has no name"},
+		{"a\n1\nx\n", "line 3: <root>.a: invalid int 'x'"},
+	}
+
+	for _, test := range tests {
+		err := ConvertCSV(strings.NewReader(test.input), &bytes.Buffer{}, CSVOptions{Schema: schema})
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("expected error %v for %q but got %v", test.expected, test.input, err)
+		}
+	}
+
+	err := ConvertCSV(strings.NewReader("a,b\n1,2,3\n"), &bytes.Buffer{}, CSVOptions{})
+	if err == nil {
+		t.Error("expected an error for a row with too many fields")
+	}
+}
+
+func TestConvertCerealCSV(t *testing.T) {
+	input := "1{id:i1,name:\"alice,server:{host:\"a,port:i80},tags:[\"x,i2],score:d2,ok:b1}\n" +
+		"\n" +
+		"1{id:i2,name:\"bob\\, jr,server:{host:\"b},tags:[],score:n,ok:b0}\n"
+
+	out := bytes.Buffer{}
+	err := ConvertCerealCSV(strings.NewReader(input), &out, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "id,name,server.host,server.port,tags[],score,ok\n" +
+		"1,alice,a,80,x;2,2.0,true\n" +
+		"2,\"bob, jr\",b,,,,false\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+
+	out.Reset()
+	err = ConvertCerealCSV(strings.NewReader(input), &out, CSVOptions{Columns: []string{"name", "tags[]"}, ArraySeparator: "|"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "name,tags[]\nalice,x|2\n\"bob, jr\",\n" {
+		t.Errorf("unexpected result %q", out.String())
+	}
+}
+
+func TestConvertCerealCSV_Errors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1{a:i1}\n1{b:i2}\n", "line 2: unexpected column 'b'"},
+		{"1{a:[{b:i1}]}\n", "line 1: <root>.a.0: only scalars can be joined in a cell"},
+		{"1{a:[\"x;y]}\n", "line 1: <root>.a.0: value contains the array separator ';'"},
+		{"1{a.b:i1}\n", "line 1: <root>.a.b: a key with a dot cannot be a column"},
+	}
+
+	for _, test := range tests {
+		err := ConvertCerealCSV(strings.NewReader(test.input), &bytes.Buffer{}, CSVOptions{})
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("expected error %v for %q but got %v", test.expected, test.input, err)
+		}
+	}
+}
+
+func TestConvertCerealCSV_RoundTrip(t *testing.T) {
+	input := "1{a:i1,b:{c:d1.5,d:\"x},e:[b1,\"y],f:n}\n1{a:i-2,b:{c:d2,d:\"007},e:[],f:\"z}\n"
+
+	csvData := bytes.Buffer{}
+	err := ConvertCerealCSV(strings.NewReader(input), &csvData, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := bytes.Buffer{}
+	err = ConvertCSV(&csvData, &out, CSVOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != input {
+		t.Errorf("expected %q but got %q", input, out.String())
+	}
+}
@@ -0,0 +1,245 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ChangeKind describes how a value differs between two documents.
+type ChangeKind int
+
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+// Change is a single difference found by Diff. Path holds the keys and array indices leading
+// to the value, not including the root. Old is unset for an Added value and New is unset for a
+// Removed value, in which case the matching type is -1, as it is for a value that cannot be
+// serialized.
+type Change struct {
+	Kind    ChangeKind
+	Path    []string
+	Old     any
+	New     any
+	OldType ValueType
+	NewType ValueType
+}
+
+// Diff compares two documents, such as the results of Parse or typed values that can be
+// serialized, and returns the values that were added, removed or changed on the way from a to
+// b. Maps and arrays with the same type are compared entry by entry, so a change is reported
+// at the deepest path where the documents differ. Values are only equal if they have the same
+// wire type, so i1 and d1 are reported as a change. Entries of a Go map are compared in key
+// order and a RawValue is compared by its bytes.
+func Diff(a, b any) []Change {
+	changes := []Change{}
+	diffValues(a, b, []string{}, &changes)
+	return changes
+}
+
+func diffValues(a, b any, path []string, changes *[]Change) {
+	aType, _ := valueTypeOf(a)
+	bType, _ := valueTypeOf(b)
+	_, aRaw := a.(RawValue)
+	_, bRaw := b.(RawValue)
+
+	if aType == bType && (aType == Map || aType == Array) && !aRaw && !bRaw {
+		aChildren := queryChildren(a)
+		bChildren := queryChildren(b)
+
+		if aType == Array {
+			for i := 0; i < max(len(aChildren), len(bChildren)); i++ {
+				if i >= len(bChildren) {
+					*changes = append(*changes, removedChange(aChildren[i], path))
+				} else if i >= len(aChildren) {
+					*changes = append(*changes, addedChange(bChildren[i], path))
+				} else {
+					diffValues(aChildren[i].value, bChildren[i].value, appendPath(path, aChildren[i].key), changes)
+				}
+			}
+			return
+		}
+
+		bValues := map[string]any{}
+		for _, c := range bChildren {
+			bValues[c.key] = c.value
+		}
+		aKeys := map[string]bool{}
+		for _, c := range aChildren {
+			aKeys[c.key] = true
+			bValue, ok := bValues[c.key]
+			if !ok {
+				*changes = append(*changes, removedChange(c, path))
+				continue
+			}
+			diffValues(c.value, bValue, appendPath(path, c.key), changes)
+		}
+		for _, c := range bChildren {
+			if !aKeys[c.key] {
+				*changes = append(*changes, addedChange(c, path))
+			}
+		}
+		return
+	}
+
+	if aType == bType && equalScalars(a, b) {
+		return
+	}
+
+	*changes = append(*changes, Change{Kind: Changed, Path: path, Old: a, New: b, OldType: aType, NewType: bType})
+}
+
+func addedChange(c queryChild, path []string) Change {
+	valueType, _ := valueTypeOf(c.value)
+	return Change{Kind: Added, Path: appendPath(path, c.key), New: c.value, OldType: -1, NewType: valueType}
+}
+
+func removedChange(c queryChild, path []string) Change {
+	valueType, _ := valueTypeOf(c.value)
+	return Change{Kind: Removed, Path: appendPath(path, c.key), Old: c.value, OldType: valueType, NewType: -1}
+}
+
+// appendPath returns a new path so that changes never share their backing arrays.
+func appendPath(path []string, key string) []string {
+	return append(path[:len(path):len(path)], key)
+}
+
+func equalScalars(a, b any) bool {
+	aRaw, aOk := a.(RawValue)
+	bRaw, bOk := b.(RawValue)
+	if aOk || bOk {
+		return aOk && bOk && bytes.Equal(aRaw, bRaw)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// valueTypeOf returns the type a value is written with by Serialize, or -1 if the value cannot
+// be serialized.
+func valueTypeOf(value any) (ValueType, bool) {
+	switch v := value.(type) {
+	case nil:
+		return Null, true
+	case *OrderedMap, OrderedMap:
+		return Map, true
+	case RawValue:
+		if len(v) == 0 {
+			return -1, false
+		}
+		valueType, err := parseValueType(v[0], nil)
+		if err != nil {
+			return -1, false
+		}
+		return valueType, true
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return -1, false
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return Bool, true
+	case reflect.Int:
+		return Int, true
+	case reflect.Float32:
+		return Float32, true
+	case reflect.Float64:
+		return Float64, true
+	case reflect.String:
+		return String, true
+	case reflect.Slice, reflect.Array:
+		return Array, true
+	case reflect.Map, reflect.Struct:
+		return Map, true
+	}
+
+	return -1, false
+}
+
+// String renders the change as lines of a unified diff, with the old value on a line starting
+// with '-' and the new value on a line starting with '+'. Values are written as they would be
+// in a document.
+func (c Change) String() string {
+	path := strings.Join(append([]string{"<root>"}, c.Path...), ".")
+
+	buf := bytes.Buffer{}
+	if c.Kind != Added {
+		fmt.Fprintf(&buf, "- %v: ", path)
+		writeDiffValue(&buf, c.Old)
+		buf.WriteByte('\n')
+	}
+	if c.Kind != Removed {
+		fmt.Fprintf(&buf, "+ %v: ", path)
+		writeDiffValue(&buf, c.New)
+		buf.WriteByte('\n')
+	}
+
+	return buf.String()
+}
+
+// WriteDiff writes the changes to the provided io.Writer as a unified diff.
+func WriteDiff(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		_, err := io.WriteString(w, c.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeDiffValue writes a value as it would appear in a document, with the entries of maps in
+// the order Diff visits them.
+func writeDiffValue(buf *bytes.Buffer, value any) {
+	valueType, ok := valueTypeOf(value)
+	if !ok {
+		fmt.Fprint(buf, value)
+		return
+	}
+
+	if raw, isRaw := value.(RawValue); isRaw {
+		buf.Write(raw)
+		return
+	}
+
+	switch valueType {
+	case Map, Array:
+		closer := byte('}')
+		if valueType == Array {
+			buf.WriteByte('[')
+			closer = ']'
+		} else {
+			buf.WriteByte('{')
+		}
+
+		for i, c := range queryChildren(value) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if valueType == Map {
+				buf.WriteString(escapeKey(c.key))
+				buf.WriteByte(':')
+			}
+			writeDiffValue(buf, c.value)
+		}
+		buf.WriteByte(closer)
+	case Null:
+		buf.WriteByte(typeMarkers[Null])
+	default:
+		err := writeValue(reflect.ValueOf(value), buf, []string{"<root>"}, false)
+		if err != nil {
+			fmt.Fprint(buf, value)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package cereal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := Parse(strings.NewReader("1{a:i1,b:{c:\"x,d:b1},e:[i1,i2,i3],f:d2}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(strings.NewReader("1{a:d1,b:{c:\"y,g:f1.5},e:[i1,i2],f:d2,h:[]}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+
+	expected := []Change{
+		{Kind: Changed, Path: []string{"a"}, Old: 1, New: float64(1), OldType: Int, NewType: Float64},
+		{Kind: Changed, Path: []string{"b", "c"}, Old: "x", New: "y", OldType: String, NewType: String},
+		{Kind: Removed, Path: []string{"b", "d"}, Old: true, OldType: Bool, NewType: -1},
+		{Kind: Added, Path: []string{"b", "g"}, New: float32(1.5), OldType: -1, NewType: Float32},
+		{Kind: Removed, Path: []string{"e", "2"}, Old: 3, OldType: Int, NewType: -1},
+		{Kind: Added, Path: []string{"h"}, New: []any{}, OldType: -1, NewType: Array},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v changes but got %v", len(expected), changes)
+	}
+	for i, e := range expected {
+		c := changes[i]
+		if c.Kind != e.Kind || strings.Join(c.Path, ".") != strings.Join(e.Path, ".") || c.OldType != e.OldType || c.NewType != e.NewType {
+			t.Errorf("expected change %v to be %+v but got %+v", i, e, c)
+		}
+		if e.Kind != Added && c.Old != e.Old {
+			t.Errorf("expected old value of change %v to be %v but got %v", i, e.Old, c.Old)
+		}
+		if e.Kind == Changed && c.New != e.New {
+			t.Errorf("expected new value of change %v to be %v but got %v", i, e.New, c.New)
+		}
+	}
+}
+
+func TestDiff_Equal(t *testing.T) {
+	a, err := Parse(strings.NewReader("1{a:i1,b:{c:[\"x,{d:b1}]}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseOrdered(strings.NewReader("1{b:{c:[\"x,{d:b1}]},a:i1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := Diff(a, b)
+	if len(changes) != 0 {
+		t.Error("expected no changes but got", changes)
+	}
+}
+
+func TestDiff_TypeChange(t *testing.T) {
+	changes := Diff(map[string]any{"a": map[string]any{"b": 1}}, map[string]any{"a": []any{1}})
+	if len(changes) != 1 {
+		t.Fatal("expected 1 change but got", changes)
+	}
+
+	c := changes[0]
+	if c.Kind != Changed || c.OldType != Map || c.NewType != Array {
+		t.Errorf("expected a map to be changed to an array but got %+v", c)
+	}
+}
+
+func TestDiff_Structs(t *testing.T) {
+	type item struct {
+		Name  string
+		Price float64
+		Tags  []string
+	}
+
+	a := item{Name: "pen", Price: 1.5, Tags: []string{"blue"}}
+	b := map[string]any{"Name": "pen", "Price": 2.0, "Tags": []any{"blue", "new"}}
+
+	var out bytes.Buffer
+	err := WriteDiff(&out, Diff(a, b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "- <root>.Price: d1.5\n+ <root>.Price: d2\n+ <root>.Tags.1: \"new\n"
+	if out.String() != expected {
+		t.Errorf("expected diff to be '%v' but got '%v'", expected, out.String())
+	}
+}
+
+func TestChange_String(t *testing.T) {
+	c := Change{
+		Kind:    Changed,
+		Path:    []string{"a"},
+		Old:     map[string]any{"y": 2, "x": []any{true}},
+		New:     RawValue("{x:[b1],y:i2}"),
+		OldType: Map,
+		NewType: Map,
+	}
+
+	expected := "- <root>.a: {x:[b1],y:i2}\n+ <root>.a: {x:[b1],y:i2}\n"
+	if c.String() != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, c.String())
+	}
+}
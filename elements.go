@@ -0,0 +1,228 @@
+package cereal
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Elements reads from the provided io.Reader and returns an iterator over the elements of
+// the array found at the given path. Each path segment is either a map key or, when the
+// enclosing value is an array, an element index. Elements are decoded one at a time so the
+// array never has to be held in memory. If an error occurs it is yielded once and the
+// iteration stops. The reader is buffered, so it may be read past the end of the document.
+func Elements(reader io.Reader, path ...string) iter.Seq2[any, error] {
+	return ElementsOf[any](reader, path...)
+}
+
+// ElementsOf behaves like Elements but decodes each element into a value of type T, using
+// the same rules as Unmarshal uses for struct fields.
+func ElementsOf[T any](reader io.Reader, path ...string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		t := newBufferedTokenizer(reader)
+
+		tok, err := t.Next()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		tok, err = seekV1(t, tok, path)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		if tok.Kind != ArrayStart {
+			yield(zero, fmt.Errorf("%v: expected an array", strings.Join(t.Path(), ".")))
+			return
+		}
+
+		for {
+			tok, err := t.Next()
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if tok.Kind == ArrayEnd {
+				return
+			}
+
+			elem := reflect.New(reflect.TypeFor[T]()).Elem()
+			err = decodeElementV1(t, tok, elem, t.Path())
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			if !yield(elem.Interface().(T), nil) {
+				return
+			}
+		}
+	}
+}
+
+// seekV1 follows the path segments starting from the value started by tok, skipping over
+// every value that is not selected, and returns the token that starts the selected value.
+func seekV1(t *Tokenizer, tok Token, segments []string) (Token, error) {
+	var err error
+	for _, segment := range segments {
+		path := t.Path()
+		switch tok.Kind {
+		case MapStart:
+			tok, err = seekKeyV1(t, segment, path)
+		case ArrayStart:
+			tok, err = seekIndexV1(t, segment, path)
+		default:
+			return tok, fmt.Errorf("%v: cannot select '%v' from a scalar value", strings.Join(path, "."), segment)
+		}
+		if err != nil {
+			return tok, err
+		}
+	}
+
+	return tok, nil
+}
+
+func seekKeyV1(t *Tokenizer, name string, path []string) (Token, error) {
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return tok, err
+		}
+		if tok.Kind == MapEnd {
+			return tok, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), name)
+		}
+
+		key := tok.Value
+		tok, err = t.Next()
+		if err != nil {
+			return tok, err
+		}
+
+		if key == name {
+			return tok, nil
+		}
+
+		err = skipValueV1(t, tok)
+		if err != nil {
+			return tok, err
+		}
+	}
+}
+
+func seekIndexV1(t *Tokenizer, segment string, path []string) (Token, error) {
+	target, err := strconv.Atoi(segment)
+	if err != nil || target < 0 {
+		return Token{}, fmt.Errorf("%v: invalid array index '%v'", strings.Join(path, "."), segment)
+	}
+
+	for index := 0; ; index++ {
+		tok, err := t.Next()
+		if err != nil {
+			return tok, err
+		}
+		if tok.Kind == ArrayEnd {
+			return tok, fmt.Errorf("%v: index %v out of range", strings.Join(path, "."), target)
+		}
+
+		if index == target {
+			return tok, nil
+		}
+
+		err = skipValueV1(t, tok)
+		if err != nil {
+			return tok, err
+		}
+	}
+}
+
+// skipValueV1 consumes the rest of the value started by tok without decoding it.
+func skipValueV1(t *Tokenizer, tok Token) error {
+	if tok.Kind == MapStart || tok.Kind == ArrayStart {
+		return t.Skip()
+	}
+
+	return nil
+}
+
+// decodeElementV1 decodes the array element started by tok into rv, using the same rules
+// as Unmarshal uses for struct fields.
+func decodeElementV1(t *Tokenizer, tok Token, rv reflect.Value, path []string) error {
+	if rv.Type() == rawValueType {
+		raw, err := readRawValueV1(t, tok)
+		if err != nil {
+			return err
+		}
+
+		rv.Set(reflect.ValueOf(raw))
+		return nil
+	}
+
+	switch tok.Kind {
+	case MapStart:
+		switch rv.Kind() {
+		case reflect.Struct:
+			return decodeStructV1(t, rv, path)
+		case reflect.Map:
+			mapValue, err := decodeTypedMapV1(t, rv.Type(), path)
+			if err != nil {
+				return err
+			}
+			rv.Set(mapValue)
+			return nil
+		case reflect.Interface:
+			result, err := decodeMapV1(t, path)
+			if err != nil {
+				return err
+			}
+			return assignElementV1(rv, reflect.ValueOf(result), path)
+		default:
+			return fmt.Errorf("%v: a struct or map cannot be assigned to element of type %v", strings.Join(path, "."), rv.Type())
+		}
+	case ArrayStart:
+		if rv.Kind() == reflect.Slice {
+			sliceValue, err := decodeTypedArrayV1(t, rv, path)
+			if err != nil {
+				return err
+			}
+			if !sliceValue.IsValid() {
+				// an empty array leaves the element as a nil slice
+				return nil
+			}
+			return assignElementV1(rv, sliceValue, path)
+		}
+
+		result, err := decodeArrayV1(t, path, false)
+		if err != nil {
+			return err
+		}
+		return assignElementV1(rv, reflect.ValueOf(result), path)
+	}
+
+	result, err := parseValue(tok.Value, tok.Type, path)
+	if err != nil {
+		return err
+	}
+
+	return assignElementV1(rv, reflect.ValueOf(result), path)
+}
+
+func assignElementV1(rv reflect.Value, value reflect.Value, path []string) error {
+	if !value.IsValid() {
+		// a null becomes the zero value of the element
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if !value.Type().AssignableTo(rv.Type()) {
+		return fmt.Errorf("%v: type %v cannot be assigned to element of type %v", strings.Join(path, "."), value.Type(), rv.Type())
+	}
+
+	rv.Set(value)
+	return nil
+}
@@ -0,0 +1,213 @@
+package cereal
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestElements(t *testing.T) {
+	count := 0
+	for elem, err := range Elements(strings.NewReader("1{a:i1,events:[i1,\"two,{x:b1}],z:b0}"), "events") {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch count {
+		case 0:
+			if elem != 1 {
+				t.Error("expected first element to be 1 but got", elem)
+			}
+		case 1:
+			if elem != "two" {
+				t.Error("expected second element to be 'two' but got", elem)
+			}
+		case 2:
+			m, ok := elem.(map[string]any)
+			if !ok || m["x"] != true {
+				t.Error("expected third element to be a map but got", elem)
+			}
+		}
+		count++
+	}
+
+	if count != 3 {
+		t.Error("expected 3 elements but got", count)
+	}
+}
+
+// readCounter counts the calls to Read of a reader that does not implement io.ByteReader.
+type readCounter struct {
+	reader io.Reader
+	reads  int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.reader.Read(p)
+}
+
+func TestElements_Buffered(t *testing.T) {
+	data := "1{events:[" + strings.Repeat("i12345,", 1000) + "]}"
+	reader := &readCounter{reader: strings.NewReader(data)}
+
+	count := 0
+	for _, err := range Elements(reader, "events") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+	}
+
+	if count != 1000 {
+		t.Error("expected 1000 elements but got", count)
+	}
+	if reader.reads > len(data)/100 {
+		t.Errorf("expected the reader to be buffered but it was read %v times for %v bytes", reader.reads, len(data))
+	}
+}
+
+func TestElements_Nested(t *testing.T) {
+	var result []any
+	for elem, err := range Elements(strings.NewReader("1{skip:{a:[i1,i2]},data:[[\"a,\"b],[\"c\\,d,\"e]]}"), "data", "1") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, elem)
+	}
+
+	if len(result) != 2 || result[0] != "c,d" || result[1] != "e" {
+		t.Error("expected [c,d e] but got", result)
+	}
+}
+
+func TestElements_Empty(t *testing.T) {
+	for elem, err := range Elements(strings.NewReader("1{events:[]}"), "events") {
+		t.Error("expected no elements but got", elem, err)
+	}
+}
+
+func TestElements_StopEarly(t *testing.T) {
+	count := 0
+	for _, err := range Elements(strings.NewReader("1{events:[i1,i2,i3"), "events") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		if count == 2 {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Error("expected 2 elements but got", count)
+	}
+}
+
+func TestElements_KeyNotFound(t *testing.T) {
+	for _, err := range Elements(strings.NewReader("1{a:i1,b:[i1]}"), "events") {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		msg := err.Error()
+		if msg != "<root>: key 'events' not found" {
+			t.Error("expected error to be \"<root>: key 'events' not found\" but got", msg)
+		}
+	}
+}
+
+func TestElements_NotAnArray(t *testing.T) {
+	for _, err := range Elements(strings.NewReader("1{a:{b:i1}}"), "a") {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		msg := err.Error()
+		if msg != "<root>.a: expected an array" {
+			t.Error("expected error to be '<root>.a: expected an array' but got", msg)
+		}
+	}
+}
+
+func TestElements_IndexOutOfRange(t *testing.T) {
+	for _, err := range Elements(strings.NewReader("1{a:[[i1],[i2]]}"), "a", "2") {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		msg := err.Error()
+		if msg != "<root>.a: index 2 out of range" {
+			t.Error("expected error to be '<root>.a: index 2 out of range' but got", msg)
+		}
+	}
+}
+
+func TestElements_BadValue(t *testing.T) {
+	var errs []error
+	for elem, err := range Elements(strings.NewReader("1{a:[i1,ix]}"), "a") {
+		if err != nil {
+			errs = append(errs, err)
+		} else if elem != 1 {
+			t.Error("expected element to be 1 but got", elem)
+		}
+	}
+
+	if len(errs) != 1 {
+		t.Fatal("expected a single error but got", errs)
+	}
+
+	msg := errs[0].Error()
+	if msg != "<root>.a.1: invalid int 'x'" {
+		t.Error("expected error to be \"<root>.a.1: invalid int 'x'\" but got", msg)
+	}
+}
+
+func TestElementsOf_Struct(t *testing.T) {
+	type Event struct {
+		Name  string
+		Count int
+	}
+
+	var events []Event
+	for event, err := range ElementsOf[Event](strings.NewReader("1{events:[{Name:\"a,Count:i1},{Name:\"b,Count:i2}]}"), "events") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+
+	if len(events) != 2 {
+		t.Fatal("expected 2 events but got", len(events))
+	}
+	if events[0].Name != "a" || events[0].Count != 1 || events[1].Name != "b" || events[1].Count != 2 {
+		t.Error("unexpected events", events)
+	}
+}
+
+func TestElementsOf_Scalar(t *testing.T) {
+	sum := 0
+	for n, err := range ElementsOf[int](strings.NewReader("1{n:[i1,i2,i3]}"), "n") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum += n
+	}
+
+	if sum != 6 {
+		t.Error("expected sum to be 6 but got", sum)
+	}
+}
+
+func TestElementsOf_WrongType(t *testing.T) {
+	for _, err := range ElementsOf[string](strings.NewReader("1{n:[i1]}"), "n") {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+
+		msg := err.Error()
+		if msg != "<root>.n.0: type int cannot be assigned to element of type string" {
+			t.Error("expected error to be '<root>.n.0: type int cannot be assigned to element of type string' but got", msg)
+		}
+	}
+}
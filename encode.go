@@ -0,0 +1,47 @@
+package cereal
+
+import (
+	"bytes"
+	"io"
+)
+
+// Encoder writes serialized documents to an output stream.
+type Encoder struct {
+	writer  io.Writer
+	version string
+	prefix  string
+	indent  string
+}
+
+// NewEncoder returns an Encoder that writes documents of the given version to the provided
+// io.Writer.
+func NewEncoder(writer io.Writer, version string) *Encoder {
+	return &Encoder{writer: writer, version: version}
+}
+
+// SetIndent instructs the encoder to format each document as if by Indent. Calling it with
+// an empty indent disables indentation.
+func (e *Encoder) SetIndent(prefix, indent string) {
+	e.prefix = prefix
+	e.indent = indent
+}
+
+// Encode serializes the value and writes it to the stream.
+func (e *Encoder) Encode(value any) error {
+	b, err := Serialize(value, e.version)
+	if err != nil {
+		return err
+	}
+
+	if e.prefix != "" || e.indent != "" {
+		buf := bytes.Buffer{}
+		err = Indent(&buf, b, e.prefix, e.indent)
+		if err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+
+	_, err = e.writer.Write(b)
+	return err
+}
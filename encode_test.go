@@ -0,0 +1,50 @@
+package cereal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := NewEncoder(&buf, "1").Encode(map[string]any{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "1{a:i1}" {
+		t.Error("expected '1{a:i1}' but got", buf.String())
+	}
+}
+
+func TestEncoder_SetIndent(t *testing.T) {
+	buf := bytes.Buffer{}
+	encoder := NewEncoder(&buf, "1")
+	encoder.SetIndent("", "  ")
+
+	err := encoder.Encode(map[string]any{"a": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n  a:[\n    i1,\n    i2,\n  ],\n}"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.String())
+	}
+}
+
+func TestEncoder_InvalidVersion(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := NewEncoder(&buf, "0").Encode(map[string]any{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	msg := err.Error()
+	if msg != "invalid version 0" {
+		t.Error("expected error to be 'invalid version 0' but got", msg)
+	}
+	if buf.Len() != 0 {
+		t.Error("expected nothing to be written but got", buf.String())
+	}
+}
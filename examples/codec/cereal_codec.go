@@ -0,0 +1,119 @@
+// Code generated by cerealcodec. DO NOT EDIT.
+
+package main
+
+import "github.com/snocorp/cereal"
+
+// MarshalCereal implements cereal.Marshaler.
+func (v Example) MarshalCereal() ([]byte, error) {
+	w := &cereal.ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *Example) writeCereal(w *cereal.ValueWriter) error {
+	w.BeginMap()
+	w.Key("key")
+	w.String(v.Key)
+	w.Key("num")
+	w.Int(v.Num)
+	w.Key("tags")
+	if v.Tags == nil {
+		w.Null()
+	} else {
+		w.BeginArray()
+		for _, e := range v.Tags {
+			w.String(e)
+		}
+		w.EndArray()
+	}
+	w.Key("owner")
+	if err := v.Owner.writeCereal(w); err != nil {
+		return err
+	}
+	w.EndMap()
+	return nil
+}
+
+// UnmarshalCereal implements cereal.Unmarshaler.
+func (v *Example) UnmarshalCereal(data []byte) error {
+	return v.readCereal(cereal.NewValueReader(data))
+}
+
+func (v *Example) readCereal(r *cereal.ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "key":
+			err = r.String(&v.Key)
+		case "num":
+			err = r.Int(&v.Num)
+		case "tags":
+			err = cereal.ReadSlice(r, &v.Tags, r.String)
+		case "owner":
+			err = v.Owner.readCereal(r)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// MarshalCereal implements cereal.Marshaler.
+func (v Owner) MarshalCereal() ([]byte, error) {
+	w := &cereal.ValueWriter{}
+	err := v.writeCereal(w)
+	return w.Bytes(), err
+}
+
+func (v *Owner) writeCereal(w *cereal.ValueWriter) error {
+	w.BeginMap()
+	w.Key("name")
+	w.String(v.Name)
+	w.Key("score")
+	w.Float64(v.Score)
+	w.EndMap()
+	return nil
+}
+
+// UnmarshalCereal implements cereal.Unmarshaler.
+func (v *Owner) UnmarshalCereal(data []byte) error {
+	return v.readCereal(cereal.NewValueReader(data))
+}
+
+func (v *Owner) readCereal(r *cereal.ValueReader) error {
+	ok, err := r.BeginMap()
+	if err != nil || !ok {
+		return err
+	}
+
+	for {
+		key, ok, err := r.NextKey()
+		if err != nil || !ok {
+			return err
+		}
+
+		switch key {
+		case "name":
+			err = r.String(&v.Name)
+		case "score":
+			err = r.Float64(&v.Score)
+		default:
+			err = r.UnexpectedKey(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+//go:generate go run github.com/snocorp/cereal/cmd/cerealcodec
+
+import (
+	"fmt"
+
+	"github.com/snocorp/cereal"
+)
+
+//cereal:codec
+type Example struct {
+	Key     string   `cereal:"key"`
+	Num     int      `cereal:"num"`
+	Tags    []string `cereal:"tags"`
+	Owner   Owner    `cereal:"owner"`
+	Ignored string   `cereal:"-"`
+}
+
+//cereal:codec
+type Owner struct {
+	Name  string  `cereal:"name"`
+	Score float64 `cereal:"score"`
+}
+
+func main() {
+	serialized := []byte("1{key:\"value,num:i42,tags:[\"a,\"b],owner:{name:\"Sam,score:d9.5}}")
+
+	example := Example{}
+	err := cereal.Unmarshal(serialized, &example)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println("Parsed:", example)
+	// Parsed: {value 42 [a b] {Sam 9.5} }
+
+	data, err := cereal.Serialize(example, "1")
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	fmt.Println("Serialized:", string(data))
+	// Serialized: 1{key:"value,num:i42,tags:["a,"b],owner:{name:"Sam,score:d9.5}}
+}
@@ -0,0 +1,139 @@
+package cereal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Compact appends to dst the document in src with all insignificant whitespace and comments
+// removed.
+func Compact(dst *bytes.Buffer, src []byte) error {
+	t := NewTokenizer(bytes.NewReader(src))
+	t.ReadToEnd()
+	out := bytes.Buffer{}
+
+	var prev Token
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if out.Len() == 0 {
+			out.WriteByte(t.Version())
+		}
+		if tok.Kind == Scalar {
+			// a document is only reformatted if it is valid
+			_, err = parseValue(tok.Value, tok.Type, t.Path())
+			if err != nil {
+				return err
+			}
+		}
+
+		switch tok.Kind {
+		case Key:
+			if prev.Kind != MapStart {
+				out.WriteByte(',')
+			}
+			out.WriteString(tok.Raw)
+			out.WriteByte(':')
+		case MapEnd, ArrayEnd:
+			out.WriteString(tok.Raw)
+		default:
+			if prev.Kind != Key && prev.Kind != ArrayStart && t.Depth() > 1 {
+				out.WriteByte(',')
+			}
+			out.WriteString(tok.Raw)
+		}
+
+		prev = tok
+	}
+
+	dst.Write(out.Bytes())
+	return nil
+}
+
+// Indent appends to dst an indented form of the document in src. Each entry of a map and each
+// element of an array begins on a new line starting with prefix followed by one or more copies
+// of indent according to its nesting, and is followed by a comma. Empty maps and arrays are
+// left on one line. Comments are kept, including those before and after the root map, either
+// on a line of their own or, if they followed other tokens on the same line in src, at the
+// end of the line.
+func Indent(dst *bytes.Buffer, src []byte, prefix, indent string) error {
+	t := NewTokenizer(bytes.NewReader(src))
+	t.EmitComments()
+	t.ReadToEnd()
+	out := bytes.Buffer{}
+
+	newline := func(depth int) {
+		out.WriteByte('\n')
+		out.WriteString(prefix)
+		out.WriteString(strings.Repeat(indent, depth))
+	}
+
+	var prev Token
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if out.Len() == 0 {
+			out.WriteByte(t.Version())
+		}
+		if tok.Kind == Scalar {
+			// a document is only reformatted if it is valid
+			_, err = parseValue(tok.Value, tok.Type, t.Path())
+			if err != nil {
+				return err
+			}
+		}
+
+		switch tok.Kind {
+		case Comment:
+			if prev.Kind != Comment && !bytes.ContainsRune(src[prev.End:tok.Offset], '\n') {
+				out.WriteByte(' ')
+			} else {
+				newline(t.Depth())
+			}
+			out.WriteByte('#')
+			out.WriteString(tok.Value)
+		case Key:
+			newline(t.Depth())
+			out.WriteString(tok.Raw)
+			out.WriteByte(':')
+		case MapEnd, ArrayEnd:
+			if prev.Kind != MapStart && prev.Kind != ArrayStart {
+				newline(t.Depth())
+			}
+			out.WriteString(tok.Raw)
+			if t.Depth() > 0 {
+				out.WriteByte(',')
+			}
+		case MapStart, ArrayStart:
+			if (prev.Kind != Key && t.Depth() > 1) || prev.Kind == Comment {
+				newline(t.Depth() - 1)
+			}
+			out.WriteString(tok.Raw)
+		case Scalar:
+			// a value stays on the line of its key unless a comment came between them
+			if prev.Kind != Key {
+				newline(t.Depth())
+			}
+			out.WriteString(tok.Raw)
+			out.WriteByte(',')
+		}
+
+		prev = tok
+	}
+
+	dst.Write(out.Bytes())
+	return nil
+}
@@ -0,0 +1,235 @@
+package cereal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIndent(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1{key:\"value,m:{a:[i1,{b:b1},[]],e:{}},n:i2}"), "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n" +
+		"\tkey:\"value,\n" +
+		"\tm:{\n" +
+		"\t\ta:[\n" +
+		"\t\t\ti1,\n" +
+		"\t\t\t{\n" +
+		"\t\t\t\tb:b1,\n" +
+		"\t\t\t},\n" +
+		"\t\t\t[],\n" +
+		"\t\t],\n" +
+		"\t\te:{},\n" +
+		"\t},\n" +
+		"\tn:i2,\n" +
+		"}"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.String())
+	}
+
+	m, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["key"] != "value" || m["n"] != 2 {
+		t.Error("unexpected result", m)
+	}
+}
+
+func TestIndent_Prefix(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1{a:[i1]}"), "> ", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n>   a:[\n>     i1,\n>   ],\n> }"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.String())
+	}
+}
+
+func TestIndent_Empty(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1{}"), "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "1{}" {
+		t.Error("expected '1{}' but got", buf.String())
+	}
+}
+
+func TestIndent_Invalid(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1{a:[i1"), "", "\t")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if buf.Len() != 0 {
+		t.Error("expected nothing to be written but got", buf.String())
+	}
+}
+
+func TestIndent_InvalidValues(t *testing.T) {
+	for _, input := range []string{"1{a:[i1,i2 ]}", "1{a:bx}", "1{a:{b:d1.5.5}}", "1{a:nx}"} {
+		expected := Validate(strings.NewReader(input))
+		if expected == nil {
+			t.Fatalf("expected %v to be invalid", input)
+		}
+
+		buf := bytes.Buffer{}
+		err := Indent(&buf, []byte(input), "", "\t")
+		if err == nil || err.Error() != expected.Error() {
+			t.Errorf("expected Indent to fail with '%v' for %v but got %v", expected, input, err)
+		}
+
+		err = Compact(&buf, []byte(input))
+		if err == nil || err.Error() != expected.Error() {
+			t.Errorf("expected Compact to fail with '%v' for %v but got %v", expected, input, err)
+		}
+
+		if buf.Len() != 0 {
+			t.Error("expected nothing to be written but got", buf.String())
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Compact(&buf, []byte("1{\n  key:\"value,\n  m: {\n    a: [ i1, {b:b1},\n [ ] ],\r\n\t},\n,\n  n:i2,\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{key:\"value,m:{a:[i1,{b:b1},[]]},n:i2}"
+	if buf.String() != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, buf.String())
+	}
+}
+
+func TestCompact_RoundTrip(t *testing.T) {
+	input := "1{a:{b:[d1.5,\"x\\,y],c:{}},\\ d:f2}"
+
+	indented := bytes.Buffer{}
+	err := Indent(&indented, []byte(input), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compacted := bytes.Buffer{}
+	err = Compact(&compacted, indented.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if compacted.String() != input {
+		t.Errorf("expected '%v' but got '%v'", input, compacted.String())
+	}
+}
+
+func TestParse_Whitespace(t *testing.T) {
+	m, err := Parse(bytes.NewBufferString("1{ a : i1,\n\t\\ b:[ b1,\n b0,\n ],}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m["a "] != 1 {
+		t.Error("expected 'a ' to be 1 but got", m["a "])
+	}
+
+	arr, ok := m[" b"].([]any)
+	if !ok || len(arr) != 2 || arr[0] != true || arr[1] != false {
+		t.Error("expected ' b' to be [true false] but got", m[" b"])
+	}
+}
+
+func TestIndent_Comments(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1{\n# settings\na:i1,# inline\nb: # after key\n[i2],m:{ # open\n}}"), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n" +
+		"  # settings\n" +
+		"  a:i1, # inline\n" +
+		"  b: # after key\n" +
+		"  [\n" +
+		"    i2,\n" +
+		"  ],\n" +
+		"  m:{ # open\n" +
+		"  },\n" +
+		"}"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.String())
+	}
+
+	again := bytes.Buffer{}
+	err = Indent(&again, buf.Bytes(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != expected {
+		t.Errorf("expected indenting to be stable but got\n%v", again.String())
+	}
+}
+
+func TestIndent_CommentsAroundRoot(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Indent(&buf, []byte("1 # header\n{a:i1} # trailing\n\n# end\n"), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1 # header\n" +
+		"{\n" +
+		"  a:i1,\n" +
+		"} # trailing\n" +
+		"# end"
+	if buf.String() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, buf.String())
+	}
+
+	again := bytes.Buffer{}
+	err = Indent(&again, buf.Bytes(), "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.String() != expected {
+		t.Errorf("expected indenting to be stable but got\n%v", again.String())
+	}
+
+	err = Indent(&bytes.Buffer{}, []byte("1{a:i1}\nGARBAGE"), "", "  ")
+	if err == nil || err.Error() != "unexpected data after the root map" {
+		t.Error("expected error to be 'unexpected data after the root map' but got", err)
+	}
+}
+
+func TestCompact_Comments(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := Compact(&buf, []byte("1{\n  # settings\n  a:i1, # inline\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "1{a:i1}" {
+		t.Error("expected '1{a:i1}' but got", buf.String())
+	}
+
+	buf.Reset()
+	err = Compact(&buf, []byte("1\n# header\n{a:i1}\n# end\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.String() != "1{a:i1}" {
+		t.Error("expected '1{a:i1}' but got", buf.String())
+	}
+}
@@ -0,0 +1,208 @@
+package cereal
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxEnumValues is the most distinct strings that are inferred as an enum.
+	maxEnumValues = 8
+	// minEnumRepeats is how many times each distinct string must be seen on average before
+	// the strings are inferred as an enum.
+	minEnumRepeats = 2
+)
+
+// inferred accumulates the values observed at one path of the samples.
+type inferred struct {
+	types []ValueType
+
+	// maps counts the maps observed, and presence counts the maps each field was found in.
+	maps     int
+	fields   []string
+	children map[string]*inferred
+	presence map[string]int
+
+	items *inferred
+
+	strings     map[string]int
+	stringOrder []string
+}
+
+// InferSchema returns a schema that every sample matches, built from the values observed at
+// each path of the samples, such as the results of Parse. A field that is missing from some
+// of the maps at its path is optional, and a path where values of more than one type are
+// observed, such as an array of mixed elements, has the type "any". Strings that take only a
+// few distinct values, each seen several times, are given an enum.
+func InferSchema(samples ...any) *Schema {
+	root := &inferred{}
+	for _, sample := range samples {
+		root.observe(sample)
+	}
+
+	return root.schema()
+}
+
+func (n *inferred) observe(value any) {
+	valueType, ok := valueTypeOf(value)
+	if !ok {
+		return
+	}
+	if !slices.Contains(n.types, valueType) {
+		n.types = append(n.types, valueType)
+	}
+
+	switch valueType {
+	case Map:
+		if n.children == nil {
+			n.children = map[string]*inferred{}
+			n.presence = map[string]int{}
+		}
+
+		n.maps++
+		for _, c := range queryChildren(value) {
+			child, ok := n.children[c.key]
+			if !ok {
+				child = &inferred{}
+				n.children[c.key] = child
+				n.fields = append(n.fields, c.key)
+			}
+
+			child.observe(c.value)
+			n.presence[c.key]++
+		}
+	case Array:
+		if n.items == nil {
+			n.items = &inferred{}
+		}
+
+		for _, c := range queryChildren(value) {
+			n.items.observe(c.value)
+		}
+	case String:
+		if n.strings == nil {
+			n.strings = map[string]int{}
+		}
+
+		s := value.(string)
+		if _, ok := n.strings[s]; !ok {
+			n.stringOrder = append(n.stringOrder, s)
+		}
+		n.strings[s]++
+	}
+}
+
+func (n *inferred) schema() *Schema {
+	if len(n.types) != 1 {
+		return &Schema{Type: "any"}
+	}
+
+	s := &Schema{Type: schemaTypeName(n.types[0])}
+	switch n.types[0] {
+	case Map:
+		s.Fields = []SchemaField{}
+		for _, name := range n.fields {
+			field := n.children[name].schema()
+			field.Optional = n.presence[name] < n.maps
+			s.Fields = append(s.Fields, SchemaField{Name: name, Schema: field})
+		}
+	case Array:
+		if len(n.items.types) > 0 {
+			s.Items = n.items.schema()
+		}
+	case String:
+		total := 0
+		for _, count := range n.strings {
+			total += count
+		}
+
+		if len(n.stringOrder) <= maxEnumValues && total >= minEnumRepeats*len(n.stringOrder) {
+			for _, value := range n.stringOrder {
+				s.Enum = append(s.Enum, value)
+			}
+		}
+	}
+
+	return s
+}
+
+// SchemaOf returns the schema of the values that Serialize writes for the type of v, which
+// may be a nil pointer to the type. Structs become maps whose fields are all required and
+// follow the cereal tags, maps with string keys become maps with values, slices become
+// arrays, and interfaces and RawValue have the type "any".
+func SchemaOf(v any) (*Schema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("<root>: unsupported type <nil>")
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return schemaOfType(t, []string{"<root>"}, map[reflect.Type]bool{})
+}
+
+func schemaOfType(t reflect.Type, path []string, visiting map[reflect.Type]bool) (*Schema, error) {
+	switch t {
+	case rawValueType:
+		return &Schema{Type: "any"}, nil
+	case reflect.TypeFor[OrderedMap](), reflect.TypeFor[*OrderedMap]():
+		return &Schema{Type: "map"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Float32, reflect.Float64, reflect.String:
+		return &Schema{Type: t.Kind().String()}, nil
+	case reflect.Interface:
+		return &Schema{Type: "any"}, nil
+	case reflect.Slice:
+		s := &Schema{Type: "array"}
+		if t.Elem().Kind() != reflect.Interface {
+			items, err := schemaOfType(t.Elem(), appendPath(path, "0"), visiting)
+			if err != nil {
+				return nil, err
+			}
+			s.Items = items
+		}
+		return s, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%v: map key type must be string, not %v", strings.Join(path, "."), t.Key().Kind())
+		}
+
+		s := &Schema{Type: "map"}
+		if t.Elem().Kind() != reflect.Interface {
+			values, err := schemaOfType(t.Elem(), path, visiting)
+			if err != nil {
+				return nil, err
+			}
+			s.Values = values
+		}
+		return s, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return nil, fmt.Errorf("%v: recursive type %v is not supported", strings.Join(path, "."), t)
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		s := &Schema{Type: "map", Fields: []SchemaField{}}
+		for _, f := range reflect.VisibleFields(t) {
+			name, ok := structFieldKey(f)
+			if !ok {
+				continue
+			}
+
+			field, err := schemaOfType(f.Type, appendPath(path, name), visiting)
+			if err != nil {
+				return nil, err
+			}
+			s.Fields = append(s.Fields, SchemaField{Name: name, Schema: field})
+		}
+		return s, nil
+	}
+
+	return nil, fmt.Errorf("%v: unsupported type %v", strings.Join(path, "."), t)
+}
@@ -0,0 +1,136 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInferSchema(t *testing.T) {
+	samples := []any{}
+	for _, input := range []string{
+		"1{name:\"a,mode:\"dev,ports:[i80],ratio:f0.5,meta:{owner:\"x}}",
+		"1{name:\"b,mode:\"dev,ports:[i81,\"http],ratio:f1,meta:{}}",
+		"1{name:\"c,mode:\"prod,ports:[],ratio:f2,debug:b1,meta:{owner:\"y}}",
+		"1{name:\"d,mode:\"prod,ports:[i82],ratio:d2,meta:{owner:\"z}}",
+	} {
+		sample, err := ParseOrdered(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, sample)
+	}
+
+	s := InferSchema(samples...)
+
+	data, err := SerializeSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{type:\"map,fields:{" +
+		"name:{type:\"string}," +
+		"mode:{type:\"string,enum:[\"dev,\"prod]}," +
+		"ports:{type:\"array,items:{type:\"any}}," +
+		"ratio:{type:\"any}," +
+		"meta:{type:\"map,fields:{owner:{type:\"string,optional:b1}}}," +
+		"debug:{type:\"bool,optional:b1}}}"
+	if string(data) != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, string(data))
+	}
+
+	for i, sample := range samples {
+		if violations := ValidateSchema(sample, s); len(violations) > 0 {
+			t.Errorf("expected sample %v to match the inferred schema but got %v", i, violations)
+		}
+	}
+}
+
+func TestInferSchema_Arrays(t *testing.T) {
+	s := InferSchema(
+		map[string]any{"a": []any{1, 2}, "b": []any{}},
+		map[string]any{"a": []any{3}, "b": []any{}},
+	)
+
+	if a := s.Field("a"); a.Type != "array" || a.Items == nil || a.Items.Type != "int" {
+		t.Errorf("expected an array of int but got %+v", a)
+	}
+	if b := s.Field("b"); b.Type != "array" || b.Items != nil {
+		t.Errorf("expected an array without items but got %+v", b)
+	}
+}
+
+func TestSerializeSchema(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := SerializeSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseSchema(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := SerializeSchema(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("expected the schema to be read back as '%v' but got '%v'", string(data), string(again))
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type address struct {
+		Street string `cereal:"street"`
+	}
+	type person struct {
+		Name      string             `cereal:"name"`
+		Age       int                `cereal:"age"`
+		Height    float32            `cereal:"height"`
+		Addresses []address          `cereal:"addresses"`
+		Labels    map[string]float64 `cereal:"labels"`
+		Extra     any                `cereal:"extra"`
+		Ignored   bool               `cereal:"-"`
+	}
+
+	s, err := SchemaOf((*person)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := SerializeSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `1{type:"map,fields:{name:{type:"string},age:{type:"int},height:{type:"float32},` +
+		`addresses:{type:"array,items:{type:"map,fields:{street:{type:"string}}}},` +
+		`labels:{type:"map,values:{type:"float64}},extra:{type:"any}}}`
+	if string(data) != expected {
+		t.Errorf("expected %v but got %v", expected, string(data))
+	}
+}
+
+func TestSchemaOf_Errors(t *testing.T) {
+	type node struct {
+		Children []node
+	}
+	type pointer struct {
+		Next *int
+	}
+
+	_, err := SchemaOf(node{})
+	if err == nil || err.Error() != "<root>.Children.0: recursive type cereal.node is not supported" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = SchemaOf(pointer{})
+	if err == nil || err.Error() != "<root>.Next: unsupported type *int" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Package cli implements the subcommands of the cereal command, which the older single
+// purpose commands also run.
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snocorp/cereal"
+)
+
+// Exit codes shared by every subcommand.
+const (
+	// ExitOK means the command succeeded.
+	ExitOK = 0
+	// ExitFailed means the input was read but did not pass, such as an invalid document,
+	// documents that differ or a query without results.
+	ExitFailed = 1
+	// ExitError means the command could not run, such as for bad arguments or unreadable
+	// input.
+	ExitError = 2
+)
+
+// command is a subcommand of the cereal command.
+type command struct {
+	name        string
+	args        string
+	description string
+	run         func(fs *flag.FlagSet, args []string) int
+}
+
+var commands = []command{
+	{"fmt", "[-indent string] [-prefix string] [-compact] [-w] <file>...", "format documents", runFmt},
+	{"validate", "[-schema file] <file>...", "check that documents are well-formed and match a schema", runValidate},
+	{"get", "[-json] <query> <file>...", "print the values selected by a query", runGet},
+	{"convert", "[-to json|cereal] [-typed] [-float32] [-schema file] [-root key] [-lines] [-workers n] <file>...", "convert between cereal and JSON", runConvert},
+	{"diff", "<file> <file>", "print the differences between two documents", runDiff},
+	{"stats", "<file>...", "count the values in documents", runStats},
+	{"schema", "[-json] <file>...", "infer a schema from sample documents", runSchema},
+	{"yaml2cereal", "<file>...", "convert YAML to cereal", fromFormat(cereal.ConvertYAML)},
+	{"cereal2yaml", "<file>...", "convert cereal to YAML", toFormat(cereal.MarshalYAML, "---\n")},
+	{"toml2cereal", "<file>...", "convert TOML to cereal", fromFormat(cereal.ConvertTOML)},
+	{"cereal2toml", "<file>...", "convert cereal to TOML", toFormat(cereal.MarshalTOML, "\n")},
+	{"csv2cereal", "[-comma c] [-array-sep s] [-schema file] <file>...", "convert CSV rows to one document per line", runCSV2Cereal},
+	{"cereal2csv", "[-comma c] [-array-sep s] [-columns names] <file>", "convert one document per line to CSV rows", runCereal2CSV},
+	{"msgpack2cereal", "<file>...", "convert MessagePack to cereal", fromFormat(cereal.ConvertMsgPack)},
+	{"cereal2msgpack", "<file>...", "convert cereal to MessagePack", toFormat(cereal.MarshalMsgPack, "")},
+	{"cbor2cereal", "<file>...", "convert CBOR to cereal", fromFormat(cereal.ConvertCBOR)},
+	{"cereal2cbor", "<file>...", "convert cereal to CBOR", toFormat(cereal.MarshalCBOR, "")},
+}
+
+// Run runs the subcommand named by the first argument and returns the exit code.
+func Run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return ExitError
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+
+		fs := flag.NewFlagSet("cereal "+c.name, flag.ContinueOnError)
+		fs.Usage = func() {
+			fmt.Fprintf(os.Stderr, "Usage: cereal %v %v\n", c.name, c.args)
+			fs.PrintDefaults()
+		}
+
+		return c.run(fs, args[1:])
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		printUsage()
+		return ExitOK
+	}
+
+	fmt.Fprintf(os.Stderr, "cereal: unknown command '%v'\n", args[0])
+	printUsage()
+	return ExitError
+}
+
+// RunLegacy runs a subcommand like Run, but returns 1 for every failure, as the single
+// purpose commands did before they ran the subcommands of the cereal command.
+func RunLegacy(args []string) int {
+	code := Run(args)
+	if code != ExitOK {
+		return ExitFailed
+	}
+
+	return code
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: cereal <command> [flags] <file>...\n\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-15v %v\n", c.name, c.description)
+	}
+	fmt.Fprintln(os.Stderr, "\nA file may be a glob pattern, or - for the standard input.")
+}
+
+// parseFlags parses the flags of a subcommand, returning false along with the exit code if
+// the command should not run.
+func parseFlags(fs *flag.FlagSet, args []string) (bool, int) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return false, ExitOK
+	}
+	if err != nil {
+		return false, ExitError
+	}
+
+	return true, ExitOK
+}
+
+// usageError prints the usage of a subcommand and returns the exit code for bad arguments.
+func usageError(fs *flag.FlagSet) int {
+	fs.Usage()
+	return ExitError
+}
+
+// input is the content of a file named on the command line.
+type input struct {
+	name string
+	data []byte
+}
+
+// readInputs reads each file, expanding glob patterns and reading the standard input for
+// "-". The whole input is read before it is used, since the size of the standard input is
+// not known in advance.
+func readInputs(patterns []string) ([]input, error) {
+	names, err := expandPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := []input{}
+	for _, name := range names {
+		var data []byte
+		if name == stdinName {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("%v: input is empty", name)
+		}
+
+		inputs = append(inputs, input{name: name, data: data})
+	}
+
+	return inputs, nil
+}
+
+// stdinName is the name of the standard input in messages.
+const stdinName = "<stdin>"
+
+// expandPatterns returns the files named by the arguments, expanding glob patterns and
+// replacing "-" with the name of the standard input.
+func expandPatterns(patterns []string) ([]string, error) {
+	names := []string{}
+	for _, pattern := range patterns {
+		if pattern == "-" {
+			names = append(names, stdinName)
+			continue
+		}
+
+		if !strings.ContainsAny(pattern, "*?[") {
+			names = append(names, pattern)
+			continue
+		}
+
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", pattern, err)
+		}
+		if len(filenames) == 0 {
+			return nil, fmt.Errorf("%v: no files match", pattern)
+		}
+		names = append(names, filenames...)
+	}
+
+	return names, nil
+}
+
+// openInput opens a file returned by expandPatterns for streaming.
+func openInput(name string) (io.ReadCloser, error) {
+	if name == stdinName {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(name)
+}
+
+// readFlagInputs reads the files named by the arguments of a subcommand, printing any error.
+func readFlagInputs(fs *flag.FlagSet, args []string) ([]input, int) {
+	if len(args) == 0 {
+		return nil, usageError(fs)
+	}
+
+	inputs, err := readInputs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, ExitError
+	}
+
+	return inputs, ExitOK
+}
+
+// readSchema reads a cereal schema, or a JSON Schema if the file starts with an object.
+func readSchema(filename string) (*cereal.Schema, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return cereal.ParseJSONSchema(bytes.NewReader(data))
+	}
+
+	return cereal.ParseSchema(bytes.NewReader(data))
+}
+
+// encodeValue returns a value as it would appear in a document.
+func encodeValue(value any) ([]byte, error) {
+	if value == nil {
+		return []byte("n"), nil
+	}
+
+	data, err := cereal.Serialize(value, "1")
+	if err != nil {
+		return nil, err
+	}
+
+	return data[1:], nil
+}
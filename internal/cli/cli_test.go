@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// run runs the cereal command with the arguments, giving it stdin as the standard input, and
+// returns its exit code along with what it wrote to the standard output and error.
+func run(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	return capture(t, stdin, func() int { return Run(args) })
+}
+
+// capture calls f with stdin as the standard input, and returns its result along with what
+// it wrote to the standard output and error.
+func capture(t *testing.T, stdin string, f func() int) (int, string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := [3]*os.File{}
+	for i, name := range []string{"stdin", "stdout", "stderr"} {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		files[i] = file
+	}
+
+	_, err := files[0].WriteString(stdin)
+	if err == nil {
+		_, err = files[0].Seek(0, 0)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origStdin, origStdout, origStderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = files[0], files[1], files[2]
+	code := f()
+	os.Stdin, os.Stdout, os.Stderr = origStdin, origStdout, origStderr
+
+	stdout, err := os.ReadFile(files[1].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.ReadFile(files[2].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return code, string(stdout), string(stderr)
+}
+
+// writeFiles writes files named by the keys of contents to a new directory, and makes it the
+// working directory for the rest of the test.
+func writeFiles(t *testing.T, contents map[string]string) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for name, content := range contents {
+		err := os.WriteFile(name, []byte(content), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// cliTest is a run of the cereal command and what it is expected to do. The errors written
+// must contain stderr, or be empty if it is.
+type cliTest struct {
+	args   []string
+	stdin  string
+	code   int
+	stdout string
+	stderr string
+}
+
+func runTests(t *testing.T, tests map[string]cliTest) {
+	t.Helper()
+	for name, test := range tests {
+		code, stdout, stderr := run(t, test.stdin, test.args...)
+		if code != test.code {
+			t.Errorf("%v: expected exit code %v but got %v", name, test.code, code)
+		}
+		if stdout != test.stdout {
+			t.Errorf("%v: expected output %q but got %q", name, test.stdout, stdout)
+		}
+		if !strings.Contains(stderr, test.stderr) || (test.stderr == "" && stderr != "") {
+			t.Errorf("%v: expected errors %q but got %q", name, test.stderr, stderr)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	runTests(t, map[string]cliTest{
+		"no command":      {args: nil, code: ExitError, stderr: "Usage: cereal <command>"},
+		"unknown command": {args: []string{"frobnicate"}, code: ExitError, stderr: "cereal: unknown command 'frobnicate'"},
+		"help":            {args: []string{"help"}, code: ExitOK, stderr: "Usage: cereal <command>"},
+		"command help":    {args: []string{"fmt", "-h"}, code: ExitOK, stderr: "Usage: cereal fmt"},
+		"unknown flag":    {args: []string{"fmt", "-frobnicate"}, code: ExitError, stderr: "flag provided but not defined: -frobnicate"},
+		"no files":        {args: []string{"stats"}, code: ExitError, stderr: "Usage: cereal stats"},
+		"missing file":    {args: []string{"stats", "missing.cereal"}, code: ExitError, stderr: "open missing.cereal: no such file or directory"},
+		"no glob matches": {args: []string{"stats", "missing*.cereal"}, code: ExitError, stderr: "missing*.cereal: no files match"},
+	})
+}
+
+func TestRun_Stdin(t *testing.T) {
+	code, stdout, stderr := run(t, "1{a:i1}", "stats", "-")
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("expected success but got %v: %v", code, stderr)
+	}
+	if !strings.HasPrefix(stdout, "<stdin>:\n") {
+		t.Errorf("expected the standard input to be named <stdin> but got %q", stdout)
+	}
+
+	code, _, stderr = run(t, "", "stats", "-")
+	if code != ExitError || stderr != "<stdin>: input is empty\n" {
+		t.Errorf("expected an error for empty input but got %v: %q", code, stderr)
+	}
+}
+
+func TestRun_Glob(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": "1{a:i1}", "b.cereal": "1{b:i2}", "c.txt": "text"})
+
+	code, stdout, _ := run(t, "", "get", "*", "*.cereal")
+	if code != ExitOK {
+		t.Fatalf("expected success but got %v", code)
+	}
+
+	expected := "a.cereal: i1\nb.cereal: i2\n"
+	if stdout != expected {
+		t.Errorf("expected %q but got %q", expected, stdout)
+	}
+}
+
+func TestRunLegacy(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": "1{a:i1}"})
+
+	tests := map[string]struct {
+		args []string
+		code int
+	}{
+		"success":      {[]string{"convert", "-to", "json", "a.cereal"}, 0},
+		"no files":     {[]string{"convert", "-to", "json"}, 1},
+		"unknown flag": {[]string{"schema", "-frobnicate"}, 1},
+		"missing file": {[]string{"schema", "missing.cereal"}, 1},
+	}
+
+	for name, test := range tests {
+		code, _, _ := capture(t, "", func() int { return RunLegacy(test.args) })
+		if code != test.code {
+			t.Errorf("%v: expected exit code %v but got %v", name, test.code, code)
+		}
+	}
+}
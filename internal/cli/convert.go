@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runConvert converts documents to JSON or JSON to documents. Without -to, each input is
+// converted to the other format, judging by whether it starts with a version byte and a map.
+func runConvert(fs *flag.FlagSet, args []string) int {
+	to := fs.String("to", "", "the format to convert to, json or cereal, rather than the opposite of each input")
+	isTyped := fs.Bool("typed", false, "tag every number with its type in JSON, or read those tags")
+	isFloat32 := fs.Bool("float32", false, "write JSON numbers that are not integers as float32 values")
+	schemaFile := fs.String("schema", "", "a cereal schema or JSON Schema that decides the types of JSON numbers")
+	rootKey := fs.String("root", "value", "the key to store a JSON root value other than an object under")
+	isLines := fs.Bool("lines", false, "convert one value per line, as in JSON Lines, reporting errors by line")
+	workers := fs.Int("workers", 0, "the number of lines converted at the same time with -lines, or the number of CPUs")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if *to != "" && *to != "json" && *to != "cereal" {
+		fmt.Fprintf(os.Stderr, "unknown format '%v'\n", *to)
+		return usageError(fs)
+	}
+
+	options := cereal.JSONOptions{Float32: *isFloat32, RootKey: *rootKey, Typed: *isTyped}
+	if *schemaFile != "" {
+		schema, err := readSchema(*schemaFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", *schemaFile, err)
+			return ExitError
+		}
+		options.Schema = schema
+	}
+
+	if *isLines {
+		return convertLines(fs, *to, cereal.LinesOptions{JSONOptions: options, Workers: *workers})
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+
+	for _, in := range inputs {
+		format := *to
+		if format == "" {
+			format = "cereal"
+			if isDocument(in.data) {
+				format = "json"
+			}
+		}
+
+		var out []byte
+		var err error
+		if format == "json" {
+			out, err = toJSON(in.data, *isTyped)
+		} else {
+			out, err = cereal.ConvertJSON(bytes.NewReader(in.data), options)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+
+		_, err = os.Stdout.Write(out)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+	}
+
+	return ExitOK
+}
+
+// convertLines streams each file a line at a time, printing the error for each line that
+// cannot be converted and carrying on with the next.
+func convertLines(fs *flag.FlagSet, to string, options cereal.LinesOptions) int {
+	if fs.NArg() == 0 {
+		return usageError(fs)
+	}
+
+	names, err := expandPatterns(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	code := ExitOK
+	for _, name := range names {
+		options.Errors = func(err *cereal.LineError) {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+			code = ExitFailed
+		}
+
+		err := convertLinesFile(name, to, options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+			return ExitError
+		}
+	}
+
+	return code
+}
+
+func convertLinesFile(name, to string, options cereal.LinesOptions) error {
+	file, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	if to == "" {
+		// the first line decides the direction of the whole file
+		start, _ := reader.Peek(64)
+		to = "cereal"
+		if isDocument(start) {
+			to = "json"
+		}
+	}
+
+	if to == "json" {
+		return cereal.ConvertCerealLines(reader, os.Stdout, options)
+	}
+	return cereal.ConvertJSONLines(reader, os.Stdout, options)
+}
+
+// isDocument reports whether data starts like a version 1 document rather than JSON.
+func isDocument(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '1' {
+		return false
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(data[1:]), []byte("{"))
+}
+
+// toJSON returns a document as a line of JSON.
+func toJSON(data []byte, typed bool) ([]byte, error) {
+	doc, err := cereal.ParseOrdered(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if typed {
+		out, err := cereal.MarshalTypedJSON(doc)
+		return append(out, '\n'), err
+	}
+
+	out := bytes.Buffer{}
+	err = json.NewEncoder(&out).Encode(doc)
+	return out.Bytes(), err
+}
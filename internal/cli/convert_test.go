@@ -0,0 +1,29 @@
+package cli
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"a.cereal":     `1{a:i1,b:["x,"y]}`,
+		"a.json":       `{"a":1,"b":[1.5,"x"]}`,
+		"bad.cereal":   "1{a:",
+		"lines.cereal": "1{a:i1}\nnope\n1{a:i2}\n",
+		"lines.json":   "{\"a\":1}\n{\"a\":2.5}\n",
+		"schema.json":  `{"properties":{"a":{"type":"number"}}}`,
+	})
+
+	runTests(t, map[string]cliTest{
+		"to json":        {args: []string{"convert", "a.cereal"}, stdout: "{\"a\":1,\"b\":[\"x\",\"y\"]}\n"},
+		"to cereal":      {args: []string{"convert", "a.json"}, stdout: "1{a:i1,b:[d1.5,\"x]}"},
+		"both ways":      {args: []string{"convert", "a.cereal", "a.json"}, stdout: "{\"a\":1,\"b\":[\"x\",\"y\"]}\n1{a:i1,b:[d1.5,\"x]}"},
+		"forced":         {args: []string{"convert", "-to", "json", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"typed":          {args: []string{"convert", "-typed", "a.cereal"}, stdout: "{\"a\":{\"$i\":1},\"b\":[\"x\",\"y\"]}\n"},
+		"options":        {args: []string{"convert", "-float32", "-root", "v", "-"}, stdin: "[1.5]", stdout: "1{v:[f1.5]}"},
+		"schema":         {args: []string{"convert", "-schema", "schema.json", "-"}, stdin: `{"a":1}`, stdout: "1{a:d1}"},
+		"unknown format": {args: []string{"convert", "-to", "yaml", "a.cereal"}, code: ExitError, stderr: "unknown format 'yaml'\nUsage: cereal convert"},
+		"missing schema": {args: []string{"convert", "-schema", "missing.json", "a.json"}, code: ExitError, stderr: "missing.json: open missing.json: no such file or directory\n"},
+		"lines":          {args: []string{"convert", "-lines", "lines.json"}, stdout: "1{a:i1}\n1{a:d2.5}\n"},
+		"bad lines":      {args: []string{"convert", "-lines", "-workers", "1", "lines.cereal"}, code: ExitFailed, stdout: "{\"a\":1}\n{\"a\":2}\n", stderr: "lines.cereal: line 2: unexpected version '110'\n"},
+		"no lines":       {args: []string{"convert", "-lines"}, code: ExitError, stderr: "Usage: cereal convert"},
+	})
+}
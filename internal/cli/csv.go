@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/snocorp/cereal"
+)
+
+// csvFlags defines the flags shared by the CSV subcommands.
+func csvFlags(fs *flag.FlagSet) (*string, *string) {
+	comma := fs.String("comma", ",", "the field delimiter")
+	arraySeparator := fs.String("array-sep", ";", "the separator between the elements of an array in a cell")
+	return comma, arraySeparator
+}
+
+// csvOptions returns the options for the flags, or false if the delimiter is not a single
+// character.
+func csvOptions(comma, arraySeparator string) (cereal.CSVOptions, bool) {
+	r, size := utf8.DecodeRuneInString(comma)
+	if size == 0 || size != len(comma) {
+		fmt.Fprintf(os.Stderr, "invalid delimiter '%v'\n", comma)
+		return cereal.CSVOptions{}, false
+	}
+
+	return cereal.CSVOptions{Comma: r, ArraySeparator: arraySeparator}, true
+}
+
+// runCSV2Cereal converts CSV files to one document per row, streaming each file.
+func runCSV2Cereal(fs *flag.FlagSet, args []string) int {
+	comma, arraySeparator := csvFlags(fs)
+	schemaFile := fs.String("schema", "", "a cereal schema or JSON Schema that decides the types of the columns")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs)
+	}
+
+	options, ok := csvOptions(*comma, *arraySeparator)
+	if !ok {
+		return usageError(fs)
+	}
+	if *schemaFile != "" {
+		schema, err := readSchema(*schemaFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", *schemaFile, err)
+			return ExitError
+		}
+		options.Schema = schema
+	}
+
+	names, err := expandPatterns(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	for _, name := range names {
+		file, err := openInput(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+
+		err = cereal.ConvertCSV(file, os.Stdout, options)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", name, err)
+			return ExitFailed
+		}
+	}
+
+	return ExitOK
+}
+
+// runCereal2CSV converts a file with one document per line to CSV, streaming the file.
+func runCereal2CSV(fs *flag.FlagSet, args []string) int {
+	comma, arraySeparator := csvFlags(fs)
+	columns := fs.String("columns", "", "the comma separated columns to write, rather than those of the first record")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs)
+	}
+
+	options, ok := csvOptions(*comma, *arraySeparator)
+	if !ok {
+		return usageError(fs)
+	}
+	if *columns != "" {
+		options.Columns = strings.Split(*columns, ",")
+	}
+
+	names, err := expandPatterns(fs.Args())
+	if err == nil && len(names) != 1 {
+		err = fmt.Errorf("%v: expected a single file", fs.Arg(0))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	file, err := openInput(names[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+	defer file.Close()
+
+	err = cereal.ConvertCerealCSV(file, os.Stdout, options)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v: %v\n", names[0], err)
+		return ExitFailed
+	}
+
+	return ExitOK
+}
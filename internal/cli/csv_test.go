@@ -0,0 +1,25 @@
+package cli
+
+import "testing"
+
+func TestCSV(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"a.csv":         "a,b\n1,x;y\n2,z\n",
+		"a.tsv":         "a\tb\n1\tx|y\n",
+		"bad.csv":       "a,b\n1\n",
+		"lines.cereal":  "1{a:i1,b:[\"x,\"y]}\n1{a:i2,b:[\"z]}\n",
+		"schema.cereal": `1{type:"map,fields:{a:{type:"float64},b:{type:"array,items:{type:"string}}}}`,
+	})
+
+	runTests(t, map[string]cliTest{
+		"from csv":          {args: []string{"csv2cereal", "a.csv"}, stdout: "1{a:i1,b:\"x;y}\n1{a:i2,b:\"z}\n"},
+		"from csv options":  {args: []string{"csv2cereal", "-comma", "\t", "-array-sep", "|", "a.tsv"}, stdout: "1{a:i1,b:\"x|y}\n"},
+		"from csv schema":   {args: []string{"csv2cereal", "-schema", "schema.cereal", "a.csv"}, stdout: "1{a:d1,b:[\"x,\"y]}\n1{a:d2,b:[\"z]}\n"},
+		"bad csv":           {args: []string{"csv2cereal", "bad.csv"}, code: ExitFailed, stderr: "bad.csv: "},
+		"bad delimiter":     {args: []string{"csv2cereal", "-comma", "ab", "a.csv"}, code: ExitError, stderr: "invalid delimiter 'ab'\nUsage: cereal csv2cereal"},
+		"to csv":            {args: []string{"cereal2csv", "lines.cereal"}, stdout: "a,b[]\n1,x;y\n2,z\n"},
+		"to csv columns":    {args: []string{"cereal2csv", "-columns", "b[]", "lines.cereal"}, stdout: "b[]\nx;y\nz\n"},
+		"to csv many files": {args: []string{"cereal2csv", "lines.cereal", "lines.cereal"}, code: ExitError, stderr: "Usage: cereal cereal2csv"},
+		"to csv glob":       {args: []string{"cereal2csv", "*.c*"}, code: ExitError, stderr: "*.c*: expected a single file\n"},
+	})
+}
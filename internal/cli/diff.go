@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runDiff prints the differences between two documents.
+func runDiff(fs *flag.FlagSet, args []string) int {
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() != 2 {
+		return usageError(fs)
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+	if len(inputs) != 2 {
+		fmt.Fprintln(os.Stderr, "expected exactly two documents")
+		return ExitError
+	}
+
+	docs := make([]any, len(inputs))
+	for i, in := range inputs {
+		doc, err := cereal.ParseOrdered(bytes.NewReader(in.data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+		docs[i] = doc
+	}
+
+	changes := cereal.Diff(docs[0], docs[1])
+	err := cereal.WriteDiff(os.Stdout, changes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	if len(changes) > 0 {
+		return ExitFailed
+	}
+	return ExitOK
+}
@@ -0,0 +1,16 @@
+package cli
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": `1{a:i1,b:["x,"y]}`, "b.cereal": `1{a:i2,b:["x]}`, "bad.cereal": "1{a:"})
+
+	runTests(t, map[string]cliTest{
+		"same":         {args: []string{"diff", "a.cereal", "a.cereal"}},
+		"different":    {args: []string{"diff", "a.cereal", "b.cereal"}, code: ExitFailed, stdout: "- <root>.a: i1\n+ <root>.a: i2\n- <root>.b.1: \"y\n"},
+		"one file":     {args: []string{"diff", "a.cereal"}, code: ExitError, stderr: "Usage: cereal diff <file> <file>\n"},
+		"glob":         {args: []string{"diff", "*.cereal", "a.cereal"}, code: ExitError, stderr: "expected exactly two documents\n"},
+		"bad input":    {args: []string{"diff", "a.cereal", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"missing file": {args: []string{"diff", "a.cereal", "missing.cereal"}, code: ExitError, stderr: "open missing.cereal: no such file or directory"},
+	})
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runFmt indents or compacts documents, writing them to the standard output or back to
+// their files.
+func runFmt(fs *flag.FlagSet, args []string) int {
+	indent := fs.String("indent", "  ", "the indentation of each level")
+	prefix := fs.String("prefix", "", "the prefix of each line")
+	compact := fs.Bool("compact", false, "remove all insignificant whitespace and comments")
+	write := fs.Bool("w", false, "write the result back to each file instead of the standard output")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+
+	for _, in := range inputs {
+		if *write && in.name == stdinName {
+			fmt.Fprintln(os.Stderr, "the standard input cannot be written back")
+			return ExitError
+		}
+
+		out := bytes.Buffer{}
+		var err error
+		if *compact {
+			err = cereal.Compact(&out, in.data)
+		} else {
+			err = cereal.Indent(&out, in.data, *prefix, *indent)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+		out.WriteByte('\n')
+
+		if *write {
+			err = writeBack(in.name, out.Bytes())
+		} else {
+			_, err = out.WriteTo(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+	}
+
+	return ExitOK
+}
+
+// writeBack replaces the content of a file, keeping its permissions.
+func writeBack(name string, data []byte) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(name, data, info.Mode().Perm())
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFmt(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": `1{a:i1,b:["x,"y]}`, "bad.cereal": "1{a:"})
+
+	indented := "1{\n  a:i1,\n  b:[\n    \"x,\n    \"y,\n  ],\n}\n"
+	runTests(t, map[string]cliTest{
+		"indent":  {args: []string{"fmt", "a.cereal"}, stdout: indented},
+		"options": {args: []string{"fmt", "-indent", "\t", "-prefix", "> ", "a.cereal"}, stdout: "1{\n> \ta:i1,\n> \tb:[\n> \t\t\"x,\n> \t\t\"y,\n> \t],\n> }\n"},
+		"compact": {args: []string{"fmt", "-compact", "a.cereal"}, stdout: "1{a:i1,b:[\"x,\"y]}\n"},
+		"stdin":   {args: []string{"fmt", "-compact", "-"}, stdin: "1{\n  a:i1}", stdout: "1{a:i1}\n"},
+		"invalid": {args: []string{"fmt", "a.cereal", "bad.cereal"}, code: ExitFailed, stdout: indented, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+	})
+}
+
+func TestFmt_Write(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": "1 # header\n{a:i1}"})
+	err := os.Chmod("a.cereal", 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := run(t, "", "fmt", "-w", "a.cereal")
+	if code != ExitOK || stdout != "" || stderr != "" {
+		t.Fatalf("expected success without output but got %v: %q %q", code, stdout, stderr)
+	}
+
+	data, err := os.ReadFile("a.cereal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1 # header\n{\n  a:i1,\n}\n" {
+		t.Errorf("expected the file to be indented but got %q", data)
+	}
+
+	info, err := os.Stat("a.cereal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected the file to keep its mode 0600 but got %v", info.Mode().Perm())
+	}
+}
+
+func TestFmt_WriteErrors(t *testing.T) {
+	writeFiles(t, map[string]string{"bad.cereal": "1{a:", "value.cereal": "1{a:[i1,i2 ]}"})
+
+	code, _, stderr := run(t, "1{a:i1}", "fmt", "-w", "-")
+	if code != ExitError || stderr != "the standard input cannot be written back\n" {
+		t.Errorf("expected an error for the standard input but got %v: %q", code, stderr)
+	}
+
+	code, _, stderr = run(t, "", "fmt", "-w", "bad.cereal")
+	if code != ExitFailed || stderr != "bad.cereal: <root>: unexpected end of input\n" {
+		t.Errorf("expected an error for an invalid document but got %v: %q", code, stderr)
+	}
+
+	code, _, stderr = run(t, "", "fmt", "-w", "value.cereal")
+	if code != ExitFailed || stderr != "value.cereal: <root>.a.1: invalid int '2 '\n" {
+		t.Errorf("expected an error for an invalid value but got %v: %q", code, stderr)
+	}
+
+	for name, content := range map[string]string{"bad.cereal": "1{a:", "value.cereal": "1{a:[i1,i2 ]}"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("expected an invalid document to be left alone but got %q", data)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// fromFormat returns a subcommand that converts files in another format to documents.
+func fromFormat(convert func(reader io.Reader) ([]byte, error)) func(fs *flag.FlagSet, args []string) int {
+	return func(fs *flag.FlagSet, args []string) int {
+		if ok, code := parseFlags(fs, args); !ok {
+			return code
+		}
+
+		inputs, code := readFlagInputs(fs, fs.Args())
+		if inputs == nil {
+			return code
+		}
+
+		for _, in := range inputs {
+			out, err := convert(bytes.NewReader(in.data))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+				return ExitFailed
+			}
+
+			_, err = os.Stdout.Write(out)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return ExitError
+			}
+		}
+
+		return ExitOK
+	}
+}
+
+// toFormat returns a subcommand that converts documents to another format, writing the
+// separator between documents when there are several.
+func toFormat(marshal func(doc any) ([]byte, error), separator string) func(fs *flag.FlagSet, args []string) int {
+	return func(fs *flag.FlagSet, args []string) int {
+		if ok, code := parseFlags(fs, args); !ok {
+			return code
+		}
+
+		inputs, code := readFlagInputs(fs, fs.Args())
+		if inputs == nil {
+			return code
+		}
+
+		for i, in := range inputs {
+			out, err := marshalDocument(in.data, marshal)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+				return ExitFailed
+			}
+
+			if i > 0 {
+				out = append([]byte(separator), out...)
+			}
+			_, err = os.Stdout.Write(out)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return ExitError
+			}
+		}
+
+		return ExitOK
+	}
+}
+
+func marshalDocument(data []byte, marshal func(doc any) ([]byte, error)) ([]byte, error) {
+	doc, err := cereal.ParseOrdered(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return marshal(doc)
+}
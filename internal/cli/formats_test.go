@@ -0,0 +1,43 @@
+package cli
+
+import "testing"
+
+func TestFormats(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"a.cereal":   `1{a:i1,b:["x,"y]}`,
+		"b.cereal":   `1{a:i2}`,
+		"bad.cereal": "1{a:",
+		"a.yaml":     "a: 1\nb: [x, y]\n",
+		"a.toml":     "a = 1\nb = [\"x\", \"y\"]\n",
+		"bad.toml":   "a = \n",
+	})
+
+	runTests(t, map[string]cliTest{
+		"to yaml":          {args: []string{"cereal2yaml", "a.cereal", "b.cereal"}, stdout: "a: 1\nb:\n  - x\n  - y\n---\na: 2\n"},
+		"from yaml":        {args: []string{"yaml2cereal", "a.yaml"}, stdout: "1{a:i1,b:[\"x,\"y]}"},
+		"to toml":          {args: []string{"cereal2toml", "a.cereal", "b.cereal"}, stdout: "a = 1\nb = [\"x\", \"y\"]\n\na = 2\n"},
+		"from toml":        {args: []string{"toml2cereal", "a.toml"}, stdout: "1{a:i1,b:[\"x,\"y]}"},
+		"bad document":     {args: []string{"cereal2yaml", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"bad input":        {args: []string{"toml2cereal", "bad.toml"}, code: ExitFailed, stderr: "bad.toml: "},
+		"msgpack no files": {args: []string{"cereal2msgpack"}, code: ExitError, stderr: "Usage: cereal cereal2msgpack"},
+	})
+}
+
+func TestFormats_Binary(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": `1{a:i1,b:["x,"y]}`})
+
+	for _, format := range []string{"msgpack", "cbor"} {
+		code, encoded, stderr := run(t, "", "cereal2"+format, "a.cereal")
+		if code != ExitOK || stderr != "" {
+			t.Fatalf("%v: expected success but got %v: %q", format, code, stderr)
+		}
+
+		code, decoded, stderr := run(t, encoded, format+"2cereal", "-")
+		if code != ExitOK || stderr != "" {
+			t.Fatalf("%v: expected success but got %v: %q", format, code, stderr)
+		}
+		if decoded != `1{a:i1,b:["x,"y]}` {
+			t.Errorf("%v: expected the document back but got %q", format, decoded)
+		}
+	}
+}
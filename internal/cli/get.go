@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runGet prints the values that a query selects from each document, one per line. With
+// more than one document, each line starts with the name of its file.
+func runGet(fs *flag.FlagSet, args []string) int {
+	isJSON := fs.Bool("json", false, "print the values as JSON")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	if fs.NArg() < 2 {
+		return usageError(fs)
+	}
+	query := fs.Arg(0)
+
+	inputs, code := readFlagInputs(fs, fs.Args()[1:])
+	if inputs == nil {
+		return code
+	}
+
+	found := false
+	for _, in := range inputs {
+		doc, err := cereal.ParseOrdered(bytes.NewReader(in.data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+
+		results, err := cereal.Query(doc, query)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+
+		for _, result := range results {
+			var data []byte
+			if *isJSON {
+				data, err = json.Marshal(result)
+			} else {
+				data, err = encodeValue(result)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+				return ExitError
+			}
+
+			if len(inputs) > 1 {
+				fmt.Printf("%v: ", in.name)
+			}
+			fmt.Printf("%s\n", data)
+			found = true
+		}
+	}
+
+	if !found {
+		return ExitFailed
+	}
+	return ExitOK
+}
@@ -0,0 +1,19 @@
+package cli
+
+import "testing"
+
+func TestGet(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": `1{a:i1,b:["x,"y]}`, "b.cereal": `1{a:i2,b:["x]}`, "bad.cereal": "1{a:"})
+
+	runTests(t, map[string]cliTest{
+		"value":        {args: []string{"get", "a", "a.cereal"}, stdout: "i1\n"},
+		"values":       {args: []string{"get", "b.*", "a.cereal"}, stdout: "\"x\n\"y\n"},
+		"json":         {args: []string{"get", "-json", "b", "a.cereal", "b.cereal"}, stdout: "a.cereal: [\"x\",\"y\"]\nb.cereal: [\"x\"]\n"},
+		"no results":   {args: []string{"get", "c", "a.cereal"}, code: ExitFailed},
+		"no files":     {args: []string{"get", "a"}, code: ExitError, stderr: "Usage: cereal get"},
+		"bad query":    {args: []string{"get", "a[", "a.cereal"}, code: ExitError, stderr: "a["},
+		"bad input":    {args: []string{"get", "a", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"missing file": {args: []string{"get", "a", "missing.cereal"}, code: ExitError, stderr: "open missing.cereal: no such file or directory"},
+		"stdin input":  {args: []string{"get", "a", "-"}, stdin: "1{a:\"z}", stdout: "\"z\n"},
+	})
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runSchema infers a schema from sample documents and prints it indented, as a cereal
+// schema or as a JSON Schema.
+func runSchema(fs *flag.FlagSet, args []string) int {
+	isJSON := fs.Bool("json", false, "print a JSON Schema instead of a cereal schema")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+
+	samples := []any{}
+	for _, in := range inputs {
+		sample, err := cereal.ParseOrdered(bytes.NewReader(in.data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+		samples = append(samples, sample)
+	}
+
+	schema := cereal.InferSchema(samples...)
+
+	var data []byte
+	var err error
+	if *isJSON {
+		data, err = cereal.JSONSchema(schema)
+	} else {
+		data, err = cereal.SerializeSchema(schema)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	out := bytes.Buffer{}
+	if *isJSON {
+		err = json.Indent(&out, data, "", "  ")
+	} else {
+		err = cereal.Indent(&out, data, "", "  ")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+	out.WriteByte('\n')
+
+	_, err = out.WriteTo(os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	return ExitOK
+}
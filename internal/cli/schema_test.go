@@ -0,0 +1,20 @@
+package cli
+
+import "testing"
+
+func TestSchema(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": `1{a:i1,b:["x,"y]}`, "b.cereal": `1{a:i2}`, "bad.cereal": "1{a:"})
+
+	runTests(t, map[string]cliTest{
+		"cereal": {
+			args:   []string{"schema", "a.cereal", "b.cereal"},
+			stdout: "1{\n  type:\"map,\n  fields:{\n    a:{\n      type:\"int,\n    },\n    b:{\n      type:\"array,\n      optional:b1,\n      items:{\n        type:\"string,\n      },\n    },\n  },\n}\n",
+		},
+		"json": {
+			args:   []string{"schema", "-json", "b.cereal"},
+			stdout: "{\n  \"$schema\": \"https://json-schema.org/draft/2020-12/schema\",\n  \"type\": \"object\",\n  \"properties\": {\n    \"a\": {\n      \"type\": \"integer\",\n      \"format\": \"int32\"\n    }\n  },\n  \"required\": [\n    \"a\"\n  ],\n  \"additionalProperties\": false\n}\n",
+		},
+		"bad input":    {args: []string{"schema", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"missing file": {args: []string{"schema", "missing.cereal"}, code: ExitError, stderr: "open missing.cereal: no such file or directory"},
+	})
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// scalarNames lists the scalar types in the order they are reported.
+var scalarNames = []struct {
+	valueType cereal.ValueType
+	name      string
+}{
+	{cereal.Bool, "bool"},
+	{cereal.Int, "int"},
+	{cereal.Float32, "float32"},
+	{cereal.Float64, "float64"},
+	{cereal.String, "string"},
+	{cereal.Null, "null"},
+}
+
+// stats counts the tokens of a document.
+type stats struct {
+	bytes    int
+	depth    int
+	maps     int
+	arrays   int
+	keys     int
+	comments int
+	scalars  map[cereal.ValueType]int
+}
+
+// runStats prints the number of values of each type in each document, along with its size
+// and how deeply it is nested.
+func runStats(fs *flag.FlagSet, args []string) int {
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+
+	for _, in := range inputs {
+		s, err := countTokens(in.data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			return ExitFailed
+		}
+
+		fmt.Printf("%v:\n", in.name)
+		fmt.Printf("  bytes: %v\n  depth: %v\n  maps: %v\n  arrays: %v\n  keys: %v\n  comments: %v\n", s.bytes, s.depth, s.maps, s.arrays, s.keys, s.comments)
+		for _, scalar := range scalarNames {
+			fmt.Printf("  %v: %v\n", scalar.name, s.scalars[scalar.valueType])
+		}
+	}
+
+	return ExitOK
+}
+
+func countTokens(data []byte) (stats, error) {
+	s := stats{bytes: len(data), scalars: map[cereal.ValueType]int{}}
+
+	t := cereal.NewTokenizer(bytes.NewReader(data))
+	t.EmitComments()
+	t.ReadToEnd()
+	depth := 0
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return s, err
+		}
+
+		switch tok.Kind {
+		case cereal.MapStart, cereal.ArrayStart:
+			if tok.Kind == cereal.MapStart {
+				s.maps++
+			} else {
+				s.arrays++
+			}
+			depth++
+			s.depth = max(s.depth, depth)
+		case cereal.MapEnd, cereal.ArrayEnd:
+			depth--
+		case cereal.Key:
+			s.keys++
+		case cereal.Comment:
+			s.comments++
+		case cereal.Scalar:
+			s.scalars[tok.Type]++
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package cli
+
+import "testing"
+
+func TestStats(t *testing.T) {
+	writeFiles(t, map[string]string{"a.cereal": "1 # header\n{a:i1,b:[\"x,\"y]} # done", "bad.cereal": "1{a:", "extra.cereal": "1{a:i1}x"})
+
+	runTests(t, map[string]cliTest{
+		"stats": {
+			args:   []string{"stats", "a.cereal"},
+			stdout: "a.cereal:\n  bytes: 34\n  depth: 2\n  maps: 1\n  arrays: 1\n  keys: 2\n  comments: 2\n  bool: 0\n  int: 1\n  float32: 0\n  float64: 0\n  string: 2\n  null: 0\n",
+		},
+		"bad input":  {args: []string{"stats", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"extra data": {args: []string{"stats", "extra.cereal"}, code: ExitFailed, stderr: "extra.cereal: unexpected data after the root map\n"},
+	})
+}
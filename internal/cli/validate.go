@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/snocorp/cereal"
+)
+
+// runValidate checks that each document is well-formed and, if a schema is given, that it
+// matches the schema. Every problem found is printed.
+func runValidate(fs *flag.FlagSet, args []string) int {
+	schemaFile := fs.String("schema", "", "a cereal schema or JSON Schema that the documents must match")
+	if ok, code := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	var schema *cereal.Schema
+	if *schemaFile != "" {
+		var err error
+		schema, err = readSchema(*schemaFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", *schemaFile, err)
+			return ExitError
+		}
+	}
+
+	inputs, code := readFlagInputs(fs, fs.Args())
+	if inputs == nil {
+		return code
+	}
+
+	code = ExitOK
+	for _, in := range inputs {
+		err := cereal.Validate(bytes.NewReader(in.data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			code = ExitFailed
+			continue
+		}
+
+		if schema == nil {
+			continue
+		}
+
+		doc, err := cereal.ParseOrdered(bytes.NewReader(in.data))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, err)
+			code = ExitFailed
+			continue
+		}
+
+		for _, v := range cereal.ValidateSchema(doc, schema) {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", in.name, v)
+			code = ExitFailed
+		}
+	}
+
+	return code
+}
@@ -0,0 +1,20 @@
+package cli
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	writeFiles(t, map[string]string{
+		"a.cereal":      `1{a:i1,b:["x,"y]}`,
+		"bad.cereal":    "1{a:",
+		"schema.cereal": `1{type:"map,fields:{a:{type:"string}}}`,
+		"schema.json":   `{"type":"object","properties":{"a":{"type":"integer"},"b":{"type":"array"}}}`,
+	})
+
+	runTests(t, map[string]cliTest{
+		"valid":          {args: []string{"validate", "a.cereal"}},
+		"invalid":        {args: []string{"validate", "a.cereal", "bad.cereal"}, code: ExitFailed, stderr: "bad.cereal: <root>: unexpected end of input\n"},
+		"schema":         {args: []string{"validate", "-schema", "schema.cereal", "a.cereal"}, code: ExitFailed, stderr: "a.cereal: <root>.a: expected string but got int\na.cereal: <root>.b: unexpected field\n"},
+		"json schema":    {args: []string{"validate", "-schema", "schema.json", "a.cereal"}},
+		"missing schema": {args: []string{"validate", "-schema", "missing.cereal", "a.cereal"}, code: ExitError, stderr: "missing.cereal: open missing.cereal: no such file or directory\n"},
+	})
+}
@@ -0,0 +1,283 @@
+package cereal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// JSONOptions controls how ConvertJSON chooses cereal types for JSON values.
+type JSONOptions struct {
+	// Float32 writes numbers that are not integers as float32 values rather than float64.
+	Float32 bool
+	// Schema, if set, decides the type of the numbers at the paths it describes, so that an
+	// integer in JSON may still be written as a float. Elsewhere types are inferred.
+	Schema *Schema
+	// RootKey is the key of the map that a root value other than an object is stored in,
+	// since a document is always a map. It defaults to "value".
+	RootKey string
+	// Typed reads the type tags written by MarshalTypedJSON, so that the numbers keep the
+	// types they had in the original document.
+	Typed bool
+}
+
+// typedJSONTags maps the keys that tag a number in typed JSON to the type of the number.
+var typedJSONTags = map[string]ValueType{
+	"$i": Int,
+	"$f": Float32,
+	"$d": Float64,
+}
+
+// typedJSONMapTag wraps a map in typed JSON whose only key is itself a tag, so that it is
+// not mistaken for a tagged value.
+const typedJSONMapTag = "$map"
+
+// ConvertJSON reads a single JSON value from the provided io.Reader and returns it as a
+// serialized document. Numbers written without a fraction or an exponent become int values
+// if they fit in 32 bits, and other numbers become float64 values, unless the options say
+// otherwise. A JSON null is written as a null, and the keys of objects keep their order.
+func ConvertJSON(reader io.Reader, options JSONOptions) ([]byte, error) {
+	decoder := json.NewDecoder(reader)
+	decoder.UseNumber()
+
+	value, err := decodeJSONValue(decoder)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+
+	_, err = decoder.Token()
+	if err == nil {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	} else if err != io.EOF {
+		return nil, err
+	}
+
+	rootKey := options.RootKey
+	if rootKey == "" {
+		rootKey = "value"
+	}
+
+	doc, ok := value.(*OrderedMap)
+	if !ok {
+		doc = NewOrderedMap()
+		doc.Set(rootKey, value)
+	}
+
+	converted, err := convertJSONValue(doc, options.Schema, []string{"<root>"}, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := converted.(*OrderedMap); !ok {
+		// a tagged number at the root
+		converted = singleEntryMap(rootKey, converted)
+	}
+
+	return Serialize(converted, "1")
+}
+
+// convertJSONValue replaces the numbers and nulls decoded from JSON with cereal values,
+// following the schema of the value if there is one.
+func convertJSONValue(value any, s *Schema, path []string, options JSONOptions) (any, error) {
+	switch v := value.(type) {
+	case nil:
+		return RawValue{typeMarkers[Null]}, nil
+	case json.Number:
+		return convertJSONNumber(v, s, path, options)
+	case *OrderedMap:
+		if options.Typed && v.Len() == 1 {
+			key := v.Keys()[0]
+			child, _ := v.Get(key)
+			if key == typedJSONMapTag {
+				m, ok := child.(*OrderedMap)
+				if !ok {
+					return nil, fmt.Errorf("%v: expected an object", strings.Join(appendPath(path, key), "."))
+				}
+				return convertJSONMap(m, s, path, options)
+			}
+			if valueType, ok := typedJSONTags[key]; ok {
+				return convertTypedJSONNumber(child, valueType, path)
+			}
+		}
+
+		return convertJSONMap(v, s, path, options)
+	case []any:
+		var itemSchema *Schema
+		if s != nil {
+			itemSchema = s.Items
+		}
+
+		result := make([]any, len(v))
+		for i, elem := range v {
+			converted, err := convertJSONValue(elem, itemSchema, appendPath(path, strconv.Itoa(i)), options)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	}
+
+	return value, nil
+}
+
+func convertJSONMap(m *OrderedMap, s *Schema, path []string, options JSONOptions) (*OrderedMap, error) {
+	result := NewOrderedMap()
+	for key, child := range m.All() {
+		var childSchema *Schema
+		if s != nil {
+			childSchema = s.Field(key)
+			if childSchema == nil {
+				childSchema = s.Values
+			}
+		}
+
+		converted, err := convertJSONValue(child, childSchema, appendPath(path, key), options)
+		if err != nil {
+			return nil, err
+		}
+		result.Set(key, converted)
+	}
+
+	return result, nil
+}
+
+// convertTypedJSONNumber reads the value of a tagged number, which is a string if the number
+// cannot be written in JSON, such as NaN.
+func convertTypedJSONNumber(value any, valueType ValueType, path []string) (any, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("%v: expected a number but got %v", strings.Join(path, "."), value)
+	}
+
+	return parseValue(s, valueType, path)
+}
+
+func convertJSONNumber(n json.Number, s *Schema, path []string, options JSONOptions) (any, error) {
+	schemaType := ""
+	if s != nil {
+		schemaType = s.Type
+	}
+
+	switch schemaType {
+	case "int":
+		i, err := strconv.ParseInt(n.String(), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v: expected int but got %v", strings.Join(path, "."), n)
+		}
+		return int(i), nil
+	case "float32":
+		f, err := strconv.ParseFloat(n.String(), 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v: expected float32 but got %v", strings.Join(path, "."), n)
+		}
+		return float32(f), nil
+	case "float64":
+		f, err := n.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%v: expected float64 but got %v", strings.Join(path, "."), n)
+		}
+		return f, nil
+	}
+
+	if !strings.ContainsAny(n.String(), ".eE") {
+		i, err := strconv.ParseInt(n.String(), 10, 32)
+		if err == nil {
+			return int(i), nil
+		}
+		// integers outside the 32 bit range of an int are written as floats
+	}
+
+	if options.Float32 {
+		f, err := strconv.ParseFloat(n.String(), 32)
+		if err != nil {
+			return nil, fmt.Errorf("%v: number %v is out of range for float32", strings.Join(path, "."), n)
+		}
+		return float32(f), nil
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%v: number %v is out of range for float64", strings.Join(path, "."), n)
+	}
+	return f, nil
+}
+
+// MarshalTypedJSON returns a document, such as the result of ParseOrdered, as JSON in which
+// every number is tagged with its type, as in {"$i":42}, {"$f":1.5} or {"$d":1.5}. Numbers
+// that JSON cannot represent, such as NaN, are tagged strings. A map whose only key is one
+// of the tags is wrapped as {"$map":{...}}. ConvertJSON with the Typed option reads the
+// result back, giving the same bytes that Serialize writes for the document.
+func MarshalTypedJSON(value any) ([]byte, error) {
+	typed, err := typedJSONValue(value, []string{"<root>"})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(typed)
+}
+
+func typedJSONValue(value any, path []string) (any, error) {
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v, nil
+	case int:
+		return singleEntryMap("$i", v), nil
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return singleEntryMap("$f", strconv.FormatFloat(float64(v), 'g', -1, 32)), nil
+		}
+		return singleEntryMap("$f", v), nil
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return singleEntryMap("$d", strconv.FormatFloat(v, 'g', -1, 64)), nil
+		}
+		return singleEntryMap("$d", v), nil
+	case []any:
+		result := make([]any, len(v))
+		for i, elem := range v {
+			typed, err := typedJSONValue(elem, appendPath(path, strconv.Itoa(i)))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = typed
+		}
+		return result, nil
+	case *OrderedMap, map[string]any:
+		result := NewOrderedMap()
+		for _, c := range queryChildren(v) {
+			typed, err := typedJSONValue(c.value, appendPath(path, c.key))
+			if err != nil {
+				return nil, err
+			}
+			result.Set(c.key, typed)
+		}
+
+		if result.Len() == 1 {
+			key := result.Keys()[0]
+			if _, ok := typedJSONTags[key]; ok || key == typedJSONMapTag {
+				return singleEntryMap(typedJSONMapTag, result), nil
+			}
+		}
+		return result, nil
+	}
+
+	return nil, fmt.Errorf("%v: unsupported value of type %T", strings.Join(path, "."), value)
+}
+
+// singleEntryMap returns a map with the key as its only entry.
+func singleEntryMap(key string, value any) *OrderedMap {
+	m := NewOrderedMap()
+	m.Set(key, value)
+	return m
+}
@@ -0,0 +1,185 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertJSON(t *testing.T) {
+	tests := map[string]string{
+		`{"a":42,"b":1.5,"c":-3e2,"d":"x"}`: `1{a:i42,b:d1.5,c:d-300,d:"x}`,
+		`{"z":1,"a":{"y":true,"b":null}}`:   `1{z:i1,a:{y:b1,b:n}}`,
+		`{"a":[1,2.0,"s",null,[]]}`:         `1{a:[i1,d2,"s,n,[]]}`,
+		`{"big":12345678901234567890}`:      `1{big:d1.2345678901234567e+19}`,
+		`[1,{"a":2}]`:                       `1{value:[i1,{a:i2}]}`,
+		`"text"`:                            `1{value:"text}`,
+		`null`:                              `1{value:n}`,
+		`{"s":"a,b}c"}`:                     `1{s:"a\,b\}c}`,
+	}
+
+	for input, expected := range tests {
+		result, err := ConvertJSON(strings.NewReader(input), JSONOptions{})
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", input, err)
+			continue
+		}
+		if string(result) != expected {
+			t.Errorf("expected %v for %v but got %v", expected, input, string(result))
+		}
+	}
+}
+
+func TestConvertJSON_Options(t *testing.T) {
+	input := `{"a":1,"b":2.5,"c":{"d":3},"e":[4,5],"f":6}`
+
+	result, err := ConvertJSON(strings.NewReader(input), JSONOptions{Float32: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `1{a:i1,b:f2.5,c:{d:i3},e:[i4,i5],f:i6}` {
+		t.Errorf("unexpected result %v", string(result))
+	}
+
+	schema, err := ParseSchema(strings.NewReader(`1{type:"map,fields:{
+		a:{type:"float64},
+		b:{type:"float32},
+		c:{type:"map,values:{type:"float32}},
+		e:{type:"array,items:{type:"float64}},
+	},values:{type:"any}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = ConvertJSON(strings.NewReader(input), JSONOptions{Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `1{a:d1,b:f2.5,c:{d:f3},e:[d4,d5],f:i6}` {
+		t.Errorf("unexpected result %v", string(result))
+	}
+
+	result, err = ConvertJSON(strings.NewReader(`[1]`), JSONOptions{RootKey: "items"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `1{items:[i1]}` {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
+
+func TestConvertJSON_Errors(t *testing.T) {
+	schema := &Schema{Type: "map", Fields: []SchemaField{{Name: "a", Schema: &Schema{Type: "int"}}}}
+
+	_, err := ConvertJSON(strings.NewReader(`{"a":1.5}`), JSONOptions{Schema: schema})
+	if err == nil || err.Error() != "<root>.a: expected int but got 1.5" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	floats := &Schema{Type: "map", Fields: []SchemaField{
+		{Name: "f", Schema: &Schema{Type: "float32"}},
+		{Name: "d", Schema: &Schema{Type: "float64"}},
+	}}
+	_, err = ConvertJSON(strings.NewReader(`{"f":1e39}`), JSONOptions{Schema: floats})
+	if err == nil || err.Error() != "<root>.f: expected float32 but got 1e39" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ConvertJSON(strings.NewReader(`{"d":1e309}`), JSONOptions{Schema: floats})
+	if err == nil || err.Error() != "<root>.d: expected float64 but got 1e309" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ConvertJSON(strings.NewReader(`{"b":[1e39]}`), JSONOptions{Float32: true})
+	if err == nil || err.Error() != "<root>.b.0: number 1e39 is out of range for float32" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	_, err = ConvertJSON(strings.NewReader(`{"b":1e309}`), JSONOptions{})
+	if err == nil || err.Error() != "<root>.b: number 1e309 is out of range for float64" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = ConvertJSON(strings.NewReader(`{"a":1} {}`), JSONOptions{})
+	if err == nil || err.Error() != "unexpected data after the JSON value" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = ConvertJSON(strings.NewReader(`{"a":`), JSONOptions{})
+	if err == nil {
+		t.Error("expected an error for incomplete JSON")
+	}
+}
+
+func TestMarshalTypedJSON(t *testing.T) {
+	doc, err := ParseOrdered(strings.NewReader(`1{a:i1,b:f0.1,c:d0.1,d:{$d:"x},e:[dNaN,f+Inf],f:n}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := MarshalTypedJSON(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"a":{"$i":1},"b":{"$f":0.1},"c":{"$d":0.1},"d":{"$map":{"$d":"x"}},"e":[{"$d":"NaN"},{"$f":"+Inf"}],"f":null}`
+	if string(result) != expected {
+		t.Errorf("expected %v but got %v", expected, string(result))
+	}
+
+	_, err = MarshalTypedJSON(map[string]any{"a": int64(1)})
+	if err == nil || err.Error() != "<root>.a: unsupported value of type int64" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMarshalTypedJSON_RoundTrip(t *testing.T) {
+	docs := []string{
+		`1{a:i1,b:f0.1,c:d0.1,d:"text,e:b0,f:n}`,
+		`1{z:{y:[i-2147483648,f3.4028235e+38,d5e-324,d-0]},a:[]}`,
+		`1{$i:{$i:i3},$map:{$map:{}},m:{$f:f1,other:i2}}`,
+		`1{nan:dNaN,inf:[f-Inf,d+Inf],s:"a\,b\}c\]d\\e}`,
+	}
+
+	for _, doc := range docs {
+		parsed, err := ParseOrdered(strings.NewReader(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		typed, err := MarshalTypedJSON(parsed)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ConvertJSON(strings.NewReader(string(typed)), JSONOptions{Typed: true})
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", string(typed), err)
+			continue
+		}
+		if string(result) != doc {
+			t.Errorf("expected %v to round trip but got %v", doc, string(result))
+		}
+	}
+}
+
+func TestConvertJSON_TypedErrors(t *testing.T) {
+	tests := map[string]string{
+		`{"a":{"$i":1.5}}`:   "<root>.a: invalid int '1.5'",
+		`{"a":{"$f":true}}`:  "<root>.a: expected a number but got true",
+		`{"a":{"$map":[1]}}`: "<root>.a.$map: expected an object",
+	}
+
+	for input, expected := range tests {
+		_, err := ConvertJSON(strings.NewReader(input), JSONOptions{Typed: true})
+		if err == nil {
+			t.Errorf("expected an error for %v", input)
+		} else if err.Error() != expected {
+			t.Errorf("expected '%v' for %v but got '%v'", expected, input, err)
+		}
+	}
+
+	result, err := ConvertJSON(strings.NewReader(`{"$i":7}`), JSONOptions{Typed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != `1{value:i7}` {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
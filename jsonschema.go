@@ -0,0 +1,303 @@
+package cereal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// jsonSchemaDialect identifies the version of JSON Schema that is written and read.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// jsonSchemaAnnotations are the JSON Schema keywords that ParseJSONSchema ignores because
+// they do not constrain values.
+var jsonSchemaAnnotations = map[string]bool{
+	"$schema":    true,
+	"$id":        true,
+	"$comment":   true,
+	"title":      true,
+	"default":    true,
+	"examples":   true,
+	"deprecated": true,
+	"readOnly":   true,
+	"writeOnly":  true,
+}
+
+// JSONSchema returns the schema as a JSON Schema (draft 2020-12) document describing the
+// JSON view of a document, such as the one written by cereal2json. Since JSON has a single
+// type for numbers, int values become an "integer" with the format "int32", and float32 and
+// float64 values become a "number" with the format "float" or "double".
+func JSONSchema(s *Schema) ([]byte, error) {
+	doc := NewOrderedMap()
+	doc.Set("$schema", jsonSchemaDialect)
+	for key, value := range jsonSchemaDocument(s).All() {
+		doc.Set(key, value)
+	}
+
+	return json.Marshal(doc)
+}
+
+func jsonSchemaDocument(s *Schema) *OrderedMap {
+	m := NewOrderedMap()
+	if s.Description != "" {
+		m.Set("description", s.Description)
+	}
+
+	switch s.Type {
+	case "bool":
+		m.Set("type", "boolean")
+	case "int":
+		m.Set("type", "integer")
+		m.Set("format", "int32")
+	case "float32":
+		m.Set("type", "number")
+		m.Set("format", "float")
+	case "float64":
+		m.Set("type", "number")
+		m.Set("format", "double")
+	case "map":
+		m.Set("type", "object")
+	case "any":
+		// any value is allowed, so there is no type
+	default:
+		m.Set("type", s.Type)
+	}
+
+	if len(s.Enum) > 0 {
+		m.Set("enum", s.Enum)
+	}
+
+	if s.Fields != nil {
+		properties := NewOrderedMap()
+		required := []string{}
+		for _, f := range s.Fields {
+			properties.Set(f.Name, jsonSchemaDocument(f.Schema))
+			if !f.Schema.Optional {
+				required = append(required, f.Name)
+			}
+		}
+
+		if len(s.Fields) > 0 {
+			m.Set("properties", properties)
+		}
+		if len(required) > 0 {
+			m.Set("required", required)
+		}
+		if s.Values == nil {
+			m.Set("additionalProperties", false)
+		}
+	}
+	if s.Values != nil && (s.Fields == nil || s.Values.Type != "any") {
+		m.Set("additionalProperties", jsonSchemaDocument(s.Values))
+	}
+
+	if s.Items != nil {
+		m.Set("items", jsonSchemaDocument(s.Items))
+	}
+
+	return m
+}
+
+// ParseJSONSchema reads a JSON Schema document from the provided io.Reader and returns the
+// equivalent schema. An "integer" becomes an int, and a "number" becomes a float32 if its
+// format is "float" and a float64 otherwise. Properties keep their order, and properties
+// that are not required are optional. A value with no type or with several types has the
+// type "any". Annotations such as "title" are ignored, but keywords that cannot be expressed
+// by a schema, such as "$ref" or "minimum", are an error.
+func ParseJSONSchema(reader io.Reader) (*Schema, error) {
+	doc := NewOrderedMap()
+	err := json.NewDecoder(reader).Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeJSONSchema(doc, []string{"<root>"})
+}
+
+func decodeJSONSchema(m *OrderedMap, path []string) (*Schema, error) {
+	s := &Schema{Type: "any"}
+
+	typeValue, _ := m.Get("type")
+	switch t := typeValue.(type) {
+	case nil:
+		// without a type, the keywords given say what kind of value is described
+		_, hasProperties := m.Get("properties")
+		_, hasAdditional := m.Get("additionalProperties")
+		_, hasItems := m.Get("items")
+		if hasProperties || hasAdditional {
+			s.Type = "object"
+		} else if hasItems {
+			s.Type = "array"
+		}
+	case string:
+		s.Type = t
+	case []any:
+		if len(t) == 1 {
+			s.Type, _ = t[0].(string)
+		}
+	default:
+		return nil, fmt.Errorf("%v: unexpected value %v", strings.Join(appendPath(path, "type"), "."), t)
+	}
+
+	format, _ := m.Get("format")
+	switch s.Type {
+	case "boolean":
+		s.Type = "bool"
+	case "integer":
+		s.Type = "int"
+	case "number":
+		s.Type = "float64"
+		if format == "float" {
+			s.Type = "float32"
+		}
+	case "object":
+		s.Type = "map"
+	case "string", "array", "null", "any":
+	default:
+		return nil, fmt.Errorf("%v: unknown type '%v'", strings.Join(appendPath(path, "type"), "."), s.Type)
+	}
+
+	required := []string{}
+	additional := any(true)
+	for key, value := range m.All() {
+		keyPath := appendPath(path, key)
+
+		var ok bool
+		switch key {
+		case "type", "format":
+			ok = true
+		case "description":
+			s.Description, ok = value.(string)
+		case "enum":
+			var values []any
+			values, ok = value.([]any)
+			if !ok {
+				break
+			}
+
+			s.Enum = make([]any, len(values))
+			for i, v := range values {
+				enumValue, err := jsonSchemaValue(v, s.Type, appendPath(keyPath, fmt.Sprint(i)))
+				if err != nil {
+					return nil, err
+				}
+				s.Enum[i] = enumValue
+			}
+		case "properties":
+			var properties *OrderedMap
+			properties, ok = value.(*OrderedMap)
+			if !ok {
+				break
+			}
+
+			s.Fields = []SchemaField{}
+			for name, property := range properties.All() {
+				propertyPath := appendPath(keyPath, name)
+				propertyMap, isMap := property.(*OrderedMap)
+				if !isMap {
+					return nil, fmt.Errorf("%v: expected an object", strings.Join(propertyPath, "."))
+				}
+
+				fieldSchema, err := decodeJSONSchema(propertyMap, propertyPath)
+				if err != nil {
+					return nil, err
+				}
+				s.Fields = append(s.Fields, SchemaField{Name: name, Schema: fieldSchema})
+			}
+		case "required":
+			var names []any
+			names, ok = value.([]any)
+			for _, name := range names {
+				nameString, isString := name.(string)
+				if !isString {
+					return nil, fmt.Errorf("%v: expected strings", strings.Join(keyPath, "."))
+				}
+				required = append(required, nameString)
+			}
+		case "additionalProperties":
+			switch value.(type) {
+			case bool, *OrderedMap:
+				additional, ok = value, true
+			}
+		case "items":
+			var items *OrderedMap
+			items, ok = value.(*OrderedMap)
+			if !ok {
+				break
+			}
+
+			var err error
+			s.Items, err = decodeJSONSchema(items, keyPath)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if !jsonSchemaAnnotations[key] {
+				return nil, fmt.Errorf("%v: unsupported keyword '%v'", strings.Join(path, "."), key)
+			}
+			ok = true
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%v: unexpected value %v", strings.Join(keyPath, "."), value)
+		}
+	}
+
+	for _, f := range s.Fields {
+		f.Schema.Optional = true
+	}
+	for _, name := range required {
+		field := s.Field(name)
+		if field == nil {
+			return nil, fmt.Errorf("%v: required property '%v' not found", strings.Join(appendPath(path, "required"), "."), name)
+		}
+		field.Optional = false
+	}
+
+	switch a := additional.(type) {
+	case bool:
+		if !a && s.Fields == nil {
+			s.Fields = []SchemaField{}
+		} else if a && s.Fields != nil {
+			s.Values = &Schema{Type: "any"}
+		}
+	case *OrderedMap:
+		var err error
+		s.Values, err = decodeJSONSchema(a, appendPath(path, "additionalProperties"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (s.Fields != nil || s.Values != nil) && s.Type != "map" {
+		return nil, fmt.Errorf("%v: only an object may have properties", strings.Join(path, "."))
+	}
+	if s.Items != nil && s.Type != "array" {
+		return nil, fmt.Errorf("%v: only an array may have items", strings.Join(path, "."))
+	}
+
+	return s, nil
+}
+
+// jsonSchemaValue converts a value decoded from JSON, such as an element of an enum, to the
+// type of the schema.
+func jsonSchemaValue(value any, schemaType string, path []string) (any, error) {
+	number, ok := value.(float64)
+	if !ok {
+		return value, nil
+	}
+
+	switch schemaType {
+	case "int":
+		if number != math.Trunc(number) {
+			return nil, fmt.Errorf("%v: %v is not an integer", strings.Join(path, "."), number)
+		}
+		return int(number), nil
+	case "float32":
+		return float32(number), nil
+	}
+
+	return number, nil
+}
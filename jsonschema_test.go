@@ -0,0 +1,146 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := JSONSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"$schema":"https://json-schema.org/draft/2020-12/schema","description":"A service","type":"object",` +
+		`"properties":{"name":{"type":"string"},"ratio":{"type":"number","format":"float"},` +
+		`"mode":{"type":"string","enum":["dev","prod"]},"ports":{"type":"array","items":{"type":"integer","format":"int32"}},` +
+		`"labels":{"type":"object","additionalProperties":{"type":"string"}},"extra":{}},` +
+		`"required":["name","mode","ports"],"additionalProperties":false}`
+	if string(data) != expected {
+		t.Errorf("expected %v but got %v", expected, string(data))
+	}
+}
+
+func TestParseJSONSchema(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := JSONSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseJSONSchema(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := SerializeSchema(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := SerializeSchema(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("expected the schema to be read back as '%v' but got '%v'", string(expected), string(actual))
+	}
+}
+
+func TestParseJSONSchema_Types(t *testing.T) {
+	input := `{
+		"title": "Settings",
+		"type": "object",
+		"properties": {
+			"count": {"type": "integer", "format": "int32", "enum": [1, 2]},
+			"scale": {"type": "number", "enum": [0.5]},
+			"small": {"type": ["number"], "format": "float"},
+			"either": {"type": ["string", "null"]},
+			"flag": {"type": "boolean"}
+		},
+		"required": ["count"]
+	}`
+
+	s, err := ParseJSONSchema(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := s.Field("count"); count.Type != "int" || count.Optional || len(count.Enum) != 2 || count.Enum[1] != 2 {
+		t.Errorf("expected count to be a required int with an enum but got %+v", count)
+	}
+	if scale := s.Field("scale"); scale.Type != "float64" || !scale.Optional || scale.Enum[0] != 0.5 {
+		t.Errorf("expected scale to be an optional float64 with an enum but got %+v", scale)
+	}
+	if small := s.Field("small"); small.Type != "float32" {
+		t.Errorf("expected small to be a float32 but got %+v", small)
+	}
+	if either := s.Field("either"); either.Type != "any" {
+		t.Errorf("expected either to be any but got %+v", either)
+	}
+	if flag := s.Field("flag"); flag.Type != "bool" {
+		t.Errorf("expected flag to be a bool but got %+v", flag)
+	}
+	if s.Values == nil || s.Values.Type != "any" {
+		t.Errorf("expected other properties to be allowed but got %+v", s.Values)
+	}
+}
+
+func TestParseJSONSchema_InferredTypes(t *testing.T) {
+	input := `{
+		"properties": {
+			"name": {"type": "string"},
+			"labels": {"additionalProperties": {"type": "string"}},
+			"ports": {"items": {"type": "integer"}}
+		},
+		"required": ["name"]
+	}`
+
+	s, err := ParseJSONSchema(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Type != "map" || s.Values == nil || s.Values.Type != "any" {
+		t.Errorf("expected the root to be a map allowing other properties but got %+v", s)
+	}
+	if name := s.Field("name"); name.Type != "string" || name.Optional {
+		t.Errorf("expected name to be a required string but got %+v", name)
+	}
+	if labels := s.Field("labels"); labels.Type != "map" || labels.Fields != nil || labels.Values.Type != "string" {
+		t.Errorf("expected labels to be a map of strings but got %+v", labels)
+	}
+	if ports := s.Field("ports"); ports.Type != "array" || ports.Items.Type != "int" {
+		t.Errorf("expected ports to be an array of ints but got %+v", ports)
+	}
+}
+
+func TestParseJSONSchema_Errors(t *testing.T) {
+	tests := map[string]string{
+		`{"type":"date"}`:                                    "<root>.type: unknown type 'date'",
+		`{"type":3}`:                                         "<root>.type: unexpected value 3",
+		`{"$ref":"#/$defs/a"}`:                               "<root>: unsupported keyword '$ref'",
+		`{"type":"integer","enum":[1.5]}`:                    "<root>.enum.0: 1.5 is not an integer",
+		`{"type":"object","required":["a"]}`:                 "<root>.required: required property 'a' not found",
+		`{"type":"string","additionalProperties":false}`:     "<root>: only an object may have properties",
+		`{"type":"object","properties":{"a":{"minimum":1}}}`: "<root>.properties.a: unsupported keyword 'minimum'",
+		`{"type":"object","properties":{"a":1}}`:             "<root>.properties.a: expected an object",
+	}
+
+	for input, expected := range tests {
+		_, err := ParseJSONSchema(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected an error for %v", input)
+		} else if err.Error() != expected {
+			t.Errorf("expected '%v' for %v but got '%v'", expected, input, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package cereal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// LinesOptions controls the conversion of a stream with one value per line.
+type LinesOptions struct {
+	JSONOptions
+
+	// Workers is the number of lines converted at the same time. It defaults to the number
+	// of CPUs that Go may use.
+	Workers int
+	// Errors, if set, receives the error for each line that cannot be converted, and the
+	// line is left out of the output. Otherwise the first such error stops the conversion,
+	// once the lines before it have been written.
+	Errors func(err *LineError)
+}
+
+// LineError is the error for one line of a stream that cannot be converted.
+type LineError struct {
+	// Line is the number of the line, starting from 1.
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %v: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// ConvertJSONLines reads JSON Lines, with one JSON value per line, and writes each value as
+// a serialized document on a line of its own, as if by ConvertJSON. Since newlines in keys
+// and strings are escaped, every document fits on one line. Lines are converted by a pool of
+// workers but written in their original order, and blank lines are skipped.
+func ConvertJSONLines(reader io.Reader, writer io.Writer, options LinesOptions) error {
+	return convertLines(reader, writer, options, func(line []byte) ([]byte, error) {
+		return ConvertJSON(bytes.NewReader(line), options.JSONOptions)
+	})
+}
+
+// ConvertCerealLines reads one document per line and writes each as a line of JSON, tagging
+// the numbers as MarshalTypedJSON does if the Typed option is set. It is the reverse of
+// ConvertJSONLines.
+func ConvertCerealLines(reader io.Reader, writer io.Writer, options LinesOptions) error {
+	return convertLines(reader, writer, options, func(line []byte) ([]byte, error) {
+		doc, err := ParseOrdered(bytes.NewReader(line))
+		if err != nil {
+			return nil, err
+		}
+
+		if options.Typed {
+			return MarshalTypedJSON(doc)
+		}
+		return json.Marshal(doc)
+	})
+}
+
+// lineResult is the outcome of converting one line.
+type lineResult struct {
+	data []byte
+	err  *LineError
+}
+
+// lineJob is a line waiting to be converted, along with where to send the result.
+type lineJob struct {
+	number int
+	data   []byte
+	result chan lineResult
+}
+
+// convertLines converts each line with a pool of workers and writes the results in order.
+func convertLines(reader io.Reader, writer io.Writer, options LinesOptions, convert func([]byte) ([]byte, error)) error {
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	jobs := make(chan lineJob)
+	pending := make(chan chan lineResult, workers*2)
+	done := make(chan struct{})
+	defer close(done)
+
+	for range workers {
+		go func() {
+			for job := range jobs {
+				data, err := convert(job.data)
+				if err != nil {
+					job.result <- lineResult{err: &LineError{Line: job.number, Err: err}}
+				} else {
+					job.result <- lineResult{data: data}
+				}
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		readErr <- readLines(reader, func(number int, line []byte) bool {
+			job := lineJob{number: number, data: line, result: make(chan lineResult, 1)}
+			select {
+			case pending <- job.result:
+			case <-done:
+				return false
+			}
+			select {
+			case jobs <- job:
+			case <-done:
+				return false
+			}
+			return true
+		})
+	}()
+
+	out := bufio.NewWriter(writer)
+	for result := range pending {
+		r := <-result
+		if r.err != nil {
+			if options.Errors == nil {
+				out.Flush()
+				return r.err
+			}
+			options.Errors(r.err)
+			continue
+		}
+
+		out.Write(r.data)
+		err := out.WriteByte('\n')
+		if err != nil {
+			return err
+		}
+	}
+
+	err := <-readErr
+	if err != nil {
+		out.Flush()
+		return err
+	}
+
+	return out.Flush()
+}
+
+// readLines calls yield with each line that is not blank until it returns false.
+func readLines(reader io.Reader, yield func(number int, line []byte) bool) error {
+	r := bufio.NewReader(reader)
+	for number := 1; ; number++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && !yield(number, trimmed) {
+			return nil
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
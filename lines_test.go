@@ -0,0 +1,107 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConvertJSONLines(t *testing.T) {
+	input := "{\"a\":1}\n\n{\"b\":\"two\\nlines\"}\r\n[1,2.5]\n{\"c\":null}"
+
+	out := bytes.Buffer{}
+	err := ConvertJSONLines(strings.NewReader(input), &out, LinesOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{a:i1}\n1{b:\"two\\nlines}\n1{value:[i1,d2.5]}\n1{c:n}\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
+
+func TestConvertJSONLines_Order(t *testing.T) {
+	input := strings.Builder{}
+	expected := strings.Builder{}
+	for i := range 1000 {
+		fmt.Fprintf(&input, "{\"n\":%v}\n", i)
+		fmt.Fprintf(&expected, "1{n:i%v}\n", i)
+	}
+
+	out := bytes.Buffer{}
+	err := ConvertJSONLines(strings.NewReader(input.String()), &out, LinesOptions{Workers: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != expected.String() {
+		t.Error("expected the lines to keep their order")
+	}
+}
+
+func TestConvertJSONLines_Errors(t *testing.T) {
+	input := "{\"a\":1}\n{\"a\":\n{\"a\":3}\n{\"a\":1.5}\n"
+	schema := &Schema{Type: "map", Fields: []SchemaField{{Name: "a", Schema: &Schema{Type: "int"}}}}
+
+	errors := []string{}
+	out := bytes.Buffer{}
+	err := ConvertJSONLines(strings.NewReader(input), &out, LinesOptions{
+		JSONOptions: JSONOptions{Schema: schema},
+		Errors: func(err *LineError) {
+			errors = append(errors, err.Error())
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != "1{a:i1}\n1{a:i3}\n" {
+		t.Errorf("expected the lines without errors but got %q", out.String())
+	}
+	if len(errors) != 2 || errors[0] != "line 2: unexpected EOF" || errors[1] != "line 4: <root>.a: expected int but got 1.5" {
+		t.Errorf("unexpected errors %v", errors)
+	}
+
+	out.Reset()
+	err = ConvertJSONLines(strings.NewReader(input), &out, LinesOptions{})
+	if err == nil || err.Error() != "line 2: unexpected EOF" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if out.String() != "1{a:i1}\n" {
+		t.Errorf("expected the lines before the error but got %q", out.String())
+	}
+}
+
+func TestConvertCerealLines(t *testing.T) {
+	input := "1{a:i1,b:\"two\\nlines}\n1{a:f1.5}\nnot a document\n"
+
+	errors := []string{}
+	out := bytes.Buffer{}
+	err := ConvertCerealLines(strings.NewReader(input), &out, LinesOptions{
+		JSONOptions: JSONOptions{Typed: true},
+		Errors: func(err *LineError) {
+			errors = append(errors, err.Error())
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "{\"a\":{\"$i\":1},\"b\":\"two\\nlines\"}\n{\"a\":{\"$f\":1.5}}\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+	if len(errors) != 1 || !strings.HasPrefix(errors[0], "line 3: ") {
+		t.Errorf("unexpected errors %v", errors)
+	}
+
+	back := bytes.Buffer{}
+	err = ConvertJSONLines(&out, &back, LinesOptions{JSONOptions: JSONOptions{Typed: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.String() != "1{a:i1,b:\"two\\nlines}\n1{a:f1.5}\n" {
+		t.Errorf("expected the documents to round trip but got %q", back.String())
+	}
+}
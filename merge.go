@@ -0,0 +1,221 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ArrayStrategy decides how Merge combines an array with the array that overrides it.
+type ArrayStrategy int
+
+const (
+	// ReplaceArrays keeps only the array from the later document.
+	ReplaceArrays ArrayStrategy = iota
+	// AppendArrays adds the elements of the later array after those of the earlier one.
+	AppendArrays
+	// MergeArraysByKey merges maps from the two arrays that have the same value for the key
+	// set in Merger.ArrayKey, and appends the remaining elements of the later array.
+	MergeArraysByKey
+)
+
+// ConflictStrategy decides what Merge does when a value is overridden by a value of another
+// type.
+type ConflictStrategy int
+
+const (
+	// ConflictError reports an error, since a value that changes type between layers is
+	// usually a mistake.
+	ConflictError ConflictStrategy = iota
+	// ConflictOverride keeps the value from the later document.
+	ConflictOverride
+)
+
+// Merger merges documents using the configured strategies. The zero value replaces arrays
+// and reports an error on type conflicts.
+type Merger struct {
+	Arrays    ArrayStrategy
+	ArrayKey  string
+	Conflicts ConflictStrategy
+}
+
+// Merge deeply merges documents with the default strategies of Merger.
+func Merge(docs ...map[string]any) (map[string]any, error) {
+	return (&Merger{}).Merge(docs...)
+}
+
+// Merge deeply merges the documents, such as the results of Parse, in order so that later
+// documents override earlier ones. Maps are merged entry by entry, arrays are combined using
+// the array strategy and any other value replaces the earlier one. A null removes an entry.
+// The documents themselves are not modified.
+func (m *Merger) Merge(docs ...map[string]any) (map[string]any, error) {
+	result := map[string]any{}
+	for i, doc := range docs {
+		err := m.mergeMap(result, doc, []string{"<root>"}, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (m *Merger) mergeMap(dst, src map[string]any, path []string, layer int) error {
+	for key, value := range src {
+		if value == nil {
+			delete(dst, key)
+			continue
+		}
+
+		merged, err := m.mergeValue(dst[key], value, appendPath(path, key), layer)
+		if err != nil {
+			return err
+		}
+		dst[key] = merged
+	}
+
+	return nil
+}
+
+func (m *Merger) mergeValue(dst, src any, path []string, layer int) (any, error) {
+	if dst == nil {
+		return m.cleanValue(src), nil
+	}
+
+	dstType, _ := valueTypeOf(dst)
+	srcType, _ := valueTypeOf(src)
+	if dstType != srcType {
+		if m.Conflicts == ConflictOverride {
+			return m.cleanValue(src), nil
+		}
+		return nil, fmt.Errorf("%v: document %v cannot replace a value of type %T with %T", strings.Join(path, "."), layer, dst, src)
+	}
+
+	switch d := dst.(type) {
+	case map[string]any:
+		s, ok := src.(map[string]any)
+		if !ok {
+			break
+		}
+
+		err := m.mergeMap(d, s, path, layer)
+		return d, err
+	case []any:
+		s, ok := src.([]any)
+		if !ok {
+			break
+		}
+
+		return m.mergeArray(d, s, path, layer)
+	}
+
+	return m.cleanValue(src), nil
+}
+
+func (m *Merger) mergeArray(dst, src []any, path []string, layer int) (any, error) {
+	switch m.Arrays {
+	case AppendArrays:
+		return append(dst, m.cleanValue(src).([]any)...), nil
+	case MergeArraysByKey:
+		for _, element := range src {
+			index := m.findByKey(dst, element)
+			if index < 0 {
+				dst = append(dst, m.cleanValue(element))
+				continue
+			}
+
+			merged, err := m.mergeValue(dst[index], element, appendPath(path, strconv.Itoa(index)), layer)
+			if err != nil {
+				return nil, err
+			}
+			dst[index] = merged
+		}
+		return dst, nil
+	}
+
+	return m.cleanValue(src), nil
+}
+
+// findByKey returns the index of the map in the array with the same value for the array key
+// as element, or -1 if there is none.
+func (m *Merger) findByKey(array []any, element any) int {
+	e, ok := element.(map[string]any)
+	if !ok {
+		return -1
+	}
+	key, ok := e[m.ArrayKey]
+	if !ok {
+		return -1
+	}
+
+	for i, candidate := range array {
+		c, ok := candidate.(map[string]any)
+		if ok && reflect.DeepEqual(c[m.ArrayKey], key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// cleanValue returns a copy of a value that is added to the result, without the null entries
+// of its maps.
+func (m *Merger) cleanValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, child := range v {
+			if child != nil {
+				result[key] = m.cleanValue(child)
+			}
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			result[i] = m.cleanValue(child)
+		}
+		return result
+	}
+
+	return value
+}
+
+// LoadLayered reads the documents in the files at the given paths, merges them with the
+// default strategies of Merger and unmarshals the result into v.
+func LoadLayered(v any, paths ...string) error {
+	return (&Merger{}).LoadLayered(v, paths...)
+}
+
+// LoadLayered reads the documents in the files at the given paths, merges them in order and
+// unmarshals the result into v, which must be a pointer to a struct or map.
+func (m *Merger) LoadLayered(v any, paths ...string) error {
+	docs := []map[string]any{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		doc, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		docs = append(docs, doc)
+	}
+
+	merged, err := m.Merge(docs...)
+	if err != nil {
+		return err
+	}
+
+	data, err := Serialize(merged, "1")
+	if err != nil {
+		return err
+	}
+
+	return Unmarshal(data, v)
+}
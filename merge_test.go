@@ -0,0 +1,153 @@
+package cereal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parseDocs(t *testing.T, inputs ...string) []map[string]any {
+	docs := []map[string]any{}
+	for _, input := range inputs {
+		doc, err := Parse(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestMerge(t *testing.T) {
+	docs := parseDocs(t,
+		"1{name:\"svc,db:{host:\"localhost,port:i5432},tags:[\"a],debug:b1}",
+		"1{db:{host:\"db.internal},tags:[\"b]}",
+		"1{db:{user:\"admin},debug:n}",
+	)
+
+	result, err := Merge(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{
+		"name": "svc",
+		"db":   map[string]any{"host": "db.internal", "port": 5432, "user": "admin"},
+		"tags": []any{"b"},
+	}
+	if changes := Diff(result, expected); len(changes) > 0 {
+		t.Error("expected the documents to be merged but got", changes)
+	}
+
+	if docs[0]["db"].(map[string]any)["host"] != "localhost" {
+		t.Error("expected the documents to be left unchanged")
+	}
+}
+
+func TestMerge_AppendArrays(t *testing.T) {
+	docs := parseDocs(t, "1{tags:[\"a]}", "1{tags:[\"b,\"c]}")
+
+	result, err := (&Merger{Arrays: AppendArrays}).Merge(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := Diff(result, map[string]any{"tags": []any{"a", "b", "c"}}); len(changes) > 0 {
+		t.Error("expected the arrays to be appended but got", changes)
+	}
+}
+
+func TestMerge_MergeArraysByKey(t *testing.T) {
+	docs := parseDocs(t,
+		"1{servers:[{name:\"a,port:i80},{name:\"b,port:i81}]}",
+		"1{servers:[{name:\"b,port:i8081,tls:b1},{name:\"c,port:i82},\"other]}",
+	)
+
+	result, err := (&Merger{Arrays: MergeArraysByKey, ArrayKey: "name"}).Merge(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]any{"servers": []any{
+		map[string]any{"name": "a", "port": 80},
+		map[string]any{"name": "b", "port": 8081, "tls": true},
+		map[string]any{"name": "c", "port": 82},
+		"other",
+	}}
+	if changes := Diff(result, expected); len(changes) > 0 {
+		t.Error("expected the arrays to be merged by key but got", changes)
+	}
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	docs := parseDocs(t, "1{a:{b:i1}}", "1{a:{b:d1}}")
+
+	_, err := Merge(docs...)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	msg := err.Error()
+	if msg != "<root>.a.b: document 1 cannot replace a value of type int with float64" {
+		t.Error("expected error to be '<root>.a.b: document 1 cannot replace a value of type int with float64' but got", msg)
+	}
+
+	result, err := (&Merger{Conflicts: ConflictOverride}).Merge(docs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["a"].(map[string]any)["b"] != 1.0 {
+		t.Error("expected the later value to win but got", result)
+	}
+}
+
+func TestLoadLayered(t *testing.T) {
+	type config struct {
+		Name  string
+		Port  int
+		Hosts []string
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.cereal":  "1{Name:\"svc,Port:i80,Hosts:[\"a]}",
+		"prod.cereal":  "1{Port:i8080}",
+		"local.cereal": "1{Hosts:[\"localhost]}",
+	}
+	paths := []string{}
+	for _, name := range []string{"base.cereal", "prod.cereal", "local.cereal"} {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(files[name]), 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	var c config
+	err := LoadLayered(&c, paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Name != "svc" || c.Port != 8080 || len(c.Hosts) != 1 || c.Hosts[0] != "localhost" {
+		t.Errorf("expected the layers to be merged but got %+v", c)
+	}
+}
+
+func TestLoadLayered_ParseError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.cereal")
+	err := os.WriteFile(path, []byte("1{a:ix}"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m map[string]any
+	err = LoadLayered(&m, path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if err.Error() != path+": <root>.a: invalid int 'x'" {
+		t.Error("expected the error to name the file but got", err)
+	}
+}
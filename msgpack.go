@@ -0,0 +1,383 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// msgPackExtTag marks an extension value, written as its type and its data in base64, as in
+// {$ext:[i-1,"AAAAAA==]}.
+const msgPackExtTag = "$ext"
+
+// ConvertMsgPack reads a single MessagePack value from the provided io.Reader and returns it
+// as a serialized document. Nil, booleans, strings and arrays become the matching values, a
+// float 32 becomes a float32 and a float 64 a float64. An integer becomes an int if it was
+// written in the smallest format for its value and fits in an int. Any other integer keeps
+// its format as a tag, as in {$uint16:i5} or {$int64:"-5000000000}.
+//
+// A binary blob becomes {$bin:"<base64>}, an extension {$ext:[<type>,"<base64>]}, and a root
+// value other than a map is stored under the key $value. A map whose only key starts with $
+// is wrapped as {$map:{...}}, so it is not mistaken for a tag. Keys must be strings.
+// MarshalMsgPack reads the tags back, giving the same bytes as the original for values
+// written in the smallest formats.
+func ConvertMsgPack(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &binaryReader{data: data}
+	value, err := decodeMsgPack(r, []string{"<root>"}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.pos < len(data) {
+		return nil, fmt.Errorf("unexpected data after the MessagePack value")
+	}
+
+	return binaryDocument(value)
+}
+
+func decodeMsgPack(r *binaryReader, path []string, depth int) (any, error) {
+	if depth > maxBinaryDepth {
+		return nil, fmt.Errorf("%v: values are nested too deeply", strings.Join(path, "."))
+	}
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return int(b), nil
+	case b >= 0xe0:
+		return int(int8(b)), nil
+	case b <= 0x8f:
+		return decodeMsgPackMap(r, uint64(b&0x0f), path, depth)
+	case b <= 0x9f:
+		return decodeMsgPackArray(r, uint64(b&0x0f), path, depth)
+	case b <= 0xbf:
+		s, err := r.next(uint64(b & 0x1f))
+		return string(s), err
+	}
+
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := r.uint(1 << (b - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		blob, err := r.next(n)
+		if err != nil {
+			return nil, err
+		}
+		return blobValue(blob), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := r.uint(1 << (b - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgPackExt(r, n)
+	case 0xca:
+		u, err := r.uint(4)
+		return math.Float32frombits(uint32(u)), err
+	case 0xcb:
+		u, err := r.uint(8)
+		return math.Float64frombits(u), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := r.uint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		smallest := u > math.MaxInt64 || msgPackIntFormat(int64(u)) == b
+		return binaryInt(strconv.FormatUint(u, 10), fmt.Sprintf("$uint%v", 8<<(b-0xcc)), smallest), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		shift := 64 - 8<<(b-0xd0)
+		u, err := r.uint(1 << (b - 0xd0))
+		if err != nil {
+			return nil, err
+		}
+		i := int64(u<<shift) >> shift
+		return binaryInt(strconv.FormatInt(i, 10), fmt.Sprintf("$int%v", 8<<(b-0xd0)), msgPackIntFormat(i) == b), nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return decodeMsgPackExt(r, 1<<(b-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := r.uint(1 << (b - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		s, err := r.next(n)
+		return string(s), err
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgPackArray(r, n, path, depth)
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return decodeMsgPackMap(r, n, path, depth)
+	}
+
+	return nil, fmt.Errorf("%v: invalid byte 0x%02x", strings.Join(path, "."), b)
+}
+
+func decodeMsgPackArray(r *binaryReader, n uint64, path []string, depth int) (any, error) {
+	length, err := r.length(n, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]any, length)
+	for i := range result {
+		result[i], err = decodeMsgPack(r, appendPath(path, strconv.Itoa(i)), depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func decodeMsgPackMap(r *binaryReader, n uint64, path []string, depth int) (any, error) {
+	length, err := r.length(n, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	m := NewOrderedMap()
+	for range length {
+		key, err := decodeMsgPack(r, path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		keyString, err := binaryKey(key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := decodeMsgPack(r, appendPath(path, keyString), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m.Set(keyString, value)
+	}
+
+	return binaryMap(m), nil
+}
+
+func decodeMsgPackExt(r *binaryReader, n uint64) (any, error) {
+	extType, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return singleEntryMap(msgPackExtTag, []any{int(int8(extType)), base64.StdEncoding.EncodeToString(data)}), nil
+}
+
+// msgPackIntFormat returns the byte of the smallest format for an integer, preferring the
+// unsigned formats for integers that are not negative.
+func msgPackIntFormat(i int64) byte {
+	switch {
+	case i >= 0 && i <= 0x7f, i < 0 && i >= -32:
+		return byte(i)
+	case i >= 0 && i <= math.MaxUint8:
+		return 0xcc
+	case i >= 0 && i <= math.MaxUint16:
+		return 0xcd
+	case i >= 0 && i <= math.MaxUint32:
+		return 0xce
+	case i >= 0:
+		return 0xcf
+	case i >= math.MinInt8:
+		return 0xd0
+	case i >= math.MinInt16:
+		return 0xd1
+	case i >= math.MinInt32:
+		return 0xd2
+	}
+	return 0xd3
+}
+
+// MarshalMsgPack returns a document, such as the result of ParseOrdered, as MessagePack,
+// reading the tags written by ConvertMsgPack. Integers that are not tagged are written in
+// the smallest format, float32 values as a float 32 and float64 values as a float 64.
+func MarshalMsgPack(doc any) ([]byte, error) {
+	e := &msgPackEncoder{}
+	err := encodeBinaryDocument(e, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.buf.Bytes(), nil
+}
+
+type msgPackEncoder struct {
+	buf bytes.Buffer
+}
+
+// writeSized writes a format byte followed by a big-endian unsigned integer of the given
+// number of bytes.
+func (e *msgPackEncoder) writeSized(b byte, n uint64, size int) {
+	e.buf.WriteByte(b)
+	for i := size - 1; i >= 0; i-- {
+		e.buf.WriteByte(byte(n >> (8 * i)))
+	}
+}
+
+// writeLength writes the smallest of the formats for a length, which take 1, 2 and 4 bytes
+// or 2 and 4 bytes if there are only two.
+func (e *msgPackEncoder) writeLength(n int, formats ...byte) {
+	size := 1
+	if len(formats) == 2 {
+		size = 2
+	}
+
+	for i, b := range formats {
+		if i == len(formats)-1 || uint64(n) < 1<<(8*size) {
+			e.writeSized(b, uint64(n), size)
+			return
+		}
+		size *= 2
+	}
+}
+
+func (e *msgPackEncoder) writeNull() {
+	e.buf.WriteByte(0xc0)
+}
+
+func (e *msgPackEncoder) writeBool(b bool) {
+	if b {
+		e.buf.WriteByte(0xc3)
+	} else {
+		e.buf.WriteByte(0xc2)
+	}
+}
+
+func (e *msgPackEncoder) writeInt(i int64) {
+	b := msgPackIntFormat(i)
+	switch {
+	case b < 0xcc || b >= 0xe0:
+		e.buf.WriteByte(b)
+	case b <= 0xcf:
+		e.writeSized(b, uint64(i), 1<<(b-0xcc))
+	default:
+		e.writeSized(b, uint64(i), 1<<(b-0xd0))
+	}
+}
+
+func (e *msgPackEncoder) writeSizedInt(n *big.Int, unsigned bool, bits int) error {
+	format := byte(0xd0)
+	if unsigned {
+		format = 0xcc
+	}
+	for size := 8; size < bits; size *= 2 {
+		format++
+	}
+
+	if unsigned {
+		if n.Sign() < 0 || n.BitLen() > bits {
+			return fmt.Errorf("%v does not fit in a uint%v", n, bits)
+		}
+		e.writeSized(format, n.Uint64(), bits/8)
+		return nil
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("%v does not fit in an int%v", n, bits)
+	}
+	e.writeSized(format, uint64(n.Int64()), bits/8)
+	return nil
+}
+
+func (e *msgPackEncoder) writeFloat32(f float32) {
+	e.writeSized(0xca, uint64(math.Float32bits(f)), 4)
+}
+
+func (e *msgPackEncoder) writeFloat64(f float64) {
+	e.writeSized(0xcb, math.Float64bits(f), 8)
+}
+
+func (e *msgPackEncoder) writeString(s string) {
+	if len(s) < 32 {
+		e.buf.WriteByte(0xa0 | byte(len(s)))
+	} else {
+		e.writeLength(len(s), 0xd9, 0xda, 0xdb)
+	}
+	e.buf.WriteString(s)
+}
+
+func (e *msgPackEncoder) writeBytes(b []byte) {
+	e.writeLength(len(b), 0xc4, 0xc5, 0xc6)
+	e.buf.Write(b)
+}
+
+func (e *msgPackEncoder) writeArrayHeader(n int) {
+	if n < 16 {
+		e.buf.WriteByte(0x90 | byte(n))
+	} else {
+		e.writeLength(n, 0xdc, 0xdd)
+	}
+}
+
+func (e *msgPackEncoder) writeMapHeader(n int) {
+	if n < 16 {
+		e.buf.WriteByte(0x80 | byte(n))
+	} else {
+		e.writeLength(n, 0xde, 0xdf)
+	}
+}
+
+func (e *msgPackEncoder) writeTagged(tag string, value any, path []string) error {
+	if tag != msgPackExtTag {
+		return fmt.Errorf("%v: unsupported tag", strings.Join(path, "."))
+	}
+
+	ext, ok := value.([]any)
+	if !ok || len(ext) != 2 {
+		return fmt.Errorf("%v: expected a type and base64 data", strings.Join(path, "."))
+	}
+	extType, ok := ext[0].(int)
+	if !ok || extType < math.MinInt8 || extType > math.MaxInt8 {
+		return fmt.Errorf("%v: invalid extension type %v", strings.Join(path, "."), ext[0])
+	}
+	data, err := binaryBlob(ext[1], appendPath(path, "1"))
+	if err != nil {
+		return err
+	}
+
+	switch len(data) {
+	case 1, 2, 4, 8, 16:
+		format := byte(0xd4)
+		for size := 1; size < len(data); size *= 2 {
+			format++
+		}
+		e.buf.WriteByte(format)
+	default:
+		e.writeLength(len(data), 0xc7, 0xc8, 0xc9)
+	}
+	e.buf.WriteByte(byte(int8(extType)))
+	e.buf.Write(data)
+	return nil
+}
@@ -0,0 +1,149 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestConvertMsgPack(t *testing.T) {
+	tests := map[string]string{
+		// {"a":1,"b":-1,"c":nil,"d":true,"e":"hi"}
+		"85a16101a162ffa163c0a164c3a165a26869": `1{a:i1,b:i-1,c:n,d:b1,e:"hi}`,
+		// {"f":float 32 1.5,"g":float 64 2.5}
+		"82a166ca3fc00000a167cb4004000000000000": `1{f:f1.5,g:d2.5}`,
+		// {"u":uint 16 5,"i":int 32 -1,"s":uint 8 200,"x":uint 64 2^63}
+		"84a175cd0005a169d2ffffffffa173ccc8a178cf8000000000000000": `1{u:{$uint16:i5},i:{$int32:i-1},s:i200,x:{$uint64:"9223372036854775808}}`,
+		// {"b":bin 8 [1 2],"e":fixext 1 type 1 [0xff]}
+		"82a162c4020102a165d401ff": `1{b:{$bin:"AQI=},e:{$ext:[i1,"/w==]}}`,
+		// {"l":[1,[]],"m":{"$k":1}}
+		"82a16c920190a16d81a2246b01": `1{l:[i1,[]],m:{$map:{$k:i1}}}`,
+		// [1,2]
+		"920102": `1{$value:[i1,i2]}`,
+		// bin 8 [1]
+		"c40101": `1{$value:{$bin:"AQ==}}`,
+		// {"$k":1}
+		"81a2246b01": `1{$map:{$k:i1}}`,
+	}
+
+	for input, expected := range tests {
+		data, _ := hex.DecodeString(input)
+		result, err := ConvertMsgPack(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", input, err)
+			continue
+		}
+		if string(result) != expected {
+			t.Errorf("expected %v for %v but got %v", expected, input, string(result))
+		}
+	}
+}
+
+func TestConvertMsgPack_Errors(t *testing.T) {
+	tests := map[string]string{
+		"81a16192":     "unexpected EOF",
+		"810102":       "<root>: map keys must be strings, not int",
+		"81a161c1":     "<root>.a: invalid byte 0xc1",
+		"0102":         "unexpected data after the MessagePack value",
+		"dfffffffff01": "unexpected EOF",
+		"":             "EOF",
+	}
+
+	for input, expected := range tests {
+		data, _ := hex.DecodeString(input)
+		_, err := ConvertMsgPack(bytes.NewReader(data))
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q for %v but got %v", expected, input, err)
+		}
+	}
+}
+
+func TestMarshalMsgPack(t *testing.T) {
+	tests := map[string]string{
+		`1{a:i1,b:i-1,c:n,d:b1,e:"hi}`: "85a16101a162ffa163c0a164c3a165a26869",
+		`1{a:i300,b:i-200,c:i70000}`:   "83a161cd012ca162d1ff38a163ce00011170",
+		`1{f:f1.5,g:d2.5}`:             "82a166ca3fc00000a167cb4004000000000000",
+		`1{$value:[i1,"x]}`:            "9201a178",
+		`1{$value:n}`:                  "c0",
+		`1{e:{$ext:[i-1,"AQID]}}`:      "81a165c703ff010203",
+	}
+
+	for input, expected := range tests {
+		doc, err := ParseOrdered(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := MarshalMsgPack(doc)
+		if err != nil {
+			t.Errorf("unexpected error for %v: %v", input, err)
+			continue
+		}
+		if hex.EncodeToString(result) != expected {
+			t.Errorf("expected %v for %v but got %x", expected, input, result)
+		}
+	}
+}
+
+func TestMarshalMsgPack_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"85a16101a162ffa163c0a164c3a165a26869",
+		"82a166ca3fc00000a167cb4004000000000000",
+		"84a175cd0005a169d2ffffffffa173ccc8a178cf8000000000000000",
+		"82a162c4020102a165d401ff",
+		"82a16c920190a16d81a2246b01",
+		"920102",
+		"81a2246b01",
+		"d3fffffffeffffffff",
+		"c70305010203",
+	}
+
+	for _, input := range inputs {
+		data, _ := hex.DecodeString(input)
+		converted, err := ConvertMsgPack(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := ParseOrdered(bytes.NewReader(converted))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := MarshalMsgPack(doc)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", converted, err)
+			continue
+		}
+		if hex.EncodeToString(result) != input {
+			t.Errorf("expected %v after a round trip through %s but got %x", input, converted, result)
+		}
+	}
+}
+
+func TestMarshalMsgPack_Errors(t *testing.T) {
+	tests := map[string]string{
+		`1{a:{$uint8:i300}}`:       "<root>.a.$uint8: 300 does not fit in a uint8",
+		`1{a:{$int8:i-129}}`:       "<root>.a.$int8: -129 does not fit in an int8",
+		`1{a:{$bin:"???}}`:         "<root>.a.$bin: illegal base64 data",
+		`1{a:{$f16:f1.5}}`:         "<root>.a.$f16: unsupported tag",
+		`1{a:{$ext:[i300,"AA==]}}`: "<root>.a.$ext: invalid extension type 300",
+	}
+
+	for input, expected := range tests {
+		doc, err := ParseOrdered(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = MarshalMsgPack(doc)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error %q for %v but got %v", expected, input, err)
+		}
+	}
+
+	_, err := MarshalMsgPack([]any{1})
+	if err == nil || err.Error() != "<root>: expected a map" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
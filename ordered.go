@@ -0,0 +1,203 @@
+package cereal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"slices"
+)
+
+// OrderedMap is a map with string keys that remembers the order in which its keys were added.
+// Serialize writes its entries in that order, and ParseOrdered produces one for every map in a
+// document so that the order of the document is kept.
+type OrderedMap struct {
+	keys   []string
+	values map[string]any
+}
+
+// NewOrderedMap returns an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{values: map[string]any{}}
+}
+
+// Get returns the value stored for the key and whether it was present.
+func (m *OrderedMap) Get(key string) (any, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Set stores the value for the key. A new key is added after all existing keys, while an
+// existing key keeps its position.
+func (m *OrderedMap) Set(key string, value any) {
+	if m.values == nil {
+		m.values = map[string]any{}
+	}
+
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Delete removes the key and its value.
+func (m *OrderedMap) Delete(key string) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+
+	delete(m.values, key)
+	m.keys = slices.DeleteFunc(m.keys, func(k string) bool { return k == key })
+}
+
+// Len returns the number of entries in the map.
+func (m *OrderedMap) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns the keys of the map in order.
+func (m *OrderedMap) Keys() []string {
+	return slices.Clone(m.keys)
+}
+
+// All returns an iterator over the entries of the map in order.
+func (m *OrderedMap) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, key := range m.keys {
+			if !yield(key, m.values[key]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON encodes the map as a JSON object with its keys in order.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a JSON object into the map, keeping the order of its keys. Nested
+// objects are decoded into an *OrderedMap as well.
+func (m *OrderedMap) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	tok, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("cannot unmarshal %v into an ordered map", tok)
+	}
+
+	*m = OrderedMap{values: map[string]any{}}
+	return decodeJSONObject(decoder, m)
+}
+
+func decodeJSONObject(decoder *json.Decoder, m *OrderedMap) error {
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+
+		key := tok.(string)
+		value, err := decodeJSONValue(decoder)
+		if err != nil {
+			return err
+		}
+
+		m.Set(key, value)
+	}
+
+	// consume the closing brace
+	_, err := decoder.Token()
+	return err
+}
+
+func decodeJSONValue(decoder *json.Decoder) (any, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		m := NewOrderedMap()
+		return m, decodeJSONObject(decoder, m)
+	case json.Delim('['):
+		result := []any{}
+		for decoder.More() {
+			value, err := decodeJSONValue(decoder)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+
+		// consume the closing bracket
+		_, err := decoder.Token()
+		return result, err
+	}
+
+	return tok, nil
+}
+
+// ParseOrdered reads from the provided io.Reader and returns an ordered map representation of
+// the data. Nested maps are returned as an *OrderedMap as well.
+func ParseOrdered(reader io.Reader) (*OrderedMap, error) {
+	t := NewTokenizer(reader)
+	_, err := t.Next()
+	if err != nil {
+		return NewOrderedMap(), err
+	}
+
+	return decodeOrderedMapV1(t, []string{"<root>"})
+}
+
+func decodeOrderedMapV1(t *Tokenizer, path []string) (*OrderedMap, error) {
+	result := NewOrderedMap()
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return result, err
+		}
+		if tok.Kind == MapEnd {
+			return result, nil
+		}
+
+		key := tok.Value
+		tok, err = t.Next()
+		if err != nil {
+			return result, err
+		}
+
+		value, err := decodeValueV1(t, tok, append(path, key), true)
+		if err != nil {
+			return result, err
+		}
+
+		result.Set(key, value)
+	}
+}
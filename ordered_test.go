@@ -0,0 +1,135 @@
+package cereal
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestOrderedMap(t *testing.T) {
+	m := NewOrderedMap()
+	m.Set("b", 1)
+	m.Set("a", 2)
+	m.Set("c", 3)
+	m.Set("b", 4)
+
+	if !slices.Equal(m.Keys(), []string{"b", "a", "c"}) {
+		t.Error("expected keys to be [b a c] but got", m.Keys())
+	}
+
+	value, ok := m.Get("b")
+	if !ok || value != 4 {
+		t.Error("expected 'b' to be 4 but got", value)
+	}
+
+	m.Delete("a")
+	if m.Len() != 2 || !slices.Equal(m.Keys(), []string{"b", "c"}) {
+		t.Error("expected keys to be [b c] but got", m.Keys())
+	}
+
+	_, ok = m.Get("a")
+	if ok {
+		t.Error("expected 'a' to be deleted")
+	}
+}
+
+func TestOrderedMap_ZeroValue(t *testing.T) {
+	m := OrderedMap{}
+	m.Set("a", 1)
+
+	if m.Len() != 1 {
+		t.Error("expected a single entry but got", m.Len())
+	}
+}
+
+func TestParseOrdered(t *testing.T) {
+	m, err := ParseOrdered(strings.NewReader("1{z:i1,a:{y:b1,b:b0},m:[{d:i1,c:i2}]}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(m.Keys(), []string{"z", "a", "m"}) {
+		t.Error("expected keys to be [z a m] but got", m.Keys())
+	}
+
+	a, _ := m.Get("a")
+	if !slices.Equal(a.(*OrderedMap).Keys(), []string{"y", "b"}) {
+		t.Error("expected nested keys to be [y b] but got", a.(*OrderedMap).Keys())
+	}
+
+	arr, _ := m.Get("m")
+	if !slices.Equal(arr.([]any)[0].(*OrderedMap).Keys(), []string{"d", "c"}) {
+		t.Error("expected keys in array to be [d c] but got", arr.([]any)[0].(*OrderedMap).Keys())
+	}
+}
+
+func TestUnmarshal_OrderedMap(t *testing.T) {
+	m := OrderedMap{}
+	err := Unmarshal([]byte("1{z:i1,a:i2}"), &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(m.Keys(), []string{"z", "a"}) {
+		t.Error("expected keys to be [z a] but got", m.Keys())
+	}
+}
+
+func TestSerialize_OrderedMap(t *testing.T) {
+	inner := NewOrderedMap()
+	inner.Set("y", true)
+	inner.Set("b:", "x")
+
+	m := NewOrderedMap()
+	m.Set("z", 1)
+	m.Set("a", inner)
+	m.Set("m", []any{inner})
+
+	b, err := Serialize(m, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{z:i1,a:{y:b1,b\\::\"x},m:[{y:b1,b\\::\"x}]}"
+	if string(b) != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, string(b))
+	}
+}
+
+func TestSerialize_OrderedMapNil(t *testing.T) {
+	m := NewOrderedMap()
+	m.Set("x", nil)
+
+	b, err := Serialize(m, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "1{x:n}" {
+		t.Error("expected '1{x:n}' but got", string(b))
+	}
+}
+
+func TestOrderedMap_JSON(t *testing.T) {
+	input := `{"z":1,"a":{"y":"s","b":[1,{"q":true}]},"n":null}`
+
+	m := NewOrderedMap()
+	err := json.Unmarshal([]byte(input), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(m.Keys(), []string{"z", "a", "n"}) {
+		t.Error("expected keys to be [z a n] but got", m.Keys())
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != input {
+		t.Errorf("expected '%v' but got '%v'", input, string(b))
+	}
+}
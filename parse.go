@@ -29,6 +29,7 @@ const (
 	String
 	Map
 	Array
+	Null
 )
 
 // Parse reads from the provided io.Reader and returns a map representation of the data.
@@ -52,256 +53,359 @@ func Parse(reader io.Reader) (map[string]any, error) {
 }
 
 func parseV1(reader io.Reader) (map[string]any, error) {
-	result := map[string]any{}
-	b := make([]byte, 1)
-	n, err := reader.Read(b)
-	if err != nil && err != io.EOF {
-		return result, err
-	}
-	if n == 0 {
-		return result, errors.New("<root>: unexpected end of input")
+	t := newTokenizerV1(reader)
+	_, err := t.Next()
+	if err != nil {
+		return map[string]any{}, err
 	}
 
-	if b[0] != '{' {
-		return result, errors.New("<root>: expected '{'")
+	return decodeMapV1(t, []string{"<root>"})
+}
+
+func parseMapV1(reader io.Reader, path []string) (map[string]any, error) {
+	return decodeMapV1(newContainerTokenizerV1(reader, Map, path), path)
+}
+
+func parseStructV1(reader io.Reader, rv reflect.Value, path []string) error {
+	return decodeStructV1(newContainerTokenizerV1(reader, Map, path), rv, path)
+}
+
+func parseTypedArrayV1(reader io.Reader, arrayValue reflect.Value, path []string) (reflect.Value, error) {
+	return decodeTypedArrayV1(newContainerTokenizerV1(reader, Array, path), arrayValue, path)
+}
+
+func parseArrayV1(reader io.Reader, path []string) ([]any, error) {
+	return decodeArrayV1(newContainerTokenizerV1(reader, Array, path), path, false)
+}
+
+// decodeValueV1 decodes the value started by tok. Maps are decoded into an *OrderedMap when
+// ordered is true.
+func decodeValueV1(t *Tokenizer, tok Token, path []string, ordered bool) (any, error) {
+	switch tok.Kind {
+	case MapStart:
+		if ordered {
+			return decodeOrderedMapV1(t, path)
+		}
+		return decodeMapV1(t, path)
+	case ArrayStart:
+		return decodeArrayV1(t, path, ordered)
+	case Scalar:
+		return parseValue(tok.Value, tok.Type, path)
 	}
 
-	return parseMapV1(reader, []string{"<root>"})
+	return nil, fmt.Errorf("%v: invalid state", strings.Join(path, "."))
 }
 
-func parseMapV1(reader io.Reader, path []string) (map[string]any, error) {
+func decodeMapV1(t *Tokenizer, path []string) (map[string]any, error) {
 	result := map[string]any{}
-	b := make([]byte, 1)
-
-	state := ReadingKey
-	key := strings.Builder{}
-	var valueType ValueType
-	value := strings.Builder{}
-	escaped := false
 	for {
-		n, err := reader.Read(b)
-		if err != nil && err != io.EOF {
+		tok, err := t.Next()
+		if err != nil {
 			return result, err
 		}
-		if n == 0 {
-			return result, fmt.Errorf("%v: unexpected end of input", strings.Join(path, "."))
+		if tok.Kind == MapEnd {
+			return result, nil
 		}
 
-		if state == ReadingKey {
-			if escaped {
-				escaped = false
-				key.Write(b)
-			} else if b[0] == '}' && key.Len() == 0 {
-				return result, nil
-			} else if b[0] == ',' && key.Len() == 0 {
-				continue
-			} else if b[0] == ':' {
-				state = ReadingType
-			} else if b[0] == '\\' {
-				escaped = true
-			} else {
-				key.Write(b)
-			}
-		} else if state == ReadingType {
-			valueType, err = parseValueType(b[0], path)
-			if err != nil {
-				return result, err
-			}
+		key := tok.Value
+		tok, err = t.Next()
+		if err != nil {
+			return result, err
+		}
 
-			switch valueType {
-			case Map:
-				result[key.String()], err = parseMapV1(reader, append(path, key.String()))
-				if err != nil {
-					return result, err
-				}
+		result[key], err = decodeValueV1(t, tok, append(path, key), false)
+		if err != nil {
+			return result, err
+		}
+	}
+}
 
-				// set the state for the next k/v pair
-				state = ReadingKey
-				key = strings.Builder{}
-				value = strings.Builder{}
-			case Array:
-				result[key.String()], err = parseArrayV1(reader, append(path, key.String()))
-				if err != nil {
-					return result, err
-				}
+// structFieldKey returns the key of a struct field in a document, which is the name given by
+// its cereal tag or otherwise the name of the field. A field tagged with "-" is left out, and
+// so is an embedded struct, whose fields are promoted to the struct that embeds it.
+func structFieldKey(f reflect.StructField) (string, bool) {
+	t := f.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if f.Anonymous && t.Kind() == reflect.Struct {
+		return "", false
+	}
 
-				// set the state for the next k/v pair
-				state = ReadingKey
-				key = strings.Builder{}
-				value = strings.Builder{}
-			default:
-				state = ReadingValue
-			}
-		} else if state == ReadingValue {
-			if escaped {
-				escaped = false
-				value.Write(b)
-			} else if b[0] == ',' {
-				result[key.String()], err = parseValue(value.String(), valueType, append(path, key.String()))
-				if err != nil {
-					return result, err
-				}
+	tag, ok := f.Tag.Lookup("cereal")
+	if !ok || tag == "" {
+		return f.Name, true
+	}
+	if tag == "-" {
+		return "", false
+	}
 
-				// set the state for the next k/v pair
-				state = ReadingKey
-				key = strings.Builder{}
-				value = strings.Builder{}
-			} else if b[0] == '}' {
-				result[key.String()], err = parseValue(value.String(), valueType, append(path, key.String()))
-				return result, err
-			} else if b[0] == '\\' {
-				escaped = true
-			} else {
-				value.Write(b)
-			}
-		} else {
-			return result, fmt.Errorf("%v: invalid state", strings.Join(path, "."))
+	return tag, true
+}
+
+// structFieldByKey returns the field of a struct with the given key, or an invalid value if
+// there is none.
+func structFieldByKey(rv reflect.Value, key string) reflect.Value {
+	for _, f := range reflect.VisibleFields(rv.Type()) {
+		if name, ok := structFieldKey(f); ok && name == key {
+			return fieldByIndex(rv, f.Index)
 		}
 	}
+
+	return reflect.Value{}
 }
 
-func parseStructV1(reader io.Reader, rv reflect.Value, path []string) error {
-	b := make([]byte, 1)
+// fieldByIndex returns the nested field of a struct with the given index, allocating the
+// embedded structs that it is promoted through when they are nil pointers. It returns an
+// invalid value if such a pointer cannot be set.
+func fieldByIndex(rv reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+
+	return rv
+}
 
-	state := ReadingKey
-	key := strings.Builder{}
-	var fv reflect.Value
-	var valueType ValueType
-	value := strings.Builder{}
-	escaped := false
+func decodeStructV1(t *Tokenizer, rv reflect.Value, path []string) error {
 	for {
-		n, err := reader.Read(b)
-		if err != nil && err != io.EOF {
+		tok, err := t.Next()
+		if err != nil {
 			return err
 		}
-		if n == 0 {
-			return fmt.Errorf("%v: unexpected end of input", strings.Join(path, "."))
+		if tok.Kind == MapEnd {
+			return nil
 		}
 
-		if state == ReadingKey {
-			if escaped {
-				escaped = false
-				key.Write(b)
-			} else if b[0] == '}' && key.Len() == 0 {
-				return nil
-			} else if b[0] == ',' && key.Len() == 0 {
-				continue
-			} else if b[0] == ':' {
-				state = ReadingType
-			} else if b[0] == '\\' {
-				escaped = true
-			} else {
-				key.Write(b)
-			}
-		} else if state == ReadingType {
-			fv = rv.FieldByName(key.String())
-			if !fv.IsValid() {
-				return fmt.Errorf("%v: unexpected field name '%v'", strings.Join(path, "."), key.String())
-			}
+		key := tok.Value
+		fv := structFieldByKey(rv, key)
+		if !fv.IsValid() {
+			return fmt.Errorf("%v: unexpected field name '%v'", strings.Join(path, "."), key)
+		}
 
-			valueType, err = parseValueType(b[0], path)
+		tok, err = t.Next()
+		if err != nil {
+			return err
+		}
+
+		if fv.Type() == rawValueType {
+			raw, err := readRawValueV1(t, tok)
 			if err != nil {
 				return err
 			}
 
-			switch valueType {
-			case Map:
-				k := fv.Kind()
-				switch k {
-				case reflect.Struct:
-					err := parseStructV1(reader, fv, append(path, key.String()))
-					if err != nil {
-						return err
-					}
-				case reflect.Map:
-					result, err := parseMapV1(reader, append(path, key.String()))
-					if err != nil {
-						return err
-					}
-					fv.Set(reflect.ValueOf(result))
-				default:
-					return fmt.Errorf(
-						"%v: a struct or map cannot be assigned to field %v with type %v",
-						strings.Join(path, "."),
-						key.String(),
-						fv.Type(),
-					)
-				}
+			fv.Set(reflect.ValueOf(raw))
+			continue
+		}
 
-				// set the state for the next k/v pair
-				state = ReadingKey
-				key = strings.Builder{}
-				value = strings.Builder{}
-			case Array:
-				sliceValue, err := parseTypedArrayV1(reader, fv, append(path, key.String()))
+		if fv.CanAddr() && fv.Addr().CanInterface() && !t.reflective {
+			if u, ok := fv.Addr().Interface().(Unmarshaler); ok {
+				raw, err := readRawValueV1(t, tok)
 				if err != nil {
 					return err
 				}
 
-				if sliceValue.Type() != fv.Type() {
-					return fmt.Errorf("%v: cannot assign slice of type %v to slice field '%v' of type %v", strings.Join(path, "."), sliceValue.Type(), key.String(), fv.Type())
+				err = u.UnmarshalCereal(raw)
+				if err != nil {
+					return err
 				}
+				continue
+			}
+		}
+
+		if fv.Kind() == reflect.Interface || (fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Interface) {
+			// values of any type are decoded as they are by Parse
+			result, err := decodeValueV1(t, tok, append(path, key), false)
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				continue
+			}
 
-				fv.Set(sliceValue)
+			resultValue := reflect.ValueOf(result)
+			if !resultValue.Type().AssignableTo(fv.Type()) {
+				return fmt.Errorf(
+					"%v: type %v cannot be assigned to field %v with type %v",
+					strings.Join(path, "."),
+					resultValue.Type(),
+					key,
+					fv.Type(),
+				)
+			}
 
-				// set the state for the next k/v pair
-				state = ReadingKey
-				key = strings.Builder{}
-				value = strings.Builder{}
-			default:
-				state = ReadingValue
-			}
-		} else if state == ReadingValue {
-			if escaped {
-				escaped = false
-				value.Write(b)
-			} else if b[0] == ',' || b[0] == '}' {
-				result, err := parseValue(value.String(), valueType, append(path, key.String()))
+			fv.Set(resultValue)
+			continue
+		}
+
+		switch tok.Kind {
+		case MapStart:
+			k := fv.Kind()
+			switch k {
+			case reflect.Struct:
+				err := decodeStructV1(t, fv, append(path, key))
 				if err != nil {
 					return err
 				}
-
-				resultValue := reflect.ValueOf(result)
-				if fv.Kind() == resultValue.Kind() {
-					fv.Set(resultValue)
-				} else {
-					return fmt.Errorf(
-						"%v: type %v cannot be assigned to field %v with type %v",
-						strings.Join(path, "."),
-						resultValue.Type(),
-						key.String(),
-						fv.Type(),
-					)
+			case reflect.Map:
+				mapValue, err := decodeTypedMapV1(t, fv.Type(), append(path, key))
+				if err != nil {
+					return err
 				}
+				fv.Set(mapValue)
+			default:
+				return fmt.Errorf(
+					"%v: a struct or map cannot be assigned to field %v with type %v",
+					strings.Join(path, "."),
+					key,
+					fv.Type(),
+				)
+			}
+		case ArrayStart:
+			sliceValue, err := decodeTypedArrayV1(t, fv, append(path, key))
+			if err != nil {
+				return err
+			}
 
-				if b[0] == ',' {
-					// set the state for the next k/v pair
-					state = ReadingKey
-					key = strings.Builder{}
-					value = strings.Builder{}
-				} else {
-					return nil
-				}
-			} else if b[0] == '\\' {
-				escaped = true
+			if !sliceValue.IsValid() {
+				// an empty array leaves the field as a nil slice
+				continue
+			}
+
+			if sliceValue.Type() != fv.Type() {
+				return fmt.Errorf("%v: cannot assign slice of type %v to slice field '%v' of type %v", strings.Join(path, "."), sliceValue.Type(), key, fv.Type())
+			}
+
+			fv.Set(sliceValue)
+		default:
+			result, err := parseValue(tok.Value, tok.Type, append(path, key))
+			if err != nil {
+				return err
+			}
+			if result == nil {
+				// a null leaves the field unchanged
+				continue
+			}
+
+			resultValue := reflect.ValueOf(result)
+			if fv.Kind() == resultValue.Kind() {
+				fv.Set(resultValue)
 			} else {
-				value.Write(b)
+				return fmt.Errorf(
+					"%v: type %v cannot be assigned to field %v with type %v",
+					strings.Join(path, "."),
+					resultValue.Type(),
+					key,
+					fv.Type(),
+				)
 			}
-		} else {
-			return fmt.Errorf("%v: invalid state", strings.Join(path, "."))
 		}
 	}
 }
 
-func parseTypedArrayV1(reader io.Reader, arrayValue reflect.Value, path []string) (reflect.Value, error) {
-	var sliceValue reflect.Value
-	b := make([]byte, 1)
+// decodeTypedMapV1 decodes a map into a new map of the given type, decoding each value into
+// the element type of the map. A null leaves its key out of the map.
+func decodeTypedMapV1(t *Tokenizer, mapType reflect.Type, path []string) (reflect.Value, error) {
+	if mapType.Key().Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("%v: cannot decode a map into type %v", strings.Join(path, "."), mapType)
+	}
+
+	mapValue := reflect.MakeMap(mapType)
+	elemType := mapType.Elem()
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return mapValue, err
+		}
+		if tok.Kind == MapEnd {
+			return mapValue, nil
+		}
+
+		key := tok.Value
+		keyValue := reflect.ValueOf(key).Convert(mapType.Key())
+		tok, err = t.Next()
+		if err != nil {
+			return mapValue, err
+		}
+
+		if elemType.Kind() == reflect.Interface {
+			result, err := decodeValueV1(t, tok, append(path, key), false)
+			if err != nil {
+				return mapValue, err
+			}
+
+			resultValue := reflect.ValueOf(result)
+			if result == nil {
+				resultValue = reflect.Zero(elemType)
+			} else if !resultValue.Type().AssignableTo(elemType) {
+				return mapValue, fmt.Errorf("%v: type %v cannot be assigned to map of type %v", strings.Join(path, "."), resultValue.Type(), mapType)
+			}
+
+			mapValue.SetMapIndex(keyValue, resultValue)
+			continue
+		}
+
+		var elemValue reflect.Value
+		switch tok.Kind {
+		case MapStart:
+			switch elemType.Kind() {
+			case reflect.Struct:
+				elemValue = reflect.New(elemType).Elem()
+				err = decodeStructV1(t, elemValue, append(path, key))
+			case reflect.Map:
+				elemValue, err = decodeTypedMapV1(t, elemType, append(path, key))
+			default:
+				return mapValue, fmt.Errorf("%v: a struct or map cannot be inserted into map of type %v", strings.Join(path, "."), mapType)
+			}
+			if err != nil {
+				return mapValue, err
+			}
+		case ArrayStart:
+			if elemType.Kind() != reflect.Slice {
+				return mapValue, fmt.Errorf("%v: an array cannot be inserted into map of type %v", strings.Join(path, "."), mapType)
+			}
+
+			elemValue, err = decodeTypedArrayV1(t, reflect.MakeSlice(elemType, 0, 0), append(path, key))
+			if err != nil {
+				return mapValue, err
+			}
+			if !elemValue.IsValid() {
+				elemValue = reflect.Zero(elemType)
+			}
+			if elemValue.Type() != elemType {
+				return mapValue, fmt.Errorf("%v: cannot insert slice of type %v into map of type %v", strings.Join(path, "."), elemValue.Type(), mapType)
+			}
+		default:
+			result, err := parseValue(tok.Value, tok.Type, append(path, key))
+			if err != nil {
+				return mapValue, err
+			}
+			if result == nil {
+				continue
+			}
+
+			elemValue = reflect.ValueOf(result)
+			if elemValue.Kind() != elemType.Kind() {
+				return mapValue, fmt.Errorf("%v: type %v cannot be inserted into map of type %v", strings.Join(path, "."), elemValue.Type(), mapType)
+			}
+			elemValue = elemValue.Convert(elemType)
+		}
+
+		mapValue.SetMapIndex(keyValue, elemValue)
+	}
+}
 
-	var valueType ValueType = -1
+func decodeTypedArrayV1(t *Tokenizer, arrayValue reflect.Value, path []string) (reflect.Value, error) {
+	var sliceValue reflect.Value
 	var origValueType ValueType = -1
-	state := ReadingType
-	value := strings.Builder{}
-	escaped := false
 
 	for {
 		var index string
@@ -311,210 +415,112 @@ func parseTypedArrayV1(reader io.Reader, arrayValue reflect.Value, path []string
 			index = strconv.Itoa(sliceValue.Len())
 		}
 
-		n, err := reader.Read(b)
-		if err != nil && err != io.EOF {
+		tok, err := t.Next()
+		if err != nil {
 			return sliceValue, err
 		}
-		if n == 0 {
-			return sliceValue, fmt.Errorf("%v: unexpected end of input", strings.Join(path, "."))
+		if tok.Kind == ArrayEnd {
+			return sliceValue, nil
 		}
 
-		if state == ReadingType {
-			if b[0] == ']' {
-				return sliceValue, nil
-			} else if b[0] == ',' && sliceValue.Len() > 0 {
-				// looking for a type, but found an unexpected comma, just try again
-				continue
-			}
-
-			valueType, err = parseValueType(b[0], append(path, index))
+		if arrayValue.Type().Elem() == rawValueType {
+			// raw elements keep their encoding, so they may be of any type
+			raw, err := readRawValueV1(t, tok)
 			if err != nil {
 				return sliceValue, err
 			}
 
-			if origValueType < 0 {
-				origValueType = valueType
-			} else if origValueType != valueType {
-				return sliceValue, fmt.Errorf("%v: arrays in structs must contain only elements of the same type", strings.Join(path, "."))
-			}
-
-			switch valueType {
-			case Map:
-				var elemValue reflect.Value
-				k := arrayValue.Type().Elem().Kind()
-				switch k {
-				case reflect.Struct:
-					structPtrValue := reflect.New(arrayValue.Type().Elem())
-					err := parseStructV1(reader, structPtrValue.Elem(), append(path, index))
-					if err != nil {
-						return sliceValue, err
-					}
-
-					elemValue = structPtrValue.Elem()
-				case reflect.Map:
-					result, err := parseMapV1(reader, append(path, index))
-					if err != nil {
-						return sliceValue, err
-					}
-					elemValue = reflect.ValueOf(result)
-				default:
-					return sliceValue, fmt.Errorf(
-						"%v: a struct or map cannot be inserted into slice of type %v",
-						strings.Join(path, "."),
-						arrayValue.Type(),
-					)
-				}
+			if !sliceValue.IsValid() {
+				sliceValue = reflect.MakeSlice(arrayValue.Type(), 0, 1)
+			}
+			sliceValue = reflect.Append(sliceValue, reflect.ValueOf(raw))
+			continue
+		}
 
-				if !sliceValue.IsValid() {
-					sliceValue = reflect.MakeSlice(arrayValue.Type(), 0, 1)
-				}
-				sliceValue = reflect.Append(sliceValue, elemValue)
-
-				// set the state to parse another element
-				state = ReadingType
-				value = strings.Builder{}
-			case Array:
-				subValue := reflect.MakeSlice(arrayValue.Type().Elem(), 0, 0)
-				subSliceValue, err := parseTypedArrayV1(reader, subValue, append(path, index))
+		if origValueType < 0 {
+			origValueType = tok.Type
+		} else if origValueType != tok.Type {
+			return sliceValue, fmt.Errorf("%v: arrays in structs must contain only elements of the same type", strings.Join(path, "."))
+		}
+
+		switch tok.Kind {
+		case MapStart:
+			var elemValue reflect.Value
+			k := arrayValue.Type().Elem().Kind()
+			switch k {
+			case reflect.Struct:
+				structPtrValue := reflect.New(arrayValue.Type().Elem())
+				err := decodeStructV1(t, structPtrValue.Elem(), append(path, index))
 				if err != nil {
 					return sliceValue, err
 				}
 
-				if !sliceValue.IsValid() {
-					sliceValue = reflect.MakeSlice(reflect.SliceOf(subSliceValue.Type()), 0, 1)
-				}
-				sliceValue = reflect.Append(sliceValue, subSliceValue)
-
-				// set the state to parse another element
-				state = ReadingType
-				value = strings.Builder{}
-			default:
-				state = ReadingValue
-			}
-		} else if state == ReadingValue {
-			if escaped {
-				escaped = false
-				value.Write(b)
-			} else if b[0] == ',' || b[0] == ']' {
-				nextByte := b[0]
-				r, err := parseValue(value.String(), valueType, append(path, index))
+				elemValue = structPtrValue.Elem()
+			case reflect.Map:
+				elemValue, err = decodeTypedMapV1(t, arrayValue.Type().Elem(), append(path, index))
 				if err != nil {
 					return sliceValue, err
 				}
+			default:
+				return sliceValue, fmt.Errorf(
+					"%v: a struct or map cannot be inserted into slice of type %v",
+					strings.Join(path, "."),
+					arrayValue.Type(),
+				)
+			}
 
-				if !sliceValue.IsValid() {
-					sliceValue = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(r)), 0, 1)
-				}
-				sliceValue = reflect.Append(sliceValue, reflect.ValueOf(r))
-
-				if nextByte == ',' {
-					// set the state to parse another element
-					state = ReadingType
-					value = strings.Builder{}
-				} else {
-					return sliceValue, nil
-				}
-			} else if b[0] == '\\' {
-				escaped = true
-			} else {
-				value.Write(b)
+			if !sliceValue.IsValid() {
+				sliceValue = reflect.MakeSlice(arrayValue.Type(), 0, 1)
 			}
-		} else {
-			return sliceValue, errors.New("invalid state")
+			sliceValue = reflect.Append(sliceValue, elemValue)
+		case ArrayStart:
+			subValue := reflect.MakeSlice(arrayValue.Type().Elem(), 0, 0)
+			subSliceValue, err := decodeTypedArrayV1(t, subValue, append(path, index))
+			if err != nil {
+				return sliceValue, err
+			}
+			if !subSliceValue.IsValid() {
+				subSliceValue = subValue
+			}
+
+			if !sliceValue.IsValid() {
+				sliceValue = reflect.MakeSlice(reflect.SliceOf(subSliceValue.Type()), 0, 1)
+			}
+			sliceValue = reflect.Append(sliceValue, subSliceValue)
+		default:
+			r, err := parseValue(tok.Value, tok.Type, append(path, index))
+			if err != nil {
+				return sliceValue, err
+			}
+			if r == nil {
+				return sliceValue, fmt.Errorf("%v: a null cannot be inserted into slice of type %v", strings.Join(path, "."), arrayValue.Type())
+			}
+
+			if !sliceValue.IsValid() {
+				sliceValue = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(r)), 0, 1)
+			}
+			sliceValue = reflect.Append(sliceValue, reflect.ValueOf(r))
 		}
 	}
 }
 
-func parseArrayV1(reader io.Reader, path []string) ([]any, error) {
+func decodeArrayV1(t *Tokenizer, path []string, ordered bool) ([]any, error) {
 	result := []any{}
-	b := make([]byte, 1)
-
-	var valueType ValueType
-	state := ReadingType
-	value := strings.Builder{}
-	escaped := false
-
 	for {
-		index := len(result)
-		n, err := reader.Read(b)
-		if err != nil && err != io.EOF {
+		tok, err := t.Next()
+		if err != nil {
 			return result, err
 		}
-		if n == 0 {
-			return result, fmt.Errorf("%v: unexpected end of input", strings.Join(path, "."))
+		if tok.Kind == ArrayEnd {
+			return result, nil
 		}
 
-		if state == ReadingType {
-			if b[0] == ']' {
-				return result, nil
-			} else if b[0] == ',' && len(result) > 0 {
-				// looking for a type, but found an unexpected comma, just try again
-				continue
-			}
-
-			valueType, err = parseValueType(b[0], append(path, strconv.Itoa(index)))
-			if err != nil {
-				return result, err
-			}
-
-			switch valueType {
-			case Map:
-				r, err := parseMapV1(reader, append(path, strconv.Itoa(index)))
-				if err != nil {
-					return result, err
-				}
-
-				result = append(result, r)
-
-				// set the state to parse another element
-				state = ReadingType
-				value = strings.Builder{}
-			case Array:
-				r, err := parseArrayV1(reader, append(path, strconv.Itoa(index)))
-				if err != nil {
-					return result, err
-				}
-
-				result = append(result, r)
-
-				// set the state to parse another element
-				state = ReadingType
-				value = strings.Builder{}
-			default:
-				state = ReadingValue
-			}
-		} else if state == ReadingValue {
-			if escaped {
-				escaped = false
-				value.Write(b)
-			} else if b[0] == ',' {
-				r, err := parseValue(value.String(), valueType, append(path, strconv.Itoa(index)))
-				if err != nil {
-					return result, err
-				}
-
-				result = append(result, r)
-
-				// set the state to parse another element
-				state = ReadingType
-				value = strings.Builder{}
-			} else if b[0] == ']' {
-				r, err := parseValue(value.String(), valueType, append(path, strconv.Itoa(index)))
-				if err != nil {
-					return result, err
-				}
-
-				result = append(result, r)
-
-				return result, nil
-			} else if b[0] == '\\' {
-				escaped = true
-			} else {
-				value.Write(b)
-			}
-		} else {
-			return result, errors.New("invalid state")
+		r, err := decodeValueV1(t, tok, append(path, strconv.Itoa(len(result))), ordered)
+		if err != nil {
+			return result, err
 		}
+
+		result = append(result, r)
 	}
 }
 
@@ -534,6 +540,8 @@ func parseValueType(b byte, path []string) (valueType ValueType, err error) {
 		valueType = Map
 	case '[':
 		valueType = Array
+	case 'n':
+		valueType = Null
 	default:
 		err = fmt.Errorf("%v: invalid type marker '%v'", strings.Join(path, "."), string(b))
 	}
@@ -541,6 +549,16 @@ func parseValueType(b byte, path []string) (valueType ValueType, err error) {
 	return
 }
 
+// typeMarkers maps each scalar value type to the byte that marks it in a document.
+var typeMarkers = map[ValueType]byte{
+	Bool:    'b',
+	Int:     'i',
+	Float32: 'f',
+	Float64: 'd',
+	String:  '"',
+	Null:    'n',
+}
+
 func parseValue(s string, valueType ValueType, path []string) (any, error) {
 	switch valueType {
 	case Bool:
@@ -572,6 +590,11 @@ func parseValue(s string, valueType ValueType, path []string) (any, error) {
 		return v, nil
 	case String:
 		return s, nil
+	case Null:
+		if s != "" {
+			return nil, fmt.Errorf("%v: invalid null '%v'", strings.Join(path, "."), s)
+		}
+		return nil, nil
 	}
 
 	return nil, fmt.Errorf("invalid type '%v' for '%v'", valueType, s)
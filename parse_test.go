@@ -2,6 +2,8 @@ package cereal
 
 import (
 	"bytes"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -41,6 +43,32 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParse_Stream(t *testing.T) {
+	// io.MultiReader does not implement io.ByteReader, so nothing may be read past a document
+	reader := io.MultiReader(strings.NewReader("1{a:i1}1{b:i2}"))
+
+	first, err := Parse(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first["a"] != 1 {
+		t.Error("expected the first document to be {a:1} but got", first)
+	}
+
+	second, err := Parse(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second) != 1 || second["b"] != 2 {
+		t.Error("expected the second document to be {b:2} but got", second)
+	}
+
+	_, err = Parse(reader)
+	if err == nil || err.Error() != "expected a version in the first byte" {
+		t.Error("expected the end of the stream but got", err)
+	}
+}
+
 func TestParseBytesV1_EmptyInput(t *testing.T) {
 	_, err := parseV1(bytes.NewBuffer([]byte{}))
 	if err == nil {
@@ -141,6 +169,17 @@ func TestParseMapV1_EscapedKeyEscape(t *testing.T) {
 	}
 }
 
+func TestParseMapV1_EscapedNewline(t *testing.T) {
+	result, err := parseMapV1(bytes.NewBufferString("a\\n:\"x\\ny\\rz\\q}"), []string{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if result["a\n"] != "x\ny\rzq" {
+		t.Errorf("expected 'a\\n' to be %q but got %q", "x\ny\rzq", result["a\n"])
+	}
+}
+
 func TestParseMapV1_SingleBool(t *testing.T) {
 	result, err := parseMapV1(bytes.NewBuffer([]byte{'b', ':', 'b', '1', '}'}), []string{})
 	if err != nil {
@@ -183,6 +222,32 @@ func TestParseMapV1_String(t *testing.T) {
 	}
 }
 
+func TestParseMapV1_Null(t *testing.T) {
+	result, err := parseMapV1(bytes.NewBuffer([]byte("a:n,b:[n]}")), []string{})
+	if err != nil {
+		t.Error(err)
+	}
+
+	value, ok := result["a"]
+	if !ok || value != nil {
+		t.Error("expected 'a' to be nil but got", value)
+	}
+	if b := result["b"].([]any); len(b) != 1 || b[0] != nil {
+		t.Error("expected 'b' to hold nil but got", b)
+	}
+}
+
+func TestParseMapV1_InvalidNull(t *testing.T) {
+	_, err := parseMapV1(bytes.NewBuffer([]byte("a:nx}")), []string{"<root>"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	msg := err.Error()
+	if msg != "<root>.a: invalid null 'x'" {
+		t.Error("expected error to be \"<root>.a: invalid null 'x'\" but got", msg)
+	}
+}
+
 func TestParseMapV1_InvalidBool(t *testing.T) {
 	_, err := parseMapV1(bytes.NewBuffer([]byte{'b', ':', 'b', '2', '}'}), []string{"<root>"})
 	if err == nil {
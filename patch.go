@@ -0,0 +1,642 @@
+package cereal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PatchOperation is a single operation of a patch. Op is one of "add", "remove", "replace",
+// "move" or "test". Path and From are paths in the dotted form used by Query, without
+// wildcards or filters, where the index "-" refers to the end of an array.
+type PatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value any
+}
+
+// ParsePatch reads a patch document from the provided io.Reader. A patch document holds an
+// array of operations under the key "operations", where each operation is a map with the keys
+// "op", "path", "from" and "value":
+//
+//	1{operations:[{op:"replace,path:"port,value:i8080},{op:"remove,path:"debug}]}
+//
+// The value of each operation is returned as a RawValue.
+func ParsePatch(reader io.Reader) ([]PatchOperation, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := ParseOrdered(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := doc.Get("operations")
+	if !ok {
+		return nil, errors.New("<root>: key 'operations' not found")
+	}
+	elements, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("<root>.operations: expected an array")
+	}
+
+	ops := []PatchOperation{}
+	for i, element := range elements {
+		path := []string{"<root>", "operations", strconv.Itoa(i)}
+
+		m, ok := element.(*OrderedMap)
+		if !ok {
+			return nil, fmt.Errorf("%v: expected a map", strings.Join(path, "."))
+		}
+
+		op := PatchOperation{}
+		op.Op, err = patchString(m, "op", path, true)
+		if err != nil {
+			return nil, err
+		}
+		op.Path, err = patchString(m, "path", path, true)
+		if err != nil {
+			return nil, err
+		}
+		op.From, err = patchString(m, "from", path, op.Op == "move")
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			raw, _, err := GetRaw(data, "operations", strconv.Itoa(i), "value")
+			if err != nil {
+				return nil, err
+			}
+			op.Value = RawValue(slices.Clone(raw))
+		case "remove", "move":
+		default:
+			return nil, fmt.Errorf("%v.op: unknown operation '%v'", strings.Join(path, "."), op.Op)
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+func patchString(m *OrderedMap, key string, path []string, required bool) (string, error) {
+	value, ok := m.Get(key)
+	if !ok {
+		if required {
+			return "", fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), key)
+		}
+		return "", nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%v.%v: expected a string", strings.Join(path, "."), key)
+	}
+
+	return s, nil
+}
+
+// ApplyPatch applies the operations of a patch document, as read by ParsePatch, to a
+// serialized document and returns the result. The document is edited in place as with SetRaw
+// and DeleteRaw, so its layout and comments are kept. Operations are applied in order and
+// the first one that fails stops the patch.
+func ApplyPatch(doc []byte, patch []byte) ([]byte, error) {
+	ops, err := ParsePatch(bytes.NewReader(patch))
+	if err != nil {
+		return nil, err
+	}
+
+	err = Validate(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		doc, err = applyRawOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %v: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyRawOperation(doc []byte, op PatchOperation) ([]byte, error) {
+	segments, err := patchPath(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+		value, err = encodePatchValue(op.Value, append([]string{"<root>"}, segments...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return insertRawV1(doc, value, segments)
+	case "remove":
+		return DeleteRaw(doc, segments...)
+	case "replace":
+		_, _, err = GetRaw(doc, segments...)
+		if err != nil {
+			return nil, err
+		}
+		return SetRaw(doc, value, segments...)
+	case "move":
+		from, err := movePath(op.From, segments)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, _, err := GetRaw(doc, from...)
+		if err != nil {
+			return nil, err
+		}
+		raw = slices.Clone(raw)
+
+		doc, err = DeleteRaw(doc, from...)
+		if err != nil {
+			return nil, err
+		}
+		return insertRawV1(doc, raw, segments)
+	case "test":
+		raw, _, err := GetRaw(doc, segments...)
+		if err != nil {
+			return nil, err
+		}
+
+		actual, err := decodeRawValue(raw)
+		if err != nil {
+			return nil, err
+		}
+		expected, err := decodeRawValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return doc, testPatchValue(actual, expected, segments)
+	}
+
+	return nil, fmt.Errorf("unknown operation '%v'", op.Op)
+}
+
+// encodePatchValue returns the encoded form of the value of an operation. A RawValue is
+// returned as it is, since it is checked where it is placed.
+func encodePatchValue(value any, path []string) ([]byte, error) {
+	if raw, ok := value.(RawValue); ok {
+		return raw, nil
+	}
+
+	buf := bytes.Buffer{}
+	err := writeValue(reflect.ValueOf(value), &buf, path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeRawValue decodes a single encoded value.
+func decodeRawValue(raw []byte) (any, error) {
+	doc := bytes.NewBufferString("1{v:")
+	doc.Write(raw)
+	doc.WriteByte('}')
+
+	m, err := ParseOrdered(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := m.Get("v")
+	return value, nil
+}
+
+// patchPath splits a path of an operation into its keys and indices.
+func patchPath(expr string) ([]string, error) {
+	steps, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := []string{}
+	for _, step := range steps {
+		if step.kind != stepKey && step.kind != stepIndex {
+			return nil, fmt.Errorf("invalid path '%v': only keys and indices are allowed", expr)
+		}
+		segments = append(segments, step.key)
+	}
+
+	return segments, nil
+}
+
+// movePath returns the segments of the path a value is moved from, which may not contain the
+// path it is moved to.
+func movePath(expr string, to []string) ([]string, error) {
+	from, err := patchPath(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(from) < len(to) && slices.Equal(from, to[:len(from)]) {
+		return nil, fmt.Errorf("%v: a value cannot be moved into itself", strings.Join(append([]string{"<root>"}, from...), "."))
+	}
+
+	return from, nil
+}
+
+func testPatchValue(actual, expected any, segments []string) error {
+	if len(Diff(actual, expected)) > 0 {
+		return fmt.Errorf("%v: value does not match", strings.Join(append([]string{"<root>"}, segments...), "."))
+	}
+
+	return nil
+}
+
+// ApplyPatchValue applies the operations to a parsed document, such as the result of Parse or
+// ParseOrdered, and returns the result. The document itself is not modified. Values of the
+// operations that are a RawValue are decoded before they are used.
+func ApplyPatchValue(doc any, ops []PatchOperation) (any, error) {
+	doc = copyValue(doc)
+
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %v: %w", i, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func applyOperation(doc any, op PatchOperation) (any, error) {
+	segments, err := patchPath(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := op.Value
+	if raw, ok := value.(RawValue); ok {
+		value, err = decodeRawValue(raw)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		value = copyValue(value)
+	}
+
+	root := []string{"<root>"}
+
+	switch op.Op {
+	case "add", "replace":
+		if len(segments) == 0 {
+			if valueType, _ := valueTypeOf(value); valueType != Map {
+				return nil, errors.New("<root>: the root must be a map")
+			}
+			return value, nil
+		}
+
+		insert := op.Op == "add"
+		return updateValue(doc, segments, root, func(container any, key string, path []string) (any, error) {
+			return setChild(container, key, value, path, insert)
+		})
+	case "remove":
+		if len(segments) == 0 {
+			return nil, errors.New("<root>: the root cannot be deleted")
+		}
+
+		return updateValue(doc, segments, root, func(container any, key string, path []string) (any, error) {
+			result, _, err := removeChild(container, key, path)
+			return result, err
+		})
+	case "move":
+		from, err := movePath(op.From, segments)
+		if err != nil {
+			return nil, err
+		}
+		if len(from) == 0 {
+			return nil, errors.New("<root>: the root cannot be deleted")
+		}
+
+		var moved any
+		doc, err = updateValue(doc, from, root, func(container any, key string, path []string) (any, error) {
+			result, removed, err := removeChild(container, key, path)
+			moved = removed
+			return result, err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return applyOperation(doc, PatchOperation{Op: "add", Path: op.Path, Value: moved})
+	case "test":
+		actual, err := valueAt(doc, segments, root)
+		if err != nil {
+			return nil, err
+		}
+
+		return doc, testPatchValue(actual, value, segments)
+	}
+
+	return nil, fmt.Errorf("unknown operation '%v'", op.Op)
+}
+
+// updateValue replaces the container holding the value at the given path with the result of
+// update, and returns the updated document.
+func updateValue(doc any, segments []string, path []string, update func(container any, key string, path []string) (any, error)) (any, error) {
+	if len(segments) == 1 {
+		return update(doc, segments[0], path)
+	}
+
+	child, err := childValue(doc, segments[0], path)
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = updateValue(child, segments[1:], appendPath(path, segments[0]), update)
+	if err != nil {
+		return nil, err
+	}
+
+	return setChild(doc, segments[0], child, path, false)
+}
+
+func valueAt(doc any, segments []string, path []string) (any, error) {
+	for _, segment := range segments {
+		var err error
+		doc, err = childValue(doc, segment, path)
+		if err != nil {
+			return nil, err
+		}
+		path = appendPath(path, segment)
+	}
+
+	return doc, nil
+}
+
+func childValue(container any, key string, path []string) (any, error) {
+	switch c := container.(type) {
+	case map[string]any:
+		value, ok := c[key]
+		if !ok {
+			return nil, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), key)
+		}
+		return value, nil
+	case *OrderedMap:
+		value, ok := c.Get(key)
+		if !ok {
+			return nil, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), key)
+		}
+		return value, nil
+	case []any:
+		index, err := arrayIndex(key, len(c), false, path)
+		if err != nil {
+			return nil, err
+		}
+		return c[index], nil
+	}
+
+	return nil, unsupportedContainer(container, key, path)
+}
+
+// setChild sets the value of a key in a map or of an element in an array. Unless insert is
+// set, the key or element must already exist. Inserting into an array moves the following
+// elements along.
+func setChild(container any, key string, value any, path []string, insert bool) (any, error) {
+	switch c := container.(type) {
+	case map[string]any:
+		if _, ok := c[key]; !ok && !insert {
+			return nil, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), key)
+		}
+		c[key] = value
+		return c, nil
+	case *OrderedMap:
+		if _, ok := c.Get(key); !ok && !insert {
+			return nil, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), key)
+		}
+		c.Set(key, value)
+		return c, nil
+	case []any:
+		index, err := arrayIndex(key, len(c), insert, path)
+		if err != nil {
+			return nil, err
+		}
+		if insert {
+			return slices.Insert(c, index, value), nil
+		}
+		c[index] = value
+		return c, nil
+	}
+
+	return nil, unsupportedContainer(container, key, path)
+}
+
+// removeChild removes a key from a map or an element from an array, and returns the updated
+// container along with the removed value.
+func removeChild(container any, key string, path []string) (any, any, error) {
+	value, err := childValue(container, key, path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch c := container.(type) {
+	case map[string]any:
+		delete(c, key)
+		return c, value, nil
+	case *OrderedMap:
+		c.Delete(key)
+		return c, value, nil
+	case []any:
+		index, _ := strconv.Atoi(key)
+		return slices.Delete(c, index, index+1), value, nil
+	}
+
+	return nil, nil, unsupportedContainer(container, key, path)
+}
+
+// arrayIndex returns the index of an element of an array with the given length. When end is
+// set, the index may also be the length of the array or "-", which refers to the end.
+func arrayIndex(key string, length int, end bool, path []string) (int, error) {
+	if end && key == "-" {
+		return length, nil
+	}
+
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%v: invalid array index '%v'", strings.Join(path, "."), key)
+	}
+	if index > length || (index == length && !end) {
+		return 0, fmt.Errorf("%v: index %v out of range", strings.Join(path, "."), key)
+	}
+
+	return index, nil
+}
+
+func unsupportedContainer(container any, key string, path []string) error {
+	if valueType, _ := valueTypeOf(container); valueType == Map || valueType == Array {
+		return fmt.Errorf("%v: cannot modify a value of type %T", strings.Join(path, "."), container)
+	}
+
+	return fmt.Errorf("%v: cannot select '%v' from a scalar value", strings.Join(path, "."), key)
+}
+
+// copyValue returns a deep copy of the maps and arrays of a parsed document.
+func copyValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, child := range v {
+			result[key] = copyValue(child)
+		}
+		return result
+	case *OrderedMap:
+		result := NewOrderedMap()
+		for key, child := range v.All() {
+			result.Set(key, copyValue(child))
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, child := range v {
+			result[i] = copyValue(child)
+		}
+		return result
+	}
+
+	return value
+}
+
+// MergePatch overlays a patch document on a serialized document in the manner of RFC 7386 and
+// returns the result. Each entry of the patch replaces the entry with the same key in the
+// document, except that maps are merged recursively and a null removes the entry. The
+// document is edited in place as with SetRaw and DeleteRaw, so its layout and comments are
+// kept where they are not replaced.
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	err := Validate(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := ParseTree(bytes.NewReader(patch))
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeRawV1(doc, patch, tree, []string{})
+}
+
+func mergeRawV1(doc []byte, patch []byte, node *Node, path []string) ([]byte, error) {
+	for _, child := range node.Children {
+		segments := appendPath(path, child.Key)
+		_, valueType, err := GetRaw(doc, segments...)
+		found := err == nil
+
+		switch {
+		case child.Type == Null:
+			if found {
+				doc, err = DeleteRaw(doc, segments...)
+			}
+		case child.Type == Map && found && valueType == Map:
+			doc, err = mergeRawV1(doc, patch, child, segments)
+		default:
+			doc, err = SetRaw(doc, mergedRaw(patch, child), segments...)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return doc, nil
+}
+
+// mergedRaw returns the encoded value of a patch node with the null entries of its maps
+// removed.
+func mergedRaw(patch []byte, node *Node) []byte {
+	if !hasNullEntry(node) {
+		return patch[node.Start:node.End]
+	}
+
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for _, child := range node.Children {
+		if child.Type == Null {
+			continue
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(child.RawKey)
+		buf.WriteByte(':')
+		buf.Write(mergedRaw(patch, child))
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+func hasNullEntry(node *Node) bool {
+	if node.Type != Map {
+		return false
+	}
+
+	for _, child := range node.Children {
+		if child.Type == Null || hasNullEntry(child) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MergePatchValue overlays a parsed patch on a parsed document in the same way as MergePatch
+// and returns the result. A nil value in the patch removes the entry. Neither argument is
+// modified.
+func MergePatchValue(doc any, patch any) any {
+	if !isMergeMap(patch) {
+		return copyValue(patch)
+	}
+
+	var result any
+	if isMergeMap(doc) {
+		result = copyValue(doc)
+	} else if _, ok := patch.(*OrderedMap); ok {
+		result = NewOrderedMap()
+	} else {
+		result = map[string]any{}
+	}
+
+	for _, c := range queryChildren(patch) {
+		if c.value == nil {
+			removeChild(result, c.key, nil)
+			continue
+		}
+
+		current, _ := childValue(result, c.key, nil)
+		setChild(result, c.key, MergePatchValue(current, c.value), nil, true)
+	}
+
+	return result
+}
+
+func isMergeMap(value any) bool {
+	switch value.(type) {
+	case map[string]any, *OrderedMap:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	ops, err := ParsePatch(strings.NewReader("1{operations:[{op:\"add,path:\"a.-,value:{b:i1}},{op:\"move,from:\"x,path:\"y}]}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ops) != 2 {
+		t.Fatal("expected 2 operations but got", ops)
+	}
+	if ops[0].Op != "add" || ops[0].Path != "a.-" || string(ops[0].Value.(RawValue)) != "{b:i1}" {
+		t.Errorf("expected an add operation but got %+v", ops[0])
+	}
+	if ops[1].Op != "move" || ops[1].From != "x" || ops[1].Path != "y" || ops[1].Value != nil {
+		t.Errorf("expected a move operation but got %+v", ops[1])
+	}
+}
+
+func TestParsePatch_Errors(t *testing.T) {
+	tests := map[string]string{
+		"1{}":                                  "<root>: key 'operations' not found",
+		"1{operations:i1}":                     "<root>.operations: expected an array",
+		"1{operations:[i1]}":                   "<root>.operations.0: expected a map",
+		"1{operations:[{path:\"a}]}":           "<root>.operations.0: key 'op' not found",
+		"1{operations:[{op:\"add}]}":           "<root>.operations.0: key 'path' not found",
+		"1{operations:[{op:\"add,path:\"a}]}":  "<root>.operations.0: key 'value' not found",
+		"1{operations:[{op:\"move,path:\"a}]}": "<root>.operations.0: key 'from' not found",
+		"1{operations:[{op:\"copy,path:\"a}]}": "<root>.operations.0.op: unknown operation 'copy'",
+		"1{operations:[{op:i1,path:\"a}]}":     "<root>.operations.0.op: expected a string",
+	}
+
+	for input, expected := range tests {
+		_, err := ParsePatch(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected '%v' to fail", input)
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := "1{\n  # the port\n  port:i80,\n  hosts:[\"a,\"c],\n  debug:b1,\n  old:{x:i1},\n}"
+	patch := `1{operations:[
+		{op:"test,path:"port,value:i80},
+		{op:"replace,path:"port,value:i8080},
+		{op:"add,path:"hosts.1,value:"b},
+		{op:"add,path:"hosts.-,value:"d},
+		{op:"remove,path:"debug},
+		{op:"move,from:"old,path:"new},
+	]}`
+
+	result, err := ApplyPatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n  # the port\n  port:i8080,\n  hosts:[\"a,\"b,\"c,\"d],\nnew:{x:i1}}"
+	if string(result) != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, string(result))
+	}
+	if !Valid(result) {
+		t.Errorf("expected '%v' to be valid", string(result))
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	doc := []byte("1{a:{b:i1},c:[i1]}")
+
+	tests := map[string]string{
+		"{op:\"test,path:\"a.b,value:d1}":    "operation 0: <root>.a.b: value does not match",
+		"{op:\"replace,path:\"a.x,value:i1}": "operation 0: <root>.a: key 'x' not found",
+		"{op:\"remove,path:\"c.1}":           "operation 0: <root>.c: index 1 out of range",
+		"{op:\"add,path:\"c.2,value:i1}":     "operation 0: <root>.c: index 2 out of range",
+		"{op:\"add,path:\"c.1,value:ix}":     "<root>.operations.0.value: invalid int 'x'",
+		"{op:\"move,from:\"a,path:\"a.b.c}":  "operation 0: <root>.a: a value cannot be moved into itself",
+		"{op:\"remove,path:\"a.*}":           "operation 0: invalid path 'a.*': only keys and indices are allowed",
+		"{op:\"remove,path:\"<root>}":        "operation 0: <root>: the root cannot be deleted",
+	}
+
+	for op, expected := range tests {
+		_, err := ApplyPatch(doc, []byte("1{operations:["+op+"]}"))
+		if err == nil {
+			t.Errorf("expected '%v' to fail", op)
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestApplyPatchValue(t *testing.T) {
+	doc, err := Parse(strings.NewReader("1{port:i80,hosts:[\"a,\"c],debug:b1,old:{x:i1}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := []PatchOperation{
+		{Op: "test", Path: "port", Value: 80},
+		{Op: "replace", Path: "port", Value: RawValue("i8080")},
+		{Op: "add", Path: "hosts[1]", Value: "b"},
+		{Op: "add", Path: "hosts.-", Value: "d"},
+		{Op: "remove", Path: "debug"},
+		{Op: "move", From: "old", Path: "new"},
+	}
+
+	result, err := ApplyPatchValue(doc, ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := Parse(strings.NewReader("1{port:i8080,hosts:[\"a,\"b,\"c,\"d],new:{x:i1}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(result, expected); len(changes) > 0 {
+		t.Error("expected the patch to be applied but got", changes)
+	}
+
+	if _, ok := doc["debug"]; !ok {
+		t.Error("expected the document to be left unchanged")
+	}
+}
+
+func TestApplyPatchValue_TestFails(t *testing.T) {
+	doc := map[string]any{"a": 1}
+
+	_, err := ApplyPatchValue(doc, []PatchOperation{{Op: "test", Path: "a", Value: 1.0}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	msg := err.Error()
+	if msg != "operation 0: <root>.a: value does not match" {
+		t.Error("expected error to be 'operation 0: <root>.a: value does not match' but got", msg)
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := "1{\n  title:\"Hello,\n  author:{given:\"John,family:\"Doe},\n  tags:[\"example,\"sample],\n  content:\"text,\n}"
+	patch := "1{title:\"Hi,phone:\"555,author:{family:n},tags:[\"example],extra:{a:n,b:i1}}"
+
+	result, err := MergePatch([]byte(doc), []byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{\n  title:\"Hi,\n  author:{given:\"John},\n  tags:[\"example],\n  content:\"text,\nphone:\"555,extra:{b:i1}}"
+	if string(result) != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, string(result))
+	}
+}
+
+func TestMergePatchValue(t *testing.T) {
+	doc, err := Parse(strings.NewReader("1{a:\"b,c:{d:\"e,f:\"g},h:i1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := Parse(strings.NewReader("1{a:\"z,c:{f:n},h:{i:n,j:b1}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := MergePatchValue(doc, patch)
+
+	expected := map[string]any{
+		"a": "z",
+		"c": map[string]any{"d": "e"},
+		"h": map[string]any{"j": true},
+	}
+	if changes := Diff(result, expected); len(changes) > 0 {
+		t.Error("expected the patch to be merged but got", changes)
+	}
+
+	if doc["a"] != "b" {
+		t.Error("expected the document to be left unchanged")
+	}
+}
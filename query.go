@@ -0,0 +1,571 @@
+package cereal
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"iter"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepWildcard
+	stepDescend
+	stepFilter
+)
+
+// queryStep is a single step of a compiled query.
+type queryStep struct {
+	kind   stepKind
+	key    string
+	filter *queryFilter
+}
+
+// queryFilter selects the children of a map or array whose value at path satisfies the
+// comparison. A filter without an operator selects the children where the path exists.
+type queryFilter struct {
+	path    []string
+	op      string
+	operand any
+}
+
+// Query evaluates the query expression against a document, such as the result of Parse, and
+// returns the selected values. Maps, arrays and structs may be mixed freely in the document.
+//
+// A query is a dotted path in the same form used in error messages, optionally starting with
+// "<root>". Each segment selects a map entry by key or an array element by index, and an
+// index may also be written in brackets, as in "a.b[3].c". The segment "*" selects every child
+// of a map or array and "**" selects a value along with all of its descendants. A filter such
+// as "[?size > i3]" selects the children whose value at the relative path compares to the
+// literal, which is written with a type marker just as in a document. The path "@" refers to
+// the child itself and a filter without an operator selects the children where the path
+// exists. A '.', '[', ']' or '\' in a key must be escaped with '\'.
+//
+// Values are only equal if they have the same type, so "[?n == i1]" does not match d1. The
+// entries of a Go map are visited in key order.
+func Query(doc any, expr string) ([]any, error) {
+	steps, err := compileQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []any{}
+	evalQuery(doc, []int{0}, steps, &results)
+	return results, nil
+}
+
+// QueryReader evaluates the query expression against a document read from the provided
+// io.Reader and returns an iterator over the selected values. Values that cannot be selected
+// are skipped without being decoded, and a value is only decoded in full once it is selected or
+// a filter has to be applied to its children. Like Elements, it buffers the reader.
+func QueryReader(reader io.Reader, expr string) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		steps, err := compileQuery(expr)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		t := newBufferedTokenizer(reader)
+		tok, err := t.Next()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		_, err = streamQueryV1(t, tok, []int{0}, steps, yield)
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func compileQuery(expr string) ([]queryStep, error) {
+	var steps []queryStep
+
+	s := strings.TrimPrefix(expr, "<root>")
+	if len(s) < len(expr) && s != "" && s[0] != '.' && s[0] != '[' {
+		// the key only starts with "<root>"
+		s = expr
+	}
+	s = strings.TrimPrefix(s, ".")
+
+	for i := 0; i < len(s); {
+		if s[i] == '[' {
+			end := findClosingBracket(s, i+1)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid query '%v': missing ']'", expr)
+			}
+
+			step, err := compileBracket(s[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("invalid query '%v': %v", expr, err)
+			}
+			steps = append(steps, step)
+
+			i = end + 1
+			if i < len(s) && s[i] == '.' {
+				i++
+				if i == len(s) {
+					return nil, fmt.Errorf("invalid query '%v': empty segment", expr)
+				}
+			} else if i < len(s) && s[i] != '[' {
+				return nil, fmt.Errorf("invalid query '%v': expected '.' or '[' after ']'", expr)
+			}
+			continue
+		}
+
+		key, n, err := readQueryKey(s[i:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query '%v': %v", expr, err)
+		}
+		i += n
+
+		switch s[i-n : i] {
+		case "*":
+			steps = append(steps, queryStep{kind: stepWildcard})
+		case "**":
+			steps = append(steps, queryStep{kind: stepDescend})
+		default:
+			steps = append(steps, queryStep{kind: stepKey, key: key})
+		}
+
+		if i < len(s) && s[i] == '.' {
+			i++
+			if i == len(s) {
+				return nil, fmt.Errorf("invalid query '%v': empty segment", expr)
+			}
+		}
+	}
+
+	return steps, nil
+}
+
+// readQueryKey reads a key up to the next unescaped '.' or '[' and returns it along with the
+// number of bytes consumed.
+func readQueryKey(s string) (string, int, error) {
+	key := strings.Builder{}
+	escaped := false
+	i := 0
+	for ; i < len(s); i++ {
+		b := s[i]
+		if escaped {
+			escaped = false
+			key.WriteByte(b)
+		} else if b == '.' || b == '[' {
+			break
+		} else if b == ']' {
+			return "", i, fmt.Errorf("unexpected ']'")
+		} else if b == '\\' {
+			escaped = true
+		} else {
+			key.WriteByte(b)
+		}
+	}
+
+	if escaped {
+		return "", i, fmt.Errorf("unterminated escape")
+	}
+	if i == 0 {
+		return "", i, fmt.Errorf("empty segment")
+	}
+
+	return key.String(), i, nil
+}
+
+// findClosingBracket returns the position of the first unescaped ']' at or after start.
+func findClosingBracket(s string, start int) int {
+	escaped := false
+	for i := start; i < len(s); i++ {
+		if escaped {
+			escaped = false
+		} else if s[i] == '\\' {
+			escaped = true
+		} else if s[i] == ']' {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func compileBracket(s string) (queryStep, error) {
+	if s == "*" {
+		return queryStep{kind: stepWildcard}, nil
+	}
+
+	if strings.HasPrefix(s, "?") {
+		filter, err := compileFilter(s[1:])
+		if err != nil {
+			return queryStep{}, err
+		}
+		return queryStep{kind: stepFilter, filter: filter}, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return queryStep{}, fmt.Errorf("invalid index '%v'", s)
+	}
+
+	return queryStep{kind: stepIndex, key: strconv.Itoa(index)}, nil
+}
+
+func compileFilter(s string) (*queryFilter, error) {
+	s = strings.TrimLeft(s, " ")
+
+	// the path ends at the first unescaped space or operator
+	end := 0
+	for escaped := false; end < len(s); end++ {
+		if escaped {
+			escaped = false
+		} else if s[end] == '\\' {
+			escaped = true
+		} else if strings.IndexByte(" =!<>", s[end]) >= 0 {
+			break
+		}
+	}
+
+	filter := &queryFilter{}
+	if s[:end] != "@" {
+		path := strings.TrimPrefix(s[:end], "@.")
+		for path != "" {
+			key, n, err := readQueryKey(path)
+			if err != nil {
+				return nil, err
+			}
+			filter.path = append(filter.path, key)
+
+			path = strings.TrimPrefix(path[n:], ".")
+			if strings.HasPrefix(path, "[") {
+				return nil, fmt.Errorf("unexpected '[' in filter path")
+			}
+		}
+	}
+
+	s = strings.TrimLeft(s[end:], " ")
+	if s == "" {
+		return filter, nil
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if strings.HasPrefix(s, op) {
+			filter.op = op
+			break
+		}
+	}
+	if filter.op == "" {
+		return nil, fmt.Errorf("invalid operator in filter '%v'", s)
+	}
+
+	literal := strings.TrimLeft(s[len(filter.op):], " ")
+	if literal == "" {
+		return nil, fmt.Errorf("missing value in filter")
+	}
+
+	path := []string{"<filter>"}
+	valueType, err := parseValueType(literal[0], path)
+	if err != nil || valueType == Map || valueType == Array {
+		return nil, fmt.Errorf("invalid type marker '%v' in filter", string(literal[0]))
+	}
+
+	filter.operand, err = parseValue(unescapeQuery(literal[1:]), valueType, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func unescapeQuery(s string) string {
+	value := strings.Builder{}
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		if !escaped && s[i] == '\\' {
+			escaped = true
+			continue
+		}
+
+		escaped = false
+		value.WriteByte(s[i])
+	}
+
+	return value.String()
+}
+
+// closeStates adds the step that follows each "**" step to the set of states, since "**" may
+// match nothing at all.
+func closeStates(states []int, steps []queryStep) []int {
+	for i := 0; i < len(states); i++ {
+		state := states[i]
+		if state < len(steps) && steps[state].kind == stepDescend && !slices.Contains(states, state+1) {
+			states = append(states, state+1)
+		}
+	}
+
+	slices.Sort(states)
+	return slices.Compact(states)
+}
+
+// childStates returns the states of a child of a map or array given the states of its parent.
+// The key is the key of the child in a map or its index in an array. Filter states are not
+// handled here since they need the value of the child.
+func childStates(states []int, steps []queryStep, key string, isArray bool) []int {
+	var next []int
+	for _, state := range states {
+		if state == len(steps) {
+			continue
+		}
+
+		step := steps[state]
+		switch step.kind {
+		case stepKey:
+			if step.key == key {
+				next = append(next, state+1)
+			}
+		case stepIndex:
+			if isArray && step.key == key {
+				next = append(next, state+1)
+			}
+		case stepWildcard:
+			next = append(next, state+1)
+		case stepDescend:
+			next = append(next, state)
+		}
+	}
+
+	return next
+}
+
+// evalQuery appends to results the values selected by the query in the given states, starting
+// from value.
+func evalQuery(value any, states []int, steps []queryStep, results *[]any) {
+	states = closeStates(states, steps)
+	if slices.Contains(states, len(steps)) {
+		*results = append(*results, value)
+	}
+
+	for _, c := range queryChildren(value) {
+		next := childStates(states, steps, c.key, c.isArray)
+		for _, state := range states {
+			if state < len(steps) && steps[state].kind == stepFilter && steps[state].filter.matches(c.value) {
+				next = append(next, state+1)
+			}
+		}
+
+		if len(next) > 0 {
+			evalQuery(c.value, next, steps, results)
+		}
+	}
+}
+
+type queryChild struct {
+	key     string
+	value   any
+	isArray bool
+}
+
+// queryChildren returns the entries of a map or the elements of an array or slice, or the
+// fields of a struct. Any other value has no children.
+func queryChildren(value any) []queryChild {
+	var children []queryChild
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			children = append(children, queryChild{key: key, value: v[key]})
+		}
+		return children
+	case *OrderedMap:
+		for key, value := range v.All() {
+			children = append(children, queryChild{key: key, value: value})
+		}
+		return children
+	case []any:
+		for i, value := range v {
+			children = append(children, queryChild{key: strconv.Itoa(i), value: value, isArray: true})
+		}
+		return children
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+
+		keys := rv.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return cmp.Compare(a.String(), b.String())
+		})
+		for _, key := range keys {
+			children = append(children, queryChild{key: key.String(), value: rv.MapIndex(key).Interface()})
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			children = append(children, queryChild{key: strconv.Itoa(i), value: rv.Index(i).Interface(), isArray: true})
+		}
+	case reflect.Struct:
+		for _, f := range reflect.VisibleFields(rv.Type()) {
+			key, ok := structFieldKey(f)
+			if ok && f.IsExported() && !f.Anonymous {
+				children = append(children, queryChild{key: key, value: rv.FieldByIndex(f.Index).Interface()})
+			}
+		}
+	}
+
+	return children
+}
+
+// matches reports whether the filter selects the value.
+func (f *queryFilter) matches(value any) bool {
+	for _, key := range f.path {
+		found := false
+		for _, c := range queryChildren(value) {
+			if c.key == key {
+				value = c.value
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if f.op == "" {
+		return true
+	}
+
+	if reflect.TypeOf(value) != reflect.TypeOf(f.operand) {
+		return f.op == "!="
+	}
+
+	var result int
+	switch v := value.(type) {
+	case nil:
+		if f.op != "==" && f.op != "!=" {
+			return false
+		}
+	case bool:
+		if f.op != "==" && f.op != "!=" {
+			return false
+		}
+		result = cmp.Compare(boolToInt(v), boolToInt(f.operand.(bool)))
+	case int:
+		result = cmp.Compare(v, f.operand.(int))
+	case float32:
+		result = cmp.Compare(v, f.operand.(float32))
+	case float64:
+		result = cmp.Compare(v, f.operand.(float64))
+	case string:
+		result = cmp.Compare(v, f.operand.(string))
+	default:
+		return false
+	}
+
+	switch f.op {
+	case "==":
+		return result == 0
+	case "!=":
+		return result != 0
+	case "<":
+		return result < 0
+	case "<=":
+		return result <= 0
+	case ">":
+		return result > 0
+	default:
+		return result >= 0
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// streamQueryV1 evaluates the query against the value started by tok. It reports whether the
+// consumer of the results wants more of them.
+func streamQueryV1(t *Tokenizer, tok Token, states []int, steps []queryStep, yield func(any, error) bool) (bool, error) {
+	states = closeStates(states, steps)
+
+	decode := slices.Contains(states, len(steps))
+	for _, state := range states {
+		if state < len(steps) && steps[state].kind == stepFilter {
+			decode = true
+		}
+	}
+
+	if decode {
+		value, err := decodeValueV1(t, tok, t.Path(), false)
+		if err != nil {
+			return false, err
+		}
+
+		results := []any{}
+		evalQuery(value, states, steps, &results)
+		for _, result := range results {
+			if !yield(result, nil) {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+
+	if tok.Kind == Scalar {
+		return true, nil
+	}
+
+	isArray := tok.Kind == ArrayStart
+	for index := 0; ; index++ {
+		tok, err := t.Next()
+		if err != nil {
+			return false, err
+		}
+		if tok.Kind == MapEnd || tok.Kind == ArrayEnd {
+			return true, nil
+		}
+
+		key := strconv.Itoa(index)
+		if tok.Kind == Key {
+			key = tok.Value
+			tok, err = t.Next()
+			if err != nil {
+				return false, err
+			}
+		}
+
+		next := childStates(states, steps, key, isArray)
+		if len(next) == 0 {
+			err = skipValueV1(t, tok)
+			if err != nil {
+				return false, err
+			}
+			continue
+		}
+
+		more, err := streamQueryV1(t, tok, next, steps, yield)
+		if err != nil || !more {
+			return more, err
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package cereal
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const queryDocument = "1{a:{b:[{c:i1},{c:i2},{c:i3},{c:i4,d:\"x}]},items:[{name:\"pen,price:d1.5,tags:[\"red]},{name:\"ink,price:d12,tags:[]},{name:\"pad,price:i3}],e\\.f:b1}"
+
+func queryAll(t *testing.T, expr string) ([]any, []any) {
+	t.Helper()
+
+	doc, err := Parse(strings.NewReader(queryDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Query(doc, expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamed := []any{}
+	for value, err := range QueryReader(strings.NewReader(queryDocument), expr) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed = append(streamed, value)
+	}
+
+	return results, streamed
+}
+
+func TestQuery(t *testing.T) {
+	tests := map[string][]any{
+		"a.b[3].c":                     {4},
+		"a.b.3.c":                      {4},
+		"<root>.a.b.0.c":               {1},
+		"a.b[*].c":                     {1, 2, 3, 4},
+		"a.b.*.d":                      {"x"},
+		"**.c":                         {1, 2, 3, 4},
+		"items[?price > d2].name":      {"ink"},
+		"items[?price == i3].name":     {"pad"},
+		"items[?price != d1.5].name":   {"ink", "pad"},
+		"items[?tags.0 == \"red].name": {"pen"},
+		"items[?tags].name":            {"pen", "ink"},
+		"a.b[?c >= i2][?@ < i4]":       {2, 3},
+		"e\\.f":                        {true},
+		"missing.key":                  {},
+		"a.b[9]":                       {},
+	}
+
+	for expr, expected := range tests {
+		results, streamed := queryAll(t, expr)
+		if !reflect.DeepEqual(results, expected) {
+			t.Errorf("expected '%v' to select %v but got %v", expr, expected, results)
+		}
+		if !reflect.DeepEqual(streamed, expected) {
+			t.Errorf("expected '%v' to stream %v but got %v", expr, expected, streamed)
+		}
+	}
+}
+
+func TestQuery_Root(t *testing.T) {
+	doc := map[string]any{"a": 1}
+
+	results, err := Query(doc, "<root>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 1 || !reflect.DeepEqual(results[0], doc) {
+		t.Error("expected the root to be selected but got", results)
+	}
+}
+
+func TestQuery_Struct(t *testing.T) {
+	type Item struct {
+		Name  string
+		Price float64
+	}
+	doc := struct {
+		Items []Item
+	}{
+		Items: []Item{{Name: "pen", Price: 1.5}, {Name: "ink", Price: 12}},
+	}
+
+	results, err := Query(doc, "Items[?Price < d5].Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(results, []any{"pen"}) {
+		t.Error("expected [pen] but got", results)
+	}
+}
+
+func TestQuery_OrderedMap(t *testing.T) {
+	doc, err := ParseOrdered(strings.NewReader("1{z:i1,a:i2,m:i3}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Query(doc, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(results, []any{1, 2, 3}) {
+		t.Error("expected [1 2 3] but got", results)
+	}
+}
+
+func TestQuery_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"a..b":        "invalid query 'a..b': empty segment",
+		"a.":          "invalid query 'a.': empty segment",
+		"a[1":         "invalid query 'a[1': missing ']'",
+		"a[x]":        "invalid query 'a[x]': invalid index 'x'",
+		"a[1]b":       "invalid query 'a[1]b': expected '.' or '[' after ']'",
+		"a[?b ~ i1]":  "invalid query 'a[?b ~ i1]': invalid operator in filter '~ i1'",
+		"a[?b == x1]": "invalid query 'a[?b == x1]': invalid type marker 'x' in filter",
+		"a[?b == ix]": "invalid query 'a[?b == ix]': <filter>: invalid int 'x'",
+	}
+
+	for expr, expected := range tests {
+		_, err := Query(map[string]any{}, expr)
+		if err == nil {
+			t.Errorf("expected '%v' to be invalid", expr)
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestQueryReader_StopEarly(t *testing.T) {
+	count := 0
+	for _, err := range QueryReader(strings.NewReader("1{a:[i1,i2,i3"), "a.*") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		count++
+		if count == 2 {
+			break
+		}
+	}
+
+	if count != 2 {
+		t.Error("expected 2 results but got", count)
+	}
+}
+
+func TestQueryReader_Error(t *testing.T) {
+	var errs []error
+	for _, err := range QueryReader(strings.NewReader("1{a:[i1,ix]}"), "a.*") {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 1 || errs[0].Error() != "<root>.a.1: invalid int 'x'" {
+		t.Error("expected error to be \"<root>.a.1: invalid int 'x'\" but got", errs)
+	}
+}
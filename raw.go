@@ -0,0 +1,366 @@
+package cereal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// RawValue is an encoded value, including its type marker. It can be used to delay decoding
+// part of a document: Unmarshal fills a RawValue with the exact bytes of a value, and
+// Serialize writes a RawValue through as it is.
+type RawValue []byte
+
+var rawValueType = reflect.TypeOf(RawValue(nil))
+
+// rawEntry locates a value in a document. When the value is a map entry, start is the offset of
+// its key.
+type rawEntry struct {
+	found      bool
+	start      int64
+	valueStart int64
+	end        int64
+	valueType  ValueType
+	// prevEnd is the end of the previous value in the same map or array, or -1 if there is none.
+	prevEnd int64
+	// closer is the offset of the closer of the map that should contain a missing key.
+	closer int64
+
+	// parent is the path of the map or array holding the value, which is nil for the root.
+	parent  []string
+	inArray bool
+	key     string
+	index   int
+}
+
+// GetRaw returns the encoded value found at the given path in the document, including its type
+// marker, along with its type. The document is scanned without decoding any values and the
+// returned slice refers to the same memory as data. Path segments follow the same rules as
+// Elements.
+func GetRaw(data []byte, path ...string) ([]byte, ValueType, error) {
+	entry, err := findRawV1(data, path)
+	if err != nil {
+		return nil, entry.valueType, err
+	}
+	if !entry.found {
+		return nil, entry.valueType, notFoundRaw(entry, path)
+	}
+
+	return data[entry.valueStart:entry.end], entry.valueType, nil
+}
+
+// SetRaw returns a copy of the document in which the value at the given path is replaced by
+// the encoded value, which must include its type marker. A missing key is added to the end of
+// its map, but the map itself must exist. Likewise, the index "-" or the length of an array
+// adds an element to the end of the array.
+func SetRaw(data []byte, value []byte, path ...string) ([]byte, error) {
+	entry, err := findRawV1(data, path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateRawValue(value, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := bytes.Buffer{}
+	if entry.found {
+		result.Write(data[:entry.valueStart])
+		result.Write(value)
+		result.Write(data[entry.end:])
+		return result.Bytes(), nil
+	}
+
+	result.Write(data[:entry.closer])
+	if entry.prevEnd >= 0 && !bytes.Contains(data[entry.prevEnd:entry.closer], []byte{','}) {
+		result.WriteByte(',')
+	}
+	if !entry.inArray {
+		result.WriteString(escapeKey(path[len(path)-1]))
+		result.WriteByte(':')
+	}
+	result.Write(value)
+	result.Write(data[entry.closer:])
+	return result.Bytes(), nil
+}
+
+// insertRawV1 works like SetRaw, except that an element of an array is moved along to make
+// room for the value rather than being replaced.
+func insertRawV1(data []byte, value []byte, path []string) ([]byte, error) {
+	entry, err := findRawV1(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.inArray || !entry.found {
+		return SetRaw(data, value, path...)
+	}
+
+	err = validateRawValue(value, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	result := bytes.Buffer{}
+	result.Write(data[:entry.start])
+	result.Write(value)
+	result.WriteByte(',')
+	result.Write(data[entry.start:])
+	return result.Bytes(), nil
+}
+
+// DeleteRaw returns a copy of the document with the map entry or array element at the given
+// path removed, along with the comma that separates it from its neighbours.
+func DeleteRaw(data []byte, path ...string) ([]byte, error) {
+	if len(path) == 0 {
+		return nil, errors.New("<root>: the root cannot be deleted")
+	}
+
+	entry, err := findRawV1(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.found {
+		return nil, notFoundRaw(entry, path)
+	}
+
+	start := entry.start
+	end := entry.end
+
+	i := end
+	for i < int64(len(data)) && isSpace(data[i]) {
+		i++
+	}
+	if i < int64(len(data)) && data[i] == ',' {
+		// remove the comma that follows the value
+		end = i + 1
+	} else if entry.prevEnd >= 0 {
+		// the value is the last one, so remove everything since the previous value instead
+		start = entry.prevEnd
+	}
+
+	// remove the whole line when nothing else is on it
+	lineStart := start
+	for lineStart > 0 && (data[lineStart-1] == ' ' || data[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < int64(len(data)) && (data[lineEnd] == ' ' || data[lineEnd] == '\t' || data[lineEnd] == '\r') {
+		lineEnd++
+	}
+	if lineStart > 0 && data[lineStart-1] == '\n' && lineEnd < int64(len(data)) && data[lineEnd] == '\n' {
+		start = lineStart
+		end = lineEnd + 1
+	}
+
+	result := bytes.Buffer{}
+	result.Write(data[:start])
+	result.Write(data[end:])
+	return result.Bytes(), nil
+}
+
+// findRawV1 scans the document for the value at the given path. If only the last key of the
+// path is missing, or the last segment is the index "-" or the length of an array, the
+// returned entry is not found but locates the closer of its map or array.
+func findRawV1(data []byte, segments []string) (rawEntry, error) {
+	t := NewTokenizer(bytes.NewReader(data))
+	tok, err := t.Next()
+	if err != nil {
+		return rawEntry{}, err
+	}
+
+	entry := rawEntry{found: true, start: tok.Offset, valueStart: tok.Offset, valueType: Map, prevEnd: -1}
+	for i, segment := range segments {
+		path := t.Path()
+		last := i == len(segments)-1
+
+		var target int
+		switch tok.Kind {
+		case MapStart:
+		case ArrayStart:
+			if last && segment == "-" {
+				target = -1
+				break
+			}
+
+			target, err = strconv.Atoi(segment)
+			if err != nil || target < 0 {
+				return rawEntry{}, fmt.Errorf("%v: invalid array index '%v'", strings.Join(path, "."), segment)
+			}
+		default:
+			return rawEntry{}, fmt.Errorf("%v: cannot select '%v' from a scalar value", strings.Join(path, "."), segment)
+		}
+
+		entry = rawEntry{prevEnd: -1, parent: path, inArray: tok.Kind == ArrayStart, key: segment, index: target}
+		for index := 0; ; index++ {
+			tok, err = t.Next()
+			if err != nil {
+				return entry, err
+			}
+
+			if tok.Kind == MapEnd {
+				if last {
+					entry.closer = tok.Offset
+					return entry, nil
+				}
+				return entry, fmt.Errorf("%v: key '%v' not found", strings.Join(path, "."), segment)
+			} else if tok.Kind == ArrayEnd {
+				if last && (target < 0 || target == index) {
+					entry.closer = tok.Offset
+					entry.index = index
+					return entry, nil
+				}
+				return entry, fmt.Errorf("%v: index %v out of range", strings.Join(path, "."), segment)
+			}
+
+			entry.start = tok.Offset
+			match := index == target
+			if tok.Kind == Key {
+				match = tok.Value == segment
+				tok, err = t.Next()
+				if err != nil {
+					return entry, err
+				}
+			}
+
+			if match {
+				break
+			}
+
+			err = skipValueV1(t, tok)
+			if err != nil {
+				return entry, err
+			}
+			entry.prevEnd = valueEnd(t, tok)
+		}
+
+		entry.found = true
+		entry.valueStart = tok.Offset
+		entry.valueType = tok.Type
+	}
+
+	err = skipValueV1(t, tok)
+	if err != nil {
+		return entry, err
+	}
+	entry.end = valueEnd(t, tok)
+
+	return entry, nil
+}
+
+// notFoundRaw returns the error for a path whose last key or index is missing.
+func notFoundRaw(entry rawEntry, path []string) error {
+	if entry.inArray {
+		return fmt.Errorf("%v: index %v out of range", strings.Join(entry.parent, "."), path[len(path)-1])
+	}
+
+	return fmt.Errorf("%v: key '%v' not found", strings.Join(entry.parent, "."), path[len(path)-1])
+}
+
+// valueEnd returns the end of the value started by tok, once the value has been consumed.
+func valueEnd(t *Tokenizer, tok Token) int64 {
+	if tok.Kind == Scalar {
+		return tok.End
+	}
+
+	return t.Offset()
+}
+
+// validateRawValue checks that value is exactly one encoded value that can be placed where the
+// entry is, reporting errors with the path of the entry.
+func validateRawValue(value []byte, entry rawEntry) error {
+	input := bytes.Buffer{}
+
+	var t *Tokenizer
+	if entry.parent == nil {
+		t = newTokenizerV1(bytes.NewReader(value))
+	} else if entry.inArray {
+		input.Write(value)
+		input.WriteByte(']')
+		t = newContainerTokenizerV1(&input, Array, entry.parent)
+		t.stack[0].count = entry.index
+	} else {
+		input.WriteString(escapeKey(entry.key))
+		input.WriteByte(':')
+		input.Write(value)
+		input.WriteByte('}')
+		t = newContainerTokenizerV1(&input, Map, entry.parent)
+
+		_, err := t.Next()
+		if err != nil {
+			return err
+		}
+	}
+	start := t.Offset()
+
+	tok, err := t.Next()
+	if err != nil {
+		return err
+	}
+	if tok.Kind == MapEnd || tok.Kind == ArrayEnd {
+		path := []string{"<root>"}
+		if entry.parent != nil {
+			path = append(append([]string{}, entry.parent...), entry.key)
+		}
+		return fmt.Errorf("%v: expected a value", strings.Join(path, "."))
+	}
+
+	err = skipValueV1(t, tok)
+	if err != nil {
+		return err
+	}
+	if tok.Kind == Scalar {
+		_, err = parseValue(tok.Value, tok.Type, t.Path())
+		if err != nil {
+			return err
+		}
+	}
+
+	if tok.Offset != start || valueEnd(t, tok) != start+int64(len(value)) {
+		return fmt.Errorf("%v: expected a single value", strings.Join(t.Path(), "."))
+	}
+
+	return nil
+}
+
+// readRawValueV1 returns the encoded bytes of the value started by tok, consuming the rest of
+// the value if it is a map or an array.
+func readRawValueV1(t *Tokenizer, tok Token) (RawValue, error) {
+	if tok.Kind == Scalar {
+		return RawValue(tok.Raw), nil
+	}
+
+	capture := bytes.NewBufferString(tok.Raw)
+	t.capture = capture
+	err := t.Skip()
+	t.capture = nil
+	if err != nil {
+		return nil, err
+	}
+
+	return RawValue(capture.Bytes()), nil
+}
+
+// writeRawValue writes the raw value after checking that it is a single valid value.
+func writeRawValue(raw RawValue, buf io.Writer, path []string) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("%v: empty raw value", strings.Join(path, "."))
+	}
+
+	entry := rawEntry{parent: path[:len(path)-1], key: path[len(path)-1]}
+	if len(path) == 1 {
+		entry.parent = nil
+	}
+
+	err := validateRawValue(raw, entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = buf.Write(raw)
+	return err
+}
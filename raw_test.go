@@ -0,0 +1,280 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetRaw(t *testing.T) {
+	data := []byte("1{a:{b:[i1,\"x\\,y,{c:d2.5}]},s:\"hello}")
+
+	tests := []struct {
+		path      []string
+		raw       string
+		valueType ValueType
+	}{
+		{[]string{}, string(data[1:]), Map},
+		{[]string{"a"}, "{b:[i1,\"x\\,y,{c:d2.5}]}", Map},
+		{[]string{"a", "b"}, "[i1,\"x\\,y,{c:d2.5}]", Array},
+		{[]string{"a", "b", "0"}, "i1", Int},
+		{[]string{"a", "b", "1"}, "\"x\\,y", String},
+		{[]string{"a", "b", "2", "c"}, "d2.5", Float64},
+		{[]string{"s"}, "\"hello", String},
+	}
+
+	for _, test := range tests {
+		raw, valueType, err := GetRaw(data, test.path...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if string(raw) != test.raw || valueType != test.valueType {
+			t.Errorf("expected %v to be '%v' of type %v but got '%v' of type %v", test.path, test.raw, test.valueType, string(raw), valueType)
+		}
+	}
+}
+
+func TestGetRaw_Errors(t *testing.T) {
+	data := []byte("1{a:{b:[i1]},s:\"hello}")
+
+	tests := map[string][]string{
+		"<root>: key 'x' not found":                       {"x"},
+		"<root>.a: key 'x' not found":                     {"a", "x", "y"},
+		"<root>.a.b: index 1 out of range":                {"a", "b", "1"},
+		"<root>.a.b: invalid array index 'x'":             {"a", "b", "x"},
+		"<root>.s: cannot select 'x' from a scalar value": {"s", "x"},
+	}
+
+	for expected, path := range tests {
+		_, _, err := GetRaw(data, path...)
+		if err == nil {
+			t.Errorf("expected %v to fail", path)
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestSetRaw(t *testing.T) {
+	data := []byte("1{a:{b:[i1,i2]},s:\"hello}")
+
+	tests := []struct {
+		path     []string
+		value    string
+		expected string
+	}{
+		{[]string{"s"}, "\"bye", "1{a:{b:[i1,i2]},s:\"bye}"},
+		{[]string{"a", "b", "1"}, "{c:b1}", "1{a:{b:[i1,{c:b1}]},s:\"hello}"},
+		{[]string{"a", "b"}, "i3", "1{a:{b:i3},s:\"hello}"},
+		{[]string{"n"}, "i3", "1{a:{b:[i1,i2]},s:\"hello,n:i3}"},
+		{[]string{"a", "c:"}, "b0", "1{a:{b:[i1,i2],c\\::b0},s:\"hello}"},
+		{[]string{}, "{}", "1{}"},
+		{[]string{"a", "b", "-"}, "i3", "1{a:{b:[i1,i2,i3]},s:\"hello}"},
+		{[]string{"a", "b", "2"}, "n", "1{a:{b:[i1,i2,n]},s:\"hello}"},
+	}
+
+	for _, test := range tests {
+		result, err := SetRaw(data, []byte(test.value), test.path...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if string(result) != test.expected {
+			t.Errorf("expected setting %v to give '%v' but got '%v'", test.path, test.expected, string(result))
+		}
+		if !Valid(result) {
+			t.Errorf("expected '%v' to be valid", string(result))
+		}
+	}
+}
+
+func TestSetRaw_EmptyMap(t *testing.T) {
+	result, err := SetRaw([]byte("1{a:{}}"), []byte("i1"), "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "1{a:{b:i1}}" {
+		t.Error("expected '1{a:{b:i1}}' but got", string(result))
+	}
+}
+
+func TestSetRaw_InvalidValue(t *testing.T) {
+	data := []byte("1{a:[i1,i2],s:\"hello}")
+
+	tests := []struct {
+		path     []string
+		value    string
+		expected string
+	}{
+		{[]string{"s"}, "ix", "<root>.s: invalid int 'x'"},
+		{[]string{"s"}, "i1,b:i2", "<root>.s: expected a single value"},
+		{[]string{"s"}, "", "<root>: invalid type marker '}'"},
+		{[]string{"a", "1"}, "i1,i2", "<root>.a.1: expected a single value"},
+		{[]string{"a", "1"}, "]", "<root>.a.1: expected a value"},
+		{[]string{"a", "1"}, "[i1", "<root>.a.1: expected a single value"},
+		{[]string{}, "i1", "<root>: expected '{'"},
+		{[]string{"x", "y"}, "i1", "<root>: key 'x' not found"},
+	}
+
+	for _, test := range tests {
+		_, err := SetRaw(data, []byte(test.value), test.path...)
+		if err == nil {
+			t.Errorf("expected setting %v to '%v' to fail", test.path, test.value)
+			continue
+		}
+
+		if err.Error() != test.expected {
+			t.Errorf("expected error to be '%v' but got '%v'", test.expected, err.Error())
+		}
+	}
+}
+
+func TestDeleteRaw(t *testing.T) {
+	tests := []struct {
+		data     string
+		path     []string
+		expected string
+	}{
+		{"1{a:i1,b:i2,c:i3}", []string{"b"}, "1{a:i1,c:i3}"},
+		{"1{a:i1,b:i2,c:i3}", []string{"a"}, "1{b:i2,c:i3}"},
+		{"1{a:i1,b:i2,c:i3}", []string{"c"}, "1{a:i1,b:i2}"},
+		{"1{a:i1}", []string{"a"}, "1{}"},
+		{"1{a:{x:i1},b:[i1,{y:i2}]}", []string{"b", "1"}, "1{a:{x:i1},b:[i1]}"},
+		{"1{a:{x:i1},b:[i1,{y:i2}]}", []string{"a"}, "1{b:[i1,{y:i2}]}"},
+		{"1{\n  a:i1,\n  b:i2,\n}", []string{"b"}, "1{\n  a:i1,\n}"},
+		{"1{\n  a:i1,\n  b:i2\n}", []string{"a"}, "1{\n  b:i2\n}"},
+	}
+
+	for _, test := range tests {
+		result, err := DeleteRaw([]byte(test.data), test.path...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if string(result) != test.expected {
+			t.Errorf("expected deleting %v from '%v' to give '%v' but got '%v'", test.path, test.data, test.expected, string(result))
+		}
+	}
+}
+
+func TestDeleteRaw_Errors(t *testing.T) {
+	_, err := DeleteRaw([]byte("1{a:{}}"), "a", "b")
+	if err == nil || err.Error() != "<root>.a: key 'b' not found" {
+		t.Error("expected error to be \"<root>.a: key 'b' not found\" but got", err)
+	}
+
+	_, err = DeleteRaw([]byte("1{a:{}}"))
+	if err == nil || err.Error() != "<root>: the root cannot be deleted" {
+		t.Error("expected error to be '<root>: the root cannot be deleted' but got", err)
+	}
+}
+
+func TestRawValue_Unmarshal(t *testing.T) {
+	type envelope struct {
+		Kind    string
+		Payload RawValue
+		Count   RawValue
+		Items   []RawValue
+	}
+
+	data := []byte("1{Kind:\"order,Payload:{id:i7, lines:[\"a\\,b] # note\n},Count:i3,Items:[b1,{x:d1.5},\"s]}")
+
+	var e envelope
+	err := Unmarshal(data, &e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e.Kind != "order" {
+		t.Error("expected Kind to be 'order' but got", e.Kind)
+	}
+	if string(e.Payload) != "{id:i7, lines:[\"a\\,b] # note\n}" {
+		t.Errorf("expected Payload to keep its exact bytes but got '%v'", string(e.Payload))
+	}
+	if string(e.Count) != "i3" {
+		t.Errorf("expected Count to be 'i3' but got '%v'", string(e.Count))
+	}
+
+	expected := []string{"b1", "{x:d1.5}", "\"s"}
+	if len(e.Items) != len(expected) {
+		t.Fatal("expected 3 items but got", len(e.Items))
+	}
+	for i, item := range expected {
+		if string(e.Items[i]) != item {
+			t.Errorf("expected item %v to be '%v' but got '%v'", i, item, string(e.Items[i]))
+		}
+	}
+
+	var payload map[string]any
+	err = Unmarshal(append([]byte("1"), e.Payload...), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if payload["id"] != 7 {
+		t.Error("expected the payload to be decoded later but got", payload)
+	}
+}
+
+func TestRawValue_Serialize(t *testing.T) {
+	type envelope struct {
+		Kind    string
+		Payload RawValue
+	}
+
+	result, err := Serialize(envelope{Kind: "order", Payload: RawValue("{ id:i7 }")}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1{Kind:\"order,Payload:{ id:i7 }}" {
+		t.Error("expected the raw value to be written verbatim but got", string(result))
+	}
+
+	result, err = Serialize(map[string]any{"a": []any{RawValue("i1"), RawValue("[b0]")}}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1{a:[i1,[b0]]}" {
+		t.Error("expected '1{a:[i1,[b0]]}' but got", string(result))
+	}
+}
+
+func TestRawValue_SerializeInvalid(t *testing.T) {
+	tests := map[string]RawValue{
+		"<root>.a: empty raw value":         nil,
+		"<root>.a: invalid int 'x'":         RawValue("ix"),
+		"<root>.a: expected a single value": RawValue("i1,b:i2"),
+	}
+
+	for expected, raw := range tests {
+		_, err := Serialize(map[string]any{"a": raw}, "1")
+		if err == nil {
+			t.Errorf("expected '%v' to fail", string(raw))
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestRawValue_Elements(t *testing.T) {
+	var values []string
+	for value, err := range ElementsOf[RawValue](strings.NewReader("1{a:[i1,{b:\"x}]}"), "a") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, string(value))
+	}
+
+	if len(values) != 2 || values[0] != "i1" || values[1] != "{b:\"x}" {
+		t.Error("expected the raw elements but got", values)
+	}
+}
@@ -0,0 +1,296 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Schema describes the values a document may contain. A schema is itself written as a
+// cereal document, where each schema is a map with the following keys:
+//
+//   - type: one of "bool", "int", "float32", "float64", "string", "map", "array", "null" or
+//     "any". It is required.
+//   - description: a string describing the value.
+//   - optional: b1 if a field may be left out of its map. Fields are required by default.
+//   - enum: an array of the values allowed, which must be of the declared type.
+//   - fields: a map from the keys of a map to their schemas. Keys that are not listed are
+//     not allowed unless values is also given.
+//   - values: the schema of the entries of a map that are not listed in fields.
+//   - items: the schema of the elements of an array.
+//
+// For example:
+//
+//	1{type:"map,fields:{
+//	  name:{type:"string},
+//	  ratio:{type:"float32,optional:b1},
+//	  mode:{type:"string,enum:["dev,"prod]},
+//	  ports:{type:"array,items:{type:"int}},
+//	}}
+type Schema struct {
+	Type        string
+	Description string
+	Optional    bool
+	Enum        []any
+	Fields      []SchemaField
+	Values      *Schema
+	Items       *Schema
+}
+
+// SchemaField is a field of a map in a schema.
+type SchemaField struct {
+	Name   string
+	Schema *Schema
+}
+
+// Field returns the schema of the named field, or nil if there is none.
+func (s *Schema) Field(name string) *Schema {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f.Schema
+		}
+	}
+
+	return nil
+}
+
+// schemaTypes maps the type names used in a schema to the value types they describe.
+var schemaTypes = map[string]ValueType{
+	"bool":    Bool,
+	"int":     Int,
+	"float32": Float32,
+	"float64": Float64,
+	"string":  String,
+	"map":     Map,
+	"array":   Array,
+	"null":    Null,
+}
+
+func schemaTypeName(valueType ValueType) string {
+	for name, t := range schemaTypes {
+		if t == valueType {
+			return name
+		}
+	}
+
+	return "unknown"
+}
+
+// ParseSchema reads a schema document from the provided io.Reader.
+func ParseSchema(reader io.Reader) (*Schema, error) {
+	doc, err := ParseOrdered(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeSchema(doc, []string{"<root>"})
+}
+
+func decodeSchema(m *OrderedMap, path []string) (*Schema, error) {
+	s := &Schema{}
+
+	for key, value := range m.All() {
+		keyPath := appendPath(path, key)
+
+		var ok bool
+		switch key {
+		case "type":
+			s.Type, ok = value.(string)
+			if ok && s.Type != "any" {
+				_, ok = schemaTypes[s.Type]
+				if !ok {
+					return nil, fmt.Errorf("%v: unknown type '%v'", strings.Join(keyPath, "."), s.Type)
+				}
+			}
+		case "description":
+			s.Description, ok = value.(string)
+		case "optional":
+			s.Optional, ok = value.(bool)
+		case "enum":
+			s.Enum, ok = value.([]any)
+		case "fields":
+			var fields *OrderedMap
+			fields, ok = value.(*OrderedMap)
+			if !ok {
+				break
+			}
+
+			// an empty list of fields still means that no other keys are allowed
+			s.Fields = []SchemaField{}
+			for name, field := range fields.All() {
+				fieldPath := appendPath(keyPath, name)
+				fieldMap, isMap := field.(*OrderedMap)
+				if !isMap {
+					return nil, fmt.Errorf("%v: expected a map", strings.Join(fieldPath, "."))
+				}
+
+				fieldSchema, err := decodeSchema(fieldMap, fieldPath)
+				if err != nil {
+					return nil, err
+				}
+				s.Fields = append(s.Fields, SchemaField{Name: name, Schema: fieldSchema})
+			}
+		case "values", "items":
+			var child *OrderedMap
+			child, ok = value.(*OrderedMap)
+			if !ok {
+				break
+			}
+
+			childSchema, err := decodeSchema(child, keyPath)
+			if err != nil {
+				return nil, err
+			}
+			if key == "values" {
+				s.Values = childSchema
+			} else {
+				s.Items = childSchema
+			}
+		default:
+			return nil, fmt.Errorf("%v: unknown key '%v'", strings.Join(path, "."), key)
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%v: unexpected value of type %v", strings.Join(keyPath, "."), schemaTypeName(mustValueType(value)))
+		}
+	}
+
+	if s.Type == "" {
+		return nil, fmt.Errorf("%v: key 'type' not found", strings.Join(path, "."))
+	}
+	if (s.Fields != nil || s.Values != nil) && s.Type != "map" {
+		return nil, fmt.Errorf("%v: only a map may have fields or values", strings.Join(path, "."))
+	}
+	if s.Items != nil && s.Type != "array" {
+		return nil, fmt.Errorf("%v: only an array may have items", strings.Join(path, "."))
+	}
+
+	for i, value := range s.Enum {
+		if s.Type != "any" && mustValueType(value) != schemaTypes[s.Type] {
+			return nil, fmt.Errorf("%v.enum.%v: expected %v but got %v", strings.Join(path, "."), i, s.Type, schemaTypeName(mustValueType(value)))
+		}
+	}
+
+	return s, nil
+}
+
+func mustValueType(value any) ValueType {
+	valueType, _ := valueTypeOf(value)
+	return valueType
+}
+
+// Violation is a way in which a document does not match a schema.
+type Violation struct {
+	Path    []string
+	Message string
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%v: %v", strings.Join(v.Path, "."), v.Message)
+}
+
+// ValidateSchema checks a document, such as the result of Parse or a typed value, against a
+// schema and returns every violation found, each with the path of the value at fault. The
+// document is valid if no violations are returned. Value types must match exactly, so an int
+// does not match a float64 schema.
+func ValidateSchema(doc any, schema *Schema) []Violation {
+	violations := []Violation{}
+	validateSchemaValue(doc, schema, []string{"<root>"}, &violations)
+	return violations
+}
+
+func validateSchemaValue(value any, s *Schema, path []string, violations *[]Violation) {
+	valueType, ok := valueTypeOf(value)
+	if !ok {
+		*violations = append(*violations, Violation{path, fmt.Sprintf("unsupported value of type %T", value)})
+		return
+	}
+
+	if s.Type != "any" && schemaTypes[s.Type] != valueType {
+		*violations = append(*violations, Violation{path, fmt.Sprintf("expected %v but got %v", s.Type, schemaTypeName(valueType))})
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return len(Diff(value, e)) == 0 }) {
+		allowed := bytes.Buffer{}
+		writeDiffValue(&allowed, s.Enum)
+
+		actual := bytes.Buffer{}
+		writeDiffValue(&actual, value)
+
+		*violations = append(*violations, Violation{path, fmt.Sprintf("%v is not one of %v", actual.String(), allowed.String())})
+	}
+
+	switch valueType {
+	case Map:
+		children := queryChildren(value)
+		for _, f := range s.Fields {
+			i := slices.IndexFunc(children, func(c queryChild) bool { return c.key == f.Name })
+			if i < 0 {
+				if !f.Schema.Optional {
+					*violations = append(*violations, Violation{appendPath(path, f.Name), "missing required field"})
+				}
+				continue
+			}
+
+			validateSchemaValue(children[i].value, f.Schema, appendPath(path, f.Name), violations)
+		}
+
+		for _, c := range children {
+			if s.Field(c.key) != nil {
+				continue
+			}
+
+			if s.Values != nil {
+				validateSchemaValue(c.value, s.Values, appendPath(path, c.key), violations)
+			} else if s.Fields != nil {
+				*violations = append(*violations, Violation{appendPath(path, c.key), "unexpected field"})
+			}
+		}
+	case Array:
+		if s.Items == nil {
+			return
+		}
+
+		for i, c := range queryChildren(value) {
+			validateSchemaValue(c.value, s.Items, appendPath(path, strconv.Itoa(i)), violations)
+		}
+	}
+}
+
+// SerializeSchema returns the schema as a cereal document, which ParseSchema reads back.
+func SerializeSchema(s *Schema) ([]byte, error) {
+	return Serialize(schemaDocument(s), "1")
+}
+
+func schemaDocument(s *Schema) *OrderedMap {
+	m := NewOrderedMap()
+	m.Set("type", s.Type)
+	if s.Description != "" {
+		m.Set("description", s.Description)
+	}
+	if s.Optional {
+		m.Set("optional", true)
+	}
+	if len(s.Enum) > 0 {
+		m.Set("enum", s.Enum)
+	}
+	if s.Fields != nil {
+		fields := NewOrderedMap()
+		for _, f := range s.Fields {
+			fields.Set(f.Name, schemaDocument(f.Schema))
+		}
+		m.Set("fields", fields)
+	}
+	if s.Values != nil {
+		m.Set("values", schemaDocument(s.Values))
+	}
+	if s.Items != nil {
+		m.Set("items", schemaDocument(s.Items))
+	}
+
+	return m
+}
@@ -0,0 +1,142 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSchema = `1{type:"map,description:"A service,fields:{
+	name:{type:"string},
+	ratio:{type:"float32,optional:b1},
+	mode:{type:"string,enum:["dev,"prod]},
+	ports:{type:"array,items:{type:"int}},
+	labels:{type:"map,optional:b1,values:{type:"string}},
+	extra:{type:"any,optional:b1},
+}}`
+
+func TestParseSchema(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Type != "map" || s.Description != "A service" || len(s.Fields) != 6 {
+		t.Fatalf("expected a map with 6 fields but got %+v", s)
+	}
+
+	names := []string{}
+	for _, f := range s.Fields {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "name,ratio,mode,ports,labels,extra" {
+		t.Error("expected the fields to keep their order but got", names)
+	}
+
+	if ratio := s.Field("ratio"); ratio == nil || ratio.Type != "float32" || !ratio.Optional {
+		t.Errorf("expected ratio to be an optional float32 but got %+v", ratio)
+	}
+	if mode := s.Field("mode"); len(mode.Enum) != 2 || mode.Enum[1] != "prod" {
+		t.Errorf("expected mode to have an enum but got %+v", mode)
+	}
+	if ports := s.Field("ports"); ports.Items == nil || ports.Items.Type != "int" {
+		t.Errorf("expected ports to be an array of int but got %+v", ports)
+	}
+	if labels := s.Field("labels"); labels.Values == nil || labels.Values.Type != "string" {
+		t.Errorf("expected labels to be a map of string but got %+v", labels)
+	}
+	if s.Field("missing") != nil {
+		t.Error("expected no schema for a missing field")
+	}
+}
+
+func TestParseSchema_Errors(t *testing.T) {
+	tests := map[string]string{
+		"1{}":                                 "<root>: key 'type' not found",
+		"1{type:\"number}":                    "<root>.type: unknown type 'number'",
+		"1{type:i1}":                          "<root>.type: unexpected value of type int",
+		"1{type:\"int,size:i4}":               "<root>: unknown key 'size'",
+		"1{type:\"int,fields:{}}":             "<root>: only a map may have fields or values",
+		"1{type:\"map,items:{type:\"int}}":    "<root>: only an array may have items",
+		"1{type:\"map,fields:{a:{type:\"x}}}": "<root>.fields.a.type: unknown type 'x'",
+		"1{type:\"map,fields:{a:i1}}":         "<root>.fields.a: expected a map",
+		"1{type:\"int,enum:[i1,d2]}":          "<root>.enum.1: expected int but got float64",
+		"1{type:\"int,optional:\"yes}":        "<root>.optional: unexpected value of type string",
+	}
+
+	for input, expected := range tests {
+		_, err := ParseSchema(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected '%v' to fail", input)
+			continue
+		}
+
+		if err.Error() != expected {
+			t.Errorf("expected error to be '%v' but got '%v'", expected, err.Error())
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Parse(strings.NewReader("1{name:\"svc,mode:\"dev,ports:[i80,i443],labels:{team:\"core},extra:[b1]}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := ValidateSchema(doc, s)
+	if len(violations) != 0 {
+		t.Error("expected no violations but got", violations)
+	}
+}
+
+func TestValidateSchema_Violations(t *testing.T) {
+	s, err := ParseSchema(strings.NewReader(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := ParseOrdered(strings.NewReader("1{ratio:d0.5,mode:\"test,ports:[i80,d1],labels:{team:i1},other:b1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"<root>.name: missing required field",
+		"<root>.ratio: expected float32 but got float64",
+		"<root>.mode: \"test is not one of [\"dev,\"prod]",
+		"<root>.ports.1: expected int but got float64",
+		"<root>.labels.team: expected string but got int",
+		"<root>.other: unexpected field",
+	}
+
+	violations := ValidateSchema(doc, s)
+	if len(violations) != len(expected) {
+		t.Fatal("expected 6 violations but got", violations)
+	}
+	for i, e := range expected {
+		if violations[i].Error() != e {
+			t.Errorf("expected violation %v to be '%v' but got '%v'", i, e, violations[i].Error())
+		}
+	}
+}
+
+func TestValidateSchema_Struct(t *testing.T) {
+	type service struct {
+		Name  string
+		Ratio float64
+	}
+
+	s, err := ParseSchema(strings.NewReader("1{type:\"map,fields:{Name:{type:\"string},Ratio:{type:\"float32}}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := ValidateSchema(service{Name: "svc", Ratio: 0.5}, s)
+	if len(violations) != 1 || violations[0].Error() != "<root>.Ratio: expected float32 but got float64" {
+		t.Error("expected a single violation for Ratio but got", violations)
+	}
+}
@@ -31,19 +31,62 @@ func Serialize(value any, version string) ([]byte, error) {
 }
 
 func serializeV1(value any, buf io.Writer) error {
-	return writeValue(reflect.ValueOf(value), buf, []string{"<root>"})
+	rv := reflect.ValueOf(value)
+	if isNilValue(rv) {
+		// the root of a document is always a map
+		return fmt.Errorf("<root>: unsupported value %v", value)
+	}
+
+	return writeValue(rv, buf, []string{"<root>"}, false)
+}
+
+// isNilValue reports whether value is nil, or a nil interface, map, pointer or slice, which
+// are written as a null.
+func isNilValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return value.IsNil()
+	}
+	return false
 }
 
-func writeValue(value reflect.Value, buf io.Writer, path []string) error {
+// writeValue writes a value, letting values that implement Marshaler encode themselves unless
+// reflective is true.
+func writeValue(value reflect.Value, buf io.Writer, path []string, reflective bool) error {
+	if value.Kind() == reflect.Interface && !value.IsNil() {
+		value = value.Elem()
+	}
+	// a nil RawValue is reported as empty rather than written as a null
+	if isNilValue(value) && (!value.IsValid() || value.Type() != reflect.TypeOf(RawValue(nil))) {
+		buf.Write([]byte{typeMarkers[Null]})
+		return nil
+	}
+
 	kind := value.Kind()
 
-	if kind == reflect.Interface {
-		v := value.Interface()
-		if v == nil {
-			return fmt.Errorf("%v: unsupported value %v", strings.Join(path, "."), v)
+	if value.CanInterface() {
+		switch m := value.Interface().(type) {
+		case *OrderedMap:
+			if m != nil {
+				return writeOrderedMap(m, buf, path, reflective)
+			}
+		case OrderedMap:
+			return writeOrderedMap(&m, buf, path, reflective)
+		case RawValue:
+			return writeRawValue(m, buf, path)
+		case Marshaler:
+			if reflective {
+				break
+			}
+			data, err := m.MarshalCereal()
+			if err != nil {
+				return err
+			}
+			_, err = buf.Write(data)
+			return err
 		}
-		kind = reflect.TypeOf(v).Kind()
-		value = reflect.ValueOf(v)
 	}
 
 	switch kind {
@@ -65,7 +108,10 @@ func writeValue(value reflect.Value, buf io.Writer, path []string) error {
 			for i := 0; i < value.Len(); i++ {
 				elemValue := value.Index(i)
 
-				writeValue(elemValue, buf, append(path, strconv.Itoa(i)))
+				err := writeValue(elemValue, buf, append(path, strconv.Itoa(i)), reflective)
+				if err != nil {
+					return err
+				}
 				if i < value.Len()-1 {
 					buf.Write([]byte{','})
 				} else {
@@ -89,7 +135,7 @@ func writeValue(value reflect.Value, buf io.Writer, path []string) error {
 				buf.Write([]byte{':'})
 
 				mapValue := value.MapIndex(mapKey)
-				err := writeValue(mapValue, buf, append(path, mapKey.String()))
+				err := writeValue(mapValue, buf, append(path, mapKey.String()), reflective)
 				if err != nil {
 					return err
 				}
@@ -102,33 +148,70 @@ func writeValue(value reflect.Value, buf io.Writer, path []string) error {
 			}
 		}
 	case reflect.Struct:
-		buf.Write([]byte{'{'})
+		return writeStruct(value, buf, path, reflective)
+	default:
+		return fmt.Errorf("%v: unsupported value type %v for %v", strings.Join(path, "."), kind, value)
+	}
+	return nil
+}
+
+// writeStruct writes the fields of a struct using reflection.
+func writeStruct(value reflect.Value, buf io.Writer, path []string, reflective bool) error {
+	buf.Write([]byte{'{'})
+
+	v := value.Interface()
+	t := reflect.TypeOf(v)
+	fields := reflect.VisibleFields(t)
+	first := true
+	for _, f := range fields {
+		name, ok := structFieldKey(f)
+		if !ok {
+			continue
+		}
+		val, err := value.FieldByIndexErr(f.Index)
+		if err != nil {
+			// the field was promoted from a nil embedded pointer
+			continue
+		}
+		key := escapeKey(name)
 
-		v := value.Interface()
-		t := reflect.TypeOf(v)
-		fields := reflect.VisibleFields(t)
-		for i, f := range fields {
-			key := escapeKey(f.Name)
-			val := value.Field(i)
+		if !first {
+			buf.Write([]byte{','})
+		}
+		first = false
 
-			buf.Write([]byte(key))
-			buf.Write([]byte{':'})
+		buf.Write([]byte(key))
+		buf.Write([]byte{':'})
 
-			err := writeValue(val, buf, append(path, key))
-			if err != nil {
-				return err
-			}
+		err = writeValue(val, buf, append(path, name), reflective)
+		if err != nil {
+			return err
+		}
+	}
 
-			if i < len(fields)-1 {
-				buf.Write([]byte{','})
-			} else {
-				buf.Write([]byte{'}'})
-			}
+	buf.Write([]byte{'}'})
+	return nil
+}
+
+func writeOrderedMap(m *OrderedMap, buf io.Writer, path []string, reflective bool) error {
+	buf.Write([]byte{'{'})
+
+	values := reflect.ValueOf(m.values)
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.Write([]byte{','})
 		}
 
-	default:
-		return fmt.Errorf("%v: unsupported value type %v for %v", strings.Join(path, "."), kind, value)
+		buf.Write([]byte(escapeKey(key)))
+		buf.Write([]byte{':'})
+
+		err := writeValue(values.MapIndex(reflect.ValueOf(key)), buf, append(path, key), reflective)
+		if err != nil {
+			return err
+		}
 	}
+
+	buf.Write([]byte{'}'})
 	return nil
 }
 
@@ -136,9 +219,25 @@ func escapeKey(key string) string {
 	key = strings.ReplaceAll(key, "\\", "\\\\")
 	key = strings.ReplaceAll(key, ":", "\\:")
 	key = strings.ReplaceAll(key, "}", "\\}")
+	key = strings.ReplaceAll(key, "\n", "\\n")
+	key = strings.ReplaceAll(key, "\r", "\\r")
+	if len(key) > 0 && (isSpace(key[0]) || key[0] == ',' || key[0] == '#') {
+		// leading whitespace, commas and comments are skipped when a key is expected
+		key = "\\" + key
+	}
 	return key
 }
 
+func escapeValue(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, ",", "\\,")
+	value = strings.ReplaceAll(value, "}", "\\}")
+	value = strings.ReplaceAll(value, "]", "\\]")
+	value = strings.ReplaceAll(value, "\n", "\\n")
+	value = strings.ReplaceAll(value, "\r", "\\r")
+	return value
+}
+
 func writeBool(buf io.Writer, value bool) {
 	buf.Write([]byte{'b'})
 	if value {
@@ -150,7 +249,7 @@ func writeBool(buf io.Writer, value bool) {
 
 func writeString(buf io.Writer, value string) {
 	buf.Write([]byte{'"'})
-	buf.Write([]byte(value))
+	buf.Write([]byte(escapeValue(value)))
 }
 
 func writeInt(buf io.Writer, value int64) {
@@ -3,6 +3,7 @@ package cereal
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -50,6 +51,56 @@ func TestSerializeV1_StructInvalidType(t *testing.T) {
 	}
 }
 
+func TestSerializeV1_StructInvalidTypeTagged(t *testing.T) {
+	type Struct struct {
+		Ptr *int `cereal:"a:b"`
+	}
+	a := 5
+	err := serializeV1(Struct{Ptr: &a}, &bytes.Buffer{})
+	if err == nil || !strings.HasPrefix(err.Error(), "<root>.a:b: unsupported value type ptr for") {
+		t.Error("expected '<root>.a:b: unsupported value type ptr for' but got", err)
+	}
+}
+
+func TestSerializeV1_EmbeddedStruct(t *testing.T) {
+	type Base struct {
+		ID   int
+		Name string `cereal:"name"`
+	}
+	type Extra struct {
+		Note string
+	}
+	type Struct struct {
+		Base
+		*Extra
+		Size int
+	}
+
+	tests := map[string]Struct{
+		`1{ID:i1,name:"a,Size:i2}`:         {Base: Base{ID: 1, Name: "a"}, Size: 2},
+		`1{ID:i1,name:"a,Note:"x,Size:i2}`: {Base: Base{ID: 1, Name: "a"}, Extra: &Extra{Note: "x"}, Size: 2},
+	}
+
+	for expected, value := range tests {
+		b, err := Serialize(value, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Errorf("expected '%v' but got '%v'", expected, string(b))
+		}
+
+		var result Struct
+		err = Unmarshal(b, &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, value) {
+			t.Errorf("expected %+v after a round trip but got %+v", value, result)
+		}
+	}
+}
+
 func TestSerialize_TooLongVersion(t *testing.T) {
 	_, err := Serialize(map[string]any{}, "😀")
 	if err == nil {
@@ -86,24 +137,86 @@ func TestSerializeV1_Unsupported(t *testing.T) {
 
 func TestSerializeV1_Pointer(t *testing.T) {
 	var x *string
-	err := serializeV1(map[string]any{"x": x}, &bytes.Buffer{})
-	if err == nil {
-		t.Error("expected an error")
+	buf := bytes.Buffer{}
+	err := serializeV1(map[string]any{"x": x}, &buf)
+	if err != nil {
+		t.Error(err)
 	}
-	msg := err.Error()
-	if msg != "<root>.x: unsupported value type ptr for <nil>" {
-		t.Error("expected error to be '<root>.x: unsupported value type ptr for <nil>' but got", msg)
+
+	if buf.String() != "{x:n}" {
+		t.Error("expected '{x:n}' but got", buf.String())
 	}
 }
 
 func TestSerializeV1_Nil(t *testing.T) {
-	err := serializeV1(map[string]any{"x": nil}, &bytes.Buffer{})
-	if err == nil {
-		t.Error("expected an error")
+	buf := bytes.Buffer{}
+	err := serializeV1(map[string]any{"x": nil}, &buf)
+	if err != nil {
+		t.Error(err)
 	}
-	msg := err.Error()
-	if msg != "<root>.x: unsupported value <nil>" {
-		t.Error("expected error to be '<root>.x: unsupported value <nil>' but got", msg)
+
+	if buf.String() != "{x:n}" {
+		t.Error("expected '{x:n}' but got", buf.String())
+	}
+}
+
+func TestSerializeV1_NilCollections(t *testing.T) {
+	type Struct struct {
+		Map   map[string]int
+		Slice []int
+	}
+
+	result, err := Serialize(Struct{}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1{Map:n,Slice:n}" {
+		t.Error("expected '1{Map:n,Slice:n}' but got", string(result))
+	}
+
+	result, err = Serialize(map[string]any{"a": []any{nil, []int(nil), 1}}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != "1{a:[n,n,i1]}" {
+		t.Error("expected '1{a:[n,n,i1]}' but got", string(result))
+	}
+}
+
+func TestSerialize_NilRoot(t *testing.T) {
+	_, err := Serialize(nil, "1")
+	if err == nil || err.Error() != "<root>: unsupported value <nil>" {
+		t.Error("expected error to be '<root>: unsupported value <nil>' but got", err)
+	}
+
+	_, err = Serialize(map[string]any(nil), "1")
+	if err == nil || err.Error() != "<root>: unsupported value map[]" {
+		t.Error("expected error to be '<root>: unsupported value map[]' but got", err)
+	}
+}
+
+func TestSerialize_NullRoundTrip(t *testing.T) {
+	input := "1{a:n,b:i1,c:[n,{d:n}]}"
+
+	m, err := ParseOrdered(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Serialize(m, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result) != input {
+		t.Errorf("expected '%v' but got '%v'", input, string(result))
+	}
+
+	parsed, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Serialize(parsed, "1"); err != nil {
+		t.Error(err)
 	}
 }
 
@@ -331,6 +444,18 @@ func TestSerializeV1_BackslashInKey(t *testing.T) {
 	}
 }
 
+func TestSerializeV1_LeadingSpaceInKey(t *testing.T) {
+	buf := bytes.Buffer{}
+	err := serializeV1(map[string]any{" x": 5}, &buf)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if buf.String() != "{\\ x:i5}" {
+		t.Error("expected '{\\ x:i5}' but got", buf.String())
+	}
+}
+
 func TestSerializeV1_InterfaceTypes(t *testing.T) {
 	var v map[string]any
 	json.NewDecoder(strings.NewReader(`{"x":{"d":1.0,"i":2,"s":"a","b":true}}`)).Decode(&v)
@@ -361,16 +486,103 @@ func TestSerializeV1_InterfaceTypes(t *testing.T) {
 	}
 }
 
-func TestSerializeV1_InvalidInterfaceTypes(t *testing.T) {
+func TestSerializeV1_NullInterfaceTypes(t *testing.T) {
 	var v map[string]any
 	json.NewDecoder(strings.NewReader(`{"x":{"nil":null}}`)).Decode(&v)
 	buf := bytes.Buffer{}
 	err := serializeV1(v, &buf)
-	if err == nil {
-		t.Error("expected an error")
+	if err != nil {
+		t.Error(err)
 	}
-	msg := err.Error()
-	if msg != "<root>.x.nil: unsupported value <nil>" {
-		t.Error("expected error to be '<root>.x.nil: unsupported value <nil>' but got", msg)
+
+	if buf.String() != "{x:{nil:n}}" {
+		t.Error("expected '{x:{nil:n}}' but got", buf.String())
+	}
+}
+
+func TestSerializeV1_EscapedString(t *testing.T) {
+	result, err := Serialize(map[string]any{"a": "x,y}z]\\"}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "1{a:\"x\\,y\\}z\\]\\\\}" {
+		t.Error("expected the string to be escaped but got", string(result))
+	}
+
+	m, err := Parse(bytes.NewReader(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != "x,y}z]\\" {
+		t.Error("expected the string to be read back but got", m["a"])
+	}
+}
+
+func TestSerializeV1_EscapedNewline(t *testing.T) {
+	result, err := Serialize(map[string]any{"a\nb": "one\r\ntwo"}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "1{a\\nb:\"one\\r\\ntwo}" {
+		t.Error("expected the newlines to be escaped but got", string(result))
+	}
+
+	m, err := Parse(bytes.NewReader(result))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a\nb"] != "one\r\ntwo" {
+		t.Error("expected the string to be read back but got", m["a\nb"])
+	}
+}
+
+func TestSerializeV1_EscapedBackslash(t *testing.T) {
+	values := []string{`a\nb`, `\n`, `\\n`, `\r\n`, "x\\\ny", `end\`, `\`}
+
+	for _, value := range values {
+		doc := map[string]any{value: value}
+		result, err := Serialize(doc, "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := Parse(bytes.NewReader(result))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", value, err)
+			continue
+		}
+		if len(m) != 1 || m[value] != value {
+			t.Errorf("expected %q to be read back from %q but got %q", value, result, m)
+		}
+	}
+}
+
+func TestSerializeV1_Tags(t *testing.T) {
+	type Struct struct {
+		Name    string `cereal:"name"`
+		Ignored string `cereal:"-"`
+		Port    int    `cereal:"port"`
+	}
+
+	result, err := Serialize(Struct{Name: "svc", Ignored: "x", Port: 80}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "1{name:\"svc,port:i80}" {
+		t.Error("expected the tags to name the keys but got", string(result))
+	}
+}
+
+func TestSerializeV1_EmptyStruct(t *testing.T) {
+	result, err := Serialize(struct{}{}, "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(result) != "1{}" {
+		t.Error("expected '1{}' but got", string(result))
 	}
 }
@@ -0,0 +1,524 @@
+package cereal
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	MapStart TokenKind = iota
+	MapEnd
+	ArrayStart
+	ArrayEnd
+	Key
+	Scalar
+	Comment
+)
+
+// Token is a single lexical element of a document.
+type Token struct {
+	Kind TokenKind
+	// Type is the type of the value started by the token. It is Map for MapStart and Array
+	// for ArrayStart.
+	Type ValueType
+	// Value is the unescaped text of a Key or Scalar, or the text of a Comment following the
+	// '#'.
+	Value string
+	// Raw is the text of the token exactly as it appears in the input, including escapes and,
+	// for values, the type marker.
+	Raw string
+	// Offset is the position of the first byte of the token in the input.
+	Offset int64
+	// End is the position just past the last byte of the token in the input.
+	End int64
+}
+
+// Tokenizer reads a document from an io.Reader and splits it into tokens. It enforces the
+// structure of the document but leaves the interpretation of scalar values to the caller.
+//
+// Whitespace is insignificant between the version and the root map and wherever a key, a type
+// marker or a closer is expected, so it may be used to lay out a document. It is significant inside keys and scalar values, which
+// means a scalar must be followed directly by its terminating comma or closer. A comment
+// starts with '#' wherever whitespace is allowed and runs to the end of the line.
+type Tokenizer struct {
+	reader io.ByteReader
+	offset int64
+
+	version  byte
+	started  bool
+	stack    []frame
+	afterKey bool
+	pending  *Token
+	path     []string
+	comments bool
+	toEnd    bool
+
+	// capture receives every byte read while it is set.
+	capture *bytes.Buffer
+	// reflective makes the decoders ignore Unmarshaler, so that values are decoded by
+	// reflection alone.
+	reflective bool
+}
+
+type frame struct {
+	kind  ValueType
+	path  []string
+	key   string
+	count int
+}
+
+// NewTokenizer returns a Tokenizer that reads from the provided io.Reader. A reader that does
+// not implement io.ByteReader is read one byte at a time, so that nothing past the end of the
+// document is consumed and another document may be read from it afterwards. Wrap it in a
+// bufio.Reader for speed when that does not matter.
+func NewTokenizer(reader io.Reader) *Tokenizer {
+	br, ok := reader.(io.ByteReader)
+	if !ok {
+		br = &byteReader{reader: reader}
+	}
+
+	return &Tokenizer{reader: br}
+}
+
+// newBufferedTokenizer returns a Tokenizer that buffers a reader that does not implement
+// io.ByteReader, for reading a document that does not need to leave the rest of its input
+// unread.
+func newBufferedTokenizer(reader io.Reader) *Tokenizer {
+	if _, ok := reader.(io.ByteReader); !ok {
+		reader = bufio.NewReader(reader)
+	}
+
+	return NewTokenizer(reader)
+}
+
+// byteReader reads from an io.Reader one byte at a time, without buffering.
+type byteReader struct {
+	reader io.Reader
+	buf    [1]byte
+}
+
+func (r *byteReader) ReadByte() (byte, error) {
+	_, err := io.ReadFull(r.reader, r.buf[:])
+	if err != nil {
+		return 0, err
+	}
+	return r.buf[0], nil
+}
+
+// newTokenizerV1 returns a Tokenizer for a version 1 document whose version byte has already
+// been consumed.
+func newTokenizerV1(reader io.Reader) *Tokenizer {
+	t := NewTokenizer(reader)
+	t.version = '1'
+	return t
+}
+
+// newContainerTokenizerV1 returns a Tokenizer positioned just inside a map or array that has
+// already been opened. The tokenizer reaches the end of input once that container is closed.
+func newContainerTokenizerV1(reader io.Reader, kind ValueType, path []string) *Tokenizer {
+	t := newTokenizerV1(reader)
+	t.started = true
+	t.stack = append(t.stack, frame{kind: kind, path: path[:len(path):len(path)]})
+	t.path = path
+	return t
+}
+
+// EmitComments makes Next return a Comment token for each comment instead of skipping it.
+func (t *Tokenizer) EmitComments() {
+	t.comments = true
+}
+
+// ReadToEnd makes Next read the rest of the input once the root map has been closed, instead
+// of returning io.EOF right away. Only whitespace and comments may follow the root map.
+func (t *Tokenizer) ReadToEnd() {
+	t.toEnd = true
+}
+
+// Version returns the version of the document. It is zero until the first call to Next.
+func (t *Tokenizer) Version() byte {
+	return t.version
+}
+
+// Offset returns the number of bytes consumed from the input.
+func (t *Tokenizer) Offset() int64 {
+	return t.offset
+}
+
+// Path returns the path of the most recently returned token, in the same form used in error
+// messages. For a Key it is the path of the value the key introduces. The returned slice must
+// not be modified.
+func (t *Tokenizer) Path() []string {
+	return t.path
+}
+
+// Depth returns the number of maps and arrays that are currently open.
+func (t *Tokenizer) Depth() int {
+	return len(t.stack)
+}
+
+// Next returns the next token in the document. It returns io.EOF once the root map has been
+// closed, or once the rest of the input has been read if ReadToEnd was called.
+func (t *Tokenizer) Next() (Token, error) {
+	if t.pending != nil {
+		tok := *t.pending
+		t.pending = nil
+		t.path = t.stack[len(t.stack)-1].path
+		t.stack = t.stack[:len(t.stack)-1]
+		return tok, nil
+	}
+
+	if !t.started {
+		return t.readRoot()
+	}
+
+	if len(t.stack) == 0 && t.toEnd {
+		return t.readEnd()
+	}
+	if len(t.stack) == 0 {
+		return Token{}, io.EOF
+	}
+
+	if t.stack[len(t.stack)-1].kind == Map && !t.afterKey {
+		return t.readKey()
+	}
+
+	return t.readValue()
+}
+
+// Skip consumes tokens up to and including the end of the innermost open map or array.
+// Calling it right after a MapStart or ArrayStart token skips that whole value.
+func (t *Tokenizer) Skip() error {
+	depth := len(t.stack)
+	for len(t.stack) >= depth {
+		_, err := t.Next()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tokenizer) readByte() (byte, bool, error) {
+	b, err := t.reader.ReadByte()
+	if err == io.EOF {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	t.offset++
+	if t.capture != nil {
+		t.capture.WriteByte(b)
+	}
+	return b, true, nil
+}
+
+func (t *Tokenizer) readRoot() (Token, error) {
+	if t.version == 0 {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return Token{}, err
+		}
+		if !ok {
+			return Token{}, errors.New("expected a version in the first byte")
+		}
+		if b != '1' {
+			return Token{}, fmt.Errorf("unexpected version '%v'", b)
+		}
+
+		t.version = b
+	}
+
+	for {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return Token{}, err
+		}
+		if !ok {
+			return Token{}, errors.New("<root>: unexpected end of input")
+		}
+
+		if isSpace(b) {
+			continue
+		} else if b == '#' {
+			comment, err := t.readComment([]string{"<root>"})
+			if err != nil || t.comments {
+				return comment, err
+			}
+			continue
+		} else if b != '{' {
+			return Token{}, errors.New("<root>: expected '{'")
+		}
+
+		break
+	}
+
+	t.started = true
+	t.path = []string{"<root>"}
+	t.stack = append(t.stack, frame{kind: Map, path: t.path})
+
+	return Token{Kind: MapStart, Type: Map, Raw: "{", Offset: t.offset - 1, End: t.offset}, nil
+}
+
+func (t *Tokenizer) readKey() (Token, error) {
+	f := &t.stack[len(t.stack)-1]
+
+	tok := Token{Kind: Key}
+	key := strings.Builder{}
+	raw := strings.Builder{}
+	escaped := false
+	for {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return tok, err
+		}
+		if !ok {
+			return tok, fmt.Errorf("%v: unexpected end of input", strings.Join(f.path, "."))
+		}
+
+		if raw.Len() == 0 {
+			tok.Offset = t.offset - 1
+		}
+
+		if isSpace(b) && raw.Len() == 0 {
+			continue
+		} else if b == '#' && raw.Len() == 0 {
+			comment, err := t.readComment(f.path)
+			if err != nil || t.comments {
+				return comment, err
+			}
+		} else if escaped {
+			escaped = false
+			key.WriteByte(unescapeByte(b))
+			raw.WriteByte(b)
+		} else if b == '}' && key.Len() == 0 {
+			t.path = f.path
+			t.stack = t.stack[:len(t.stack)-1]
+			return Token{Kind: MapEnd, Type: Map, Raw: "}", Offset: t.offset - 1, End: t.offset}, nil
+		} else if b == ',' && key.Len() == 0 {
+			continue
+		} else if b == ':' {
+			tok.Value = key.String()
+			tok.Raw = raw.String()
+			tok.End = t.offset - 1
+
+			f.key = tok.Value
+			t.afterKey = true
+			t.path = append(f.path, f.key)
+			return tok, nil
+		} else if b == '\\' {
+			escaped = true
+			raw.WriteByte(b)
+		} else {
+			key.WriteByte(b)
+			raw.WriteByte(b)
+		}
+	}
+}
+
+func (t *Tokenizer) readValue() (Token, error) {
+	f := &t.stack[len(t.stack)-1]
+
+	var path []string
+	var closer byte
+	if f.kind == Map {
+		path = append(f.path, f.key)
+		closer = '}'
+	} else {
+		path = append(f.path, strconv.Itoa(f.count))
+		closer = ']'
+	}
+	path = path[:len(path):len(path)]
+
+	var b byte
+	for {
+		var ok bool
+		var err error
+		b, ok, err = t.readByte()
+		if err != nil {
+			return Token{}, err
+		}
+		if !ok {
+			return Token{}, fmt.Errorf("%v: unexpected end of input", strings.Join(f.path, "."))
+		}
+
+		if isSpace(b) {
+			continue
+		} else if b == '#' {
+			comment, err := t.readComment(f.path)
+			if err != nil || t.comments {
+				return comment, err
+			}
+			continue
+		}
+
+		if f.kind == Array {
+			if b == ']' {
+				t.path = f.path
+				t.stack = t.stack[:len(t.stack)-1]
+				return Token{Kind: ArrayEnd, Type: Array, Raw: "]", Offset: t.offset - 1, End: t.offset}, nil
+			} else if b == ',' && f.count > 0 {
+				// looking for a type, but found an unexpected comma, just try again
+				continue
+			}
+		}
+
+		break
+	}
+
+	markerPath := path
+	if f.kind == Map {
+		markerPath = f.path
+	}
+
+	valueType, err := parseValueType(b, markerPath)
+	if err != nil {
+		return Token{}, err
+	}
+
+	f.count++
+	t.afterKey = false
+	t.path = path
+
+	tok := Token{Type: valueType, Offset: t.offset - 1}
+	switch valueType {
+	case Map:
+		t.stack = append(t.stack, frame{kind: Map, path: path})
+		tok.Kind = MapStart
+		tok.Raw = "{"
+		tok.End = t.offset
+		return tok, nil
+	case Array:
+		t.stack = append(t.stack, frame{kind: Array, path: path})
+		tok.Kind = ArrayStart
+		tok.Raw = "["
+		tok.End = t.offset
+		return tok, nil
+	}
+
+	tok.Kind = Scalar
+	value := strings.Builder{}
+	raw := strings.Builder{}
+	raw.WriteByte(b)
+	escaped := false
+	for {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return tok, err
+		}
+		if !ok {
+			return tok, fmt.Errorf("%v: unexpected end of input", strings.Join(f.path, "."))
+		}
+
+		if escaped {
+			escaped = false
+			value.WriteByte(unescapeByte(b))
+			raw.WriteByte(b)
+		} else if b == ',' || b == closer {
+			tok.Value = value.String()
+			tok.Raw = raw.String()
+			tok.End = t.offset - 1
+
+			if b == closer {
+				kind := MapEnd
+				if closer == ']' {
+					kind = ArrayEnd
+				}
+				t.pending = &Token{Kind: kind, Type: f.kind, Raw: string(closer), Offset: t.offset - 1, End: t.offset}
+			}
+			return tok, nil
+		} else if b == '\\' {
+			escaped = true
+			raw.WriteByte(b)
+		} else {
+			value.WriteByte(b)
+			raw.WriteByte(b)
+		}
+	}
+}
+
+// readEnd reads the rest of the input after the root map, which may only hold whitespace and
+// comments.
+func (t *Tokenizer) readEnd() (Token, error) {
+	for {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return Token{}, err
+		}
+		if !ok {
+			return Token{}, io.EOF
+		}
+
+		if isSpace(b) {
+			continue
+		} else if b == '#' {
+			comment, err := t.readComment(nil)
+			if err != nil || t.comments {
+				return comment, err
+			}
+			continue
+		}
+
+		return Token{}, errors.New("unexpected data after the root map")
+	}
+}
+
+// unescapeByte returns the byte that an escaped byte stands for. The escapes \n and \r stand
+// for a newline and a carriage return so that a document can be written on a single line, and
+// any other escaped byte stands for itself.
+func unescapeByte(b byte) byte {
+	switch b {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	}
+
+	return b
+}
+
+// isSpace reports whether b is insignificant whitespace outside of keys and values.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// readComment reads the rest of a comment whose '#' has just been consumed, along with the
+// line break that ends it. A comment after the root map may also end the input.
+func (t *Tokenizer) readComment(path []string) (Token, error) {
+	tok := Token{Kind: Comment, Offset: t.offset - 1}
+	text := strings.Builder{}
+	for {
+		b, ok, err := t.readByte()
+		if err != nil {
+			return tok, err
+		}
+		if !ok && t.started && len(t.stack) == 0 {
+			tok.Raw = "#" + text.String()
+			tok.Value = strings.TrimSuffix(text.String(), "\r")
+			tok.End = t.offset
+			return tok, nil
+		}
+		if !ok {
+			return tok, fmt.Errorf("%v: unexpected end of input", strings.Join(path, "."))
+		}
+
+		if b == '\n' {
+			tok.Raw = "#" + text.String()
+			tok.Value = strings.TrimSuffix(text.String(), "\r")
+			tok.End = t.offset - 1
+			return tok, nil
+		}
+
+		text.WriteByte(b)
+	}
+}
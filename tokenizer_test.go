@@ -0,0 +1,269 @@
+package cereal
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTokenizer(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{a:i1,b\\::[\"x\\,y,{}],c:{d:b1}}"))
+
+	expected := []Token{
+		{Kind: MapStart, Type: Map, Raw: "{", Offset: 1, End: 2},
+		{Kind: Key, Value: "a", Raw: "a", Offset: 2, End: 3},
+		{Kind: Scalar, Type: Int, Value: "1", Raw: "i1", Offset: 4, End: 6},
+		{Kind: Key, Value: "b:", Raw: "b\\:", Offset: 7, End: 10},
+		{Kind: ArrayStart, Type: Array, Raw: "[", Offset: 11, End: 12},
+		{Kind: Scalar, Type: String, Value: "x,y", Raw: "\"x\\,y", Offset: 12, End: 17},
+		{Kind: MapStart, Type: Map, Raw: "{", Offset: 18, End: 19},
+		{Kind: MapEnd, Type: Map, Raw: "}", Offset: 19, End: 20},
+		{Kind: ArrayEnd, Type: Array, Raw: "]", Offset: 20, End: 21},
+		{Kind: Key, Value: "c", Raw: "c", Offset: 22, End: 23},
+		{Kind: MapStart, Type: Map, Raw: "{", Offset: 24, End: 25},
+		{Kind: Key, Value: "d", Raw: "d", Offset: 25, End: 26},
+		{Kind: Scalar, Type: Bool, Value: "1", Raw: "b1", Offset: 27, End: 29},
+		{Kind: MapEnd, Type: Map, Raw: "}", Offset: 29, End: 30},
+		{Kind: MapEnd, Type: Map, Raw: "}", Offset: 30, End: 31},
+	}
+
+	for i, e := range expected {
+		tok, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tok != e {
+			t.Errorf("expected token %v to be %+v but got %+v", i, e, tok)
+		}
+	}
+
+	_, err := tokenizer.Next()
+	if err != io.EOF {
+		t.Error("expected io.EOF but got", err)
+	}
+}
+
+func TestTokenizer_Version(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{}"))
+	if tokenizer.Version() != 0 {
+		t.Error("expected version to be unknown before the first token")
+	}
+
+	_, err := tokenizer.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tokenizer.Version() != '1' {
+		t.Error("expected version to be '1' but got", tokenizer.Version())
+	}
+}
+
+func TestTokenizer_BadVersion(t *testing.T) {
+	_, err := NewTokenizer(strings.NewReader("2{}")).Next()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	msg := err.Error()
+	if msg != "unexpected version '50'" {
+		t.Error("expected error to be \"unexpected version '50'\" but got", msg)
+	}
+}
+
+func TestTokenizer_Path(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{a:[i1,{b:\"x}]}"))
+
+	expected := []string{
+		"<root>",
+		"<root>.a",
+		"<root>.a",
+		"<root>.a.0",
+		"<root>.a.1",
+		"<root>.a.1.b",
+		"<root>.a.1.b",
+		"<root>.a.1",
+		"<root>.a",
+		"<root>",
+	}
+
+	for i, e := range expected {
+		_, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := strings.Join(tokenizer.Path(), ".")
+		if path != e {
+			t.Errorf("expected path of token %v to be '%v' but got '%v'", i, e, path)
+		}
+	}
+}
+
+func TestTokenizer_Skip(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{a:{b:[i1,{c:i2}]},d:i3}"))
+
+	for range 3 {
+		_, err := tokenizer.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := tokenizer.Skip()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := tokenizer.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tok.Kind != Key || tok.Value != "d" {
+		t.Errorf("expected key 'd' but got %+v", tok)
+	}
+	if tokenizer.Depth() != 1 {
+		t.Error("expected depth to be 1 but got", tokenizer.Depth())
+	}
+}
+
+func TestTokenizer_UnexpectedEnd(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{a:[i1"))
+
+	var err error
+	for err == nil {
+		_, err = tokenizer.Next()
+	}
+
+	msg := err.Error()
+	if msg != "<root>.a: unexpected end of input" {
+		t.Error("expected error to be '<root>.a: unexpected end of input' but got", msg)
+	}
+}
+
+func TestTokenizer_InvalidTypeMarker(t *testing.T) {
+	tokenizer := NewTokenizer(strings.NewReader("1{a:[i1,X]}"))
+
+	var err error
+	for err == nil {
+		_, err = tokenizer.Next()
+	}
+
+	msg := err.Error()
+	if msg != "<root>.a.1: invalid type marker 'X'" {
+		t.Error("expected error to be \"<root>.a.1: invalid type marker 'X'\" but got", msg)
+	}
+}
+
+func TestTokenizer_Comments(t *testing.T) {
+	input := "1{# first\n a:i1, # second\r\n b:[ # third\n i2]}"
+
+	m, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != 1 {
+		t.Error("expected 'a' to be 1 but got", m["a"])
+	}
+
+	tokenizer := NewTokenizer(strings.NewReader(input))
+	tokenizer.EmitComments()
+
+	var comments []Token
+	for {
+		tok, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tok.Kind == Comment {
+			comments = append(comments, tok)
+		}
+	}
+
+	expected := []Token{
+		{Kind: Comment, Value: " first", Raw: "# first", Offset: 2, End: 9},
+		{Kind: Comment, Value: " second", Raw: "# second\r", Offset: 17, End: 26},
+		{Kind: Comment, Value: " third", Raw: "# third", Offset: 32, End: 39},
+	}
+	if len(comments) != len(expected) {
+		t.Fatal("expected 3 comments but got", comments)
+	}
+	for i, e := range expected {
+		if comments[i] != e {
+			t.Errorf("expected comment %v to be %+v but got %+v", i, e, comments[i])
+		}
+	}
+}
+
+func TestTokenizer_CommentsAroundRoot(t *testing.T) {
+	input := "1 # header\n{a:i1}\n# end"
+
+	m, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != 1 {
+		t.Error("expected 'a' to be 1 but got", m["a"])
+	}
+
+	tokenizer := NewTokenizer(strings.NewReader(input))
+	tokenizer.EmitComments()
+	tokenizer.ReadToEnd()
+
+	var comments []Token
+	for {
+		tok, err := tokenizer.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tok.Kind == Comment {
+			comments = append(comments, tok)
+		}
+	}
+
+	expected := []Token{
+		{Kind: Comment, Value: " header", Raw: "# header", Offset: 2, End: 10},
+		{Kind: Comment, Value: " end", Raw: "# end", Offset: 18, End: 23},
+	}
+	if len(comments) != len(expected) {
+		t.Fatal("expected 2 comments but got", comments)
+	}
+	for i, e := range expected {
+		if comments[i] != e {
+			t.Errorf("expected comment %v to be %+v but got %+v", i, e, comments[i])
+		}
+	}
+}
+
+func TestTokenizer_CommentInValue(t *testing.T) {
+	m, err := Parse(strings.NewReader("1{a:\"x # y,\\#b:i1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m["a"] != "x # y" || m["#b"] != 1 {
+		t.Error("expected '#' to be kept in values and escaped keys but got", m)
+	}
+}
+
+func TestTokenizer_UnterminatedComment(t *testing.T) {
+	_, err := Parse(strings.NewReader("1{a:i1,# comment"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	msg := err.Error()
+	if msg != "<root>: unexpected end of input" {
+		t.Error("expected error to be '<root>: unexpected end of input' but got", msg)
+	}
+}
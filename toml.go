@@ -0,0 +1,856 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ConvertTOML reads a TOML document from the provided io.Reader and returns it as a
+// serialized document. Integers become int values, or are tagged as in {$int64:"5000000000}
+// outside the 32 bit range of an int, and floats become float64 values. Since cereal has no type for dates and
+// times, an offset date-time becomes a string in RFC 3339 format, and a local date-time, date
+// or time becomes a string in the same form it has in TOML. Tables and arrays of tables keep
+// the order their keys are first defined in.
+func ConvertTOML(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &tomlParser{
+		s:           strings.ReplaceAll(string(data), "\r\n", "\n"),
+		line:        1,
+		root:        NewOrderedMap(),
+		tables:      map[*OrderedMap]tomlTableKind{},
+		arrayTables: map[tomlSlot]bool{},
+	}
+	p.current = p.root
+
+	err = p.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	return serializeParsed(p.root)
+}
+
+// tomlTableKind records how a table was defined, which decides whether it may be extended.
+type tomlTableKind int
+
+const (
+	// tomlImplicit is a table created as the parent of another table.
+	tomlImplicit tomlTableKind = iota
+	// tomlHeader is a table defined by a [header].
+	tomlHeader
+	// tomlDotted is a table defined by a dotted key.
+	tomlDotted
+	// tomlInline is an inline table, which cannot be extended.
+	tomlInline
+)
+
+// tomlSlot is a key of a table, used to know which arrays are arrays of tables.
+type tomlSlot struct {
+	table *OrderedMap
+	key   string
+}
+
+type tomlParser struct {
+	s    string
+	i    int
+	line int
+
+	root    *OrderedMap
+	current *OrderedMap
+
+	tables      map[*OrderedMap]tomlTableKind
+	arrayTables map[tomlSlot]bool
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %v: %v", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return nil
+		}
+
+		var err error
+		switch p.s[p.i] {
+		case '#', '\n':
+		case '[':
+			err = p.parseHeader()
+		default:
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = p.endLine()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p *tomlParser) skipSpace() {
+	for p.i < len(p.s) && (p.s[p.i] == ' ' || p.s[p.i] == '\t') {
+		p.i++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments, as allowed inside an array.
+func (p *tomlParser) skipBlank() {
+	for p.i < len(p.s) {
+		switch p.s[p.i] {
+		case ' ', '\t':
+			p.i++
+		case '\n':
+			p.i++
+			p.line++
+		case '#':
+			for p.i < len(p.s) && p.s[p.i] != '\n' {
+				p.i++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine makes sure nothing but a comment follows on the current line and moves past it.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '#' {
+		for p.i < len(p.s) && p.s[p.i] != '\n' {
+			p.i++
+		}
+	}
+	if p.i >= len(p.s) {
+		return nil
+	}
+	if p.s[p.i] != '\n' {
+		return p.errorf("unexpected '%v'", p.rest())
+	}
+
+	p.i++
+	p.line++
+	return nil
+}
+
+// rest returns what remains of the current line, for error messages.
+func (p *tomlParser) rest() string {
+	end := strings.IndexByte(p.s[p.i:], '\n')
+	if end < 0 {
+		return p.s[p.i:]
+	}
+	return p.s[p.i : p.i+end]
+}
+
+func (p *tomlParser) parseHeader() error {
+	array := strings.HasPrefix(p.s[p.i:], "[[")
+	if array {
+		p.i += 2
+	} else {
+		p.i++
+	}
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.s[p.i:], closing) {
+		return p.errorf("expected '%v' after the table name", closing)
+	}
+	p.i += len(closing)
+
+	table := p.root
+	for _, key := range keys[:len(keys)-1] {
+		table, err = p.childTable(table, key, tomlImplicit, keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	key := keys[len(keys)-1]
+	existing, exists := table.Get(key)
+	if array {
+		slot := tomlSlot{table, key}
+		if exists && !p.arrayTables[slot] {
+			return p.errorf("'%v' is already defined", strings.Join(keys, "."))
+		}
+
+		elements, _ := existing.([]any)
+		p.current = NewOrderedMap()
+		p.tables[p.current] = tomlHeader
+		table.Set(key, append(elements, p.current))
+		p.arrayTables[slot] = true
+		return nil
+	}
+
+	if !exists {
+		p.current = NewOrderedMap()
+		p.tables[p.current] = tomlHeader
+		table.Set(key, p.current)
+		return nil
+	}
+
+	m, ok := existing.(*OrderedMap)
+	if !ok || p.tables[m] != tomlImplicit {
+		return p.errorf("table '%v' is already defined", strings.Join(keys, "."))
+	}
+	p.tables[m] = tomlHeader
+	p.current = m
+	return nil
+}
+
+// childTable returns the table at the key, creating it with the given kind if it is missing.
+// The last table of an array of tables stands for the array.
+func (p *tomlParser) childTable(table *OrderedMap, key string, kind tomlTableKind, keys []string) (*OrderedMap, error) {
+	existing, exists := table.Get(key)
+	if !exists {
+		m := NewOrderedMap()
+		p.tables[m] = kind
+		table.Set(key, m)
+		return m, nil
+	}
+
+	switch v := existing.(type) {
+	case *OrderedMap:
+		existingKind := p.tables[v]
+		if existingKind == tomlInline || (kind == tomlDotted && existingKind != tomlDotted) {
+			return nil, p.errorf("table '%v' cannot be extended", strings.Join(keys, "."))
+		}
+		return v, nil
+	case []any:
+		if p.arrayTables[tomlSlot{table, key}] {
+			return v[len(v)-1].(*OrderedMap), nil
+		}
+	}
+
+	return nil, p.errorf("'%v' is already defined as a value", strings.Join(keys, "."))
+}
+
+func (p *tomlParser) parseKeyValue(table *OrderedMap) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	if p.i >= len(p.s) || p.s[p.i] != '=' {
+		return p.errorf("expected '=' after the key '%v'", strings.Join(keys, "."))
+	}
+	p.i++
+	p.skipSpace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys[:len(keys)-1] {
+		table, err = p.childTable(table, key, tomlDotted, keys)
+		if err != nil {
+			return err
+		}
+	}
+
+	key := keys[len(keys)-1]
+	if _, exists := table.Get(key); exists {
+		return p.errorf("duplicate key '%v'", strings.Join(keys, "."))
+	}
+	table.Set(key, value)
+
+	return nil
+}
+
+// parseKey reads a key, which may be dotted, along with the whitespace that follows it.
+func (p *tomlParser) parseKey() ([]string, error) {
+	keys := []string{}
+	for {
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("expected a key")
+		}
+
+		switch p.s[p.i] {
+		case '"', '\'':
+			if strings.HasPrefix(p.s[p.i:], `"""`) || strings.HasPrefix(p.s[p.i:], "'''") {
+				return nil, p.errorf("a key cannot be a multi-line string")
+			}
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		default:
+			start := p.i
+			for p.i < len(p.s) && isBareTOMLKeyByte(p.s[p.i]) {
+				p.i++
+			}
+			if p.i == start {
+				return nil, p.errorf("invalid key '%v'", p.rest())
+			}
+			keys = append(keys, p.s[start:p.i])
+		}
+
+		p.skipSpace()
+		if p.i >= len(p.s) || p.s[p.i] != '.' {
+			return keys, nil
+		}
+		p.i++
+	}
+}
+
+func isBareTOMLKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	if p.i >= len(p.s) {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.s[p.i] {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+
+	start := p.i
+	for p.i < len(p.s) && (isBareTOMLKeyByte(p.s[p.i]) || strings.IndexByte("+.:", p.s[p.i]) >= 0) {
+		p.i++
+	}
+	// a date and a time may be separated by a space
+	if tomlDatePattern.MatchString(p.s[start:p.i]) && p.i+3 < len(p.s) && p.s[p.i] == ' ' && isDigit(p.s[p.i+1]) && isDigit(p.s[p.i+2]) && p.s[p.i+3] == ':' {
+		p.i++
+		for p.i < len(p.s) && (isBareTOMLKeyByte(p.s[p.i]) || strings.IndexByte("+.:", p.s[p.i]) >= 0) {
+			p.i++
+		}
+	}
+
+	text := p.s[start:p.i]
+	switch text {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	case "":
+		if strings.TrimSpace(p.rest()) == "" {
+			return nil, p.errorf("expected a value")
+		}
+		return nil, p.errorf("invalid value '%v'", p.rest())
+	}
+
+	if value, ok := parseTOMLNumber(text); ok {
+		return value, nil
+	}
+	if value, ok := parseTOMLDateTime(text); ok {
+		return value, nil
+	}
+
+	return nil, p.errorf("invalid value '%v'", text)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *tomlParser) parseArray() (any, error) {
+	p.i++
+	result := []any{}
+	for {
+		p.skipBlank()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.s[p.i] == ']' {
+			p.i++
+			return result, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+
+		p.skipBlank()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' but got '%c'", p.s[p.i])
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (any, error) {
+	p.i++
+	table := NewOrderedMap()
+	p.tables[table] = tomlInline
+
+	p.skipSpace()
+	if p.i < len(p.s) && p.s[p.i] == '}' {
+		p.i++
+		return table, nil
+	}
+
+	for {
+		err := p.parseKeyValue(table)
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if p.i >= len(p.s) {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.s[p.i] {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			p.freeze(table)
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' but got '%c'", p.s[p.i])
+		}
+	}
+}
+
+// freeze marks the tables that dotted keys created inside an inline table as inline, so
+// that they cannot be extended either.
+func (p *tomlParser) freeze(table *OrderedMap) {
+	p.tables[table] = tomlInline
+	for _, value := range table.All() {
+		if m, ok := value.(*OrderedMap); ok {
+			p.freeze(m)
+		}
+	}
+}
+
+// tomlEscapes maps the escapes of basic strings to the characters they stand for.
+var tomlEscapes = map[byte]string{
+	'b': "\b", 't': "\t", 'n': "\n", 'f': "\f", 'r': "\r", '"': "\"", '\\': "\\",
+}
+
+// parseString reads a basic or literal string, either of which may be multi-line.
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.s[p.i]
+	multiline := strings.HasPrefix(p.s[p.i:], strings.Repeat(string(quote), 3))
+	if multiline {
+		p.i += 3
+		// a newline right after the opening quotes is trimmed
+		if p.i < len(p.s) && p.s[p.i] == '\n' {
+			p.i++
+			p.line++
+		}
+	} else {
+		p.i++
+	}
+
+	b := strings.Builder{}
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch {
+		case c == quote && !multiline:
+			p.i++
+			return b.String(), nil
+		case c == quote && strings.HasPrefix(p.s[p.i:], strings.Repeat(string(quote), 3)):
+			// up to two quotes may come right before the closing ones
+			end := p.i + 3
+			for end < len(p.s) && p.s[end] == quote && end-p.i < 5 {
+				end++
+			}
+			b.WriteString(p.s[p.i : end-3])
+			p.i = end
+			return b.String(), nil
+		case c == '\n':
+			if !multiline {
+				return "", p.errorf("unterminated string")
+			}
+			b.WriteByte(c)
+			p.i++
+			p.line++
+		case c == '\\' && quote == '"':
+			err := p.parseEscape(&b, multiline)
+			if err != nil {
+				return "", err
+			}
+		case c < ' ' && c != '\t' || c == 0x7f:
+			return "", p.errorf("control characters must be escaped in strings")
+		default:
+			b.WriteByte(c)
+			p.i++
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder, multiline bool) error {
+	p.i++
+	if p.i >= len(p.s) {
+		return p.errorf("unterminated string")
+	}
+
+	e := p.s[p.i]
+	if s, ok := tomlEscapes[e]; ok {
+		b.WriteString(s)
+		p.i++
+		return nil
+	}
+
+	if multiline && (e == ' ' || e == '\t' || e == '\n') {
+		// a backslash at the end of a line trims the whitespace that follows it
+		rest := strings.TrimLeft(p.s[p.i:], " \t")
+		if !strings.HasPrefix(rest, "\n") {
+			return p.errorf("invalid escape '\\%c'", e)
+		}
+		for p.i < len(p.s) && strings.IndexByte(" \t\n", p.s[p.i]) >= 0 {
+			if p.s[p.i] == '\n' {
+				p.line++
+			}
+			p.i++
+		}
+		return nil
+	}
+
+	size := map[byte]int{'u': 4, 'U': 8}[e]
+	if size == 0 || p.i+1+size > len(p.s) {
+		return p.errorf("invalid escape '\\%c'", e)
+	}
+	r, err := strconv.ParseUint(p.s[p.i+1:p.i+1+size], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return p.errorf("invalid escape '\\%c%v'", e, p.s[p.i+1:p.i+1+size])
+	}
+	b.WriteRune(rune(r))
+	p.i += 1 + size
+	return nil
+}
+
+var (
+	tomlIntPattern      = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)$`)
+	tomlPrefixedPattern = regexp.MustCompile(`^0(x[0-9a-fA-F](_?[0-9a-fA-F])*|o[0-7](_?[0-7])*|b[01](_?[01])*)$`)
+	tomlFloatPattern    = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+	tomlDatePattern     = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	tomlTimePattern     = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?$`)
+	tomlDateTimePattern = regexp.MustCompile(`^([0-9]{4}-[0-9]{2}-[0-9]{2})[Tt ]([0-9]{2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]+)?)([Zz]|[-+][0-9]{2}:[0-9]{2})?$`)
+)
+
+func parseTOMLNumber(text string) (any, bool) {
+	var i int64
+	var err error
+	switch {
+	case tomlIntPattern.MatchString(text):
+		i, err = strconv.ParseInt(strings.ReplaceAll(text, "_", ""), 10, 64)
+	case tomlPrefixedPattern.MatchString(text):
+		// the prefix is understood by ParseInt with a base of 0
+		i, err = strconv.ParseInt(strings.ReplaceAll(text, "_", ""), 0, 64)
+	case tomlFloatPattern.MatchString(text):
+		f, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+		return f, err == nil
+	default:
+		return nil, false
+	}
+
+	if err != nil {
+		return nil, false
+	}
+	return wideInt(i), true
+}
+
+// parseTOMLDateTime returns an offset date-time in RFC 3339 format, and any local date-time,
+// date or time in the form it has in TOML, with a T between the date and the time.
+func parseTOMLDateTime(text string) (string, bool) {
+	if tomlDatePattern.MatchString(text) {
+		_, err := time.Parse(time.DateOnly, text)
+		return text, err == nil
+	}
+	if tomlTimePattern.MatchString(text) {
+		_, err := time.Parse("15:04:05.999999999", text)
+		return text, err == nil
+	}
+
+	m := tomlDateTimePattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+
+	local := m[1] + "T" + m[2]
+	if m[3] == "" {
+		_, err := time.Parse("2006-01-02T15:04:05.999999999", local)
+		return local, err == nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, local+strings.ToUpper(m[3]))
+	if err != nil {
+		return "", false
+	}
+	return t.Format(time.RFC3339Nano), true
+}
+
+// MarshalTOML returns a document, such as the result of ParseOrdered, as a TOML document.
+// Maps become tables, and arrays whose elements are all maps become arrays of tables. Since
+// the values of a table come before its tables, keys may change order. Floats
+// always have a fraction or an exponent, so ConvertTOML reads the result back with the same
+// types, except that float32 values are read back as float64 values. Integers tagged as
+// ConvertTOML does are written as integers. TOML has no null, so a document with a null
+// cannot be written.
+func MarshalTOML(doc any) ([]byte, error) {
+	valueType, _ := valueTypeOf(doc)
+	if valueType != Map {
+		return nil, fmt.Errorf("<root>: expected a map")
+	}
+
+	buf := bytes.Buffer{}
+	err := writeTOMLTable(&buf, doc, nil, []string{"<root>"})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeTOMLTable writes the values of a table, followed by its tables and arrays of tables
+// under headers named by keys.
+func writeTOMLTable(buf *bytes.Buffer, table any, keys []string, path []string) error {
+	children := queryChildren(table)
+
+	for _, c := range children {
+		if isTOMLTable(c.value) || isTOMLArrayOfTables(c.value) {
+			continue
+		}
+
+		buf.WriteString(quoteTOMLKey(c.key))
+		buf.WriteString(" = ")
+		err := writeTOMLValue(buf, c.value, appendPath(path, c.key))
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+	}
+
+	for _, c := range children {
+		childKeys := append(keys[:len(keys):len(keys)], quoteTOMLKey(c.key))
+		childPath := appendPath(path, c.key)
+
+		switch {
+		case isTOMLTable(c.value):
+			if !onlyTOMLTables(c.value) {
+				writeTOMLHeader(buf, "["+strings.Join(childKeys, ".")+"]")
+			}
+			err := writeTOMLTable(buf, c.value, childKeys, childPath)
+			if err != nil {
+				return err
+			}
+		case isTOMLArrayOfTables(c.value):
+			for _, elem := range queryChildren(c.value) {
+				writeTOMLHeader(buf, "[["+strings.Join(childKeys, ".")+"]]")
+				err := writeTOMLTable(buf, elem.value, childKeys, appendPath(childPath, elem.key))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeTOMLHeader(buf *bytes.Buffer, header string) {
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(header)
+	buf.WriteByte('\n')
+}
+
+func isTOMLTable(value any) bool {
+	valueType, _ := valueTypeOf(value)
+	if _, ok := taggedInt64(value); ok {
+		return false
+	}
+	return valueType == Map
+}
+
+// onlyTOMLTables reports whether a map has tables or arrays of tables and nothing else, so
+// that it needs no header of its own.
+func onlyTOMLTables(value any) bool {
+	children := queryChildren(value)
+	for _, c := range children {
+		if !isTOMLTable(c.value) && !isTOMLArrayOfTables(c.value) {
+			return false
+		}
+	}
+	return len(children) > 0
+}
+
+// isTOMLArrayOfTables reports whether a value is an array of one or more maps.
+func isTOMLArrayOfTables(value any) bool {
+	valueType, _ := valueTypeOf(value)
+	if valueType != Array {
+		return false
+	}
+
+	children := queryChildren(value)
+	for _, c := range children {
+		if !isTOMLTable(c.value) {
+			return false
+		}
+	}
+	return len(children) > 0
+}
+
+// writeTOMLValue writes a value on a single line, with maps as inline tables.
+func writeTOMLValue(buf *bytes.Buffer, value any, path []string) error {
+	valueType, ok := valueTypeOf(value)
+	if !ok {
+		return fmt.Errorf("%v: unsupported value of type %T", strings.Join(path, "."), value)
+	}
+	if _, isRaw := value.(RawValue); isRaw && valueType != Null {
+		return fmt.Errorf("%v: unsupported value %v", strings.Join(path, "."), value)
+	}
+
+	if i, ok := taggedInt64(value); ok {
+		buf.WriteString(strconv.FormatInt(i, 10))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	switch valueType {
+	case Null:
+		return fmt.Errorf("%v: TOML cannot represent a null", strings.Join(path, "."))
+	case Bool:
+		buf.WriteString(strconv.FormatBool(rv.Bool()))
+	case Int:
+		buf.WriteString(strconv.FormatInt(rv.Int(), 10))
+	case Float32:
+		buf.WriteString(formatTOMLFloat(rv.Float(), 32))
+	case Float64:
+		buf.WriteString(formatTOMLFloat(rv.Float(), 64))
+	case String:
+		buf.WriteString(quoteTOMLString(rv.String()))
+	case Map, Array:
+		open, close := "[", "]"
+		if valueType == Map {
+			open, close = "{", "}"
+		}
+
+		buf.WriteString(open)
+		for i, c := range queryChildren(value) {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if valueType == Map {
+				buf.WriteString(quoteTOMLKey(c.key))
+				buf.WriteString(" = ")
+			}
+
+			err := writeTOMLValue(buf, c.value, appendPath(path, c.key))
+			if err != nil {
+				return err
+			}
+		}
+		buf.WriteString(close)
+	}
+
+	return nil
+}
+
+func formatTOMLFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// quoteTOMLKey returns a key as a bare key if it can be one, and as a basic string otherwise.
+func quoteTOMLKey(key string) string {
+	for i := 0; i < len(key); i++ {
+		if !isBareTOMLKeyByte(key[i]) {
+			return quoteTOMLString(key)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
+
+// quoteTOMLString returns a string as a basic string, escaping quotes, backslashes and
+// control characters.
+func quoteTOMLString(s string) string {
+	b := strings.Builder{}
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
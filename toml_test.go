@@ -0,0 +1,152 @@
+package cereal
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConvertTOML(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\nb = 2.5\nc = \"x\"\nd = true # comment\n":                  `1{a:i1,b:d2.5,c:"x,d:b1}`,
+		"hex = 0xDEAD\noct = 0o17\nbin = 0b101\nbig = 1_000_000_000_000\n": `1{hex:i57005,oct:i15,bin:i5,big:{$int64:"1000000000000}}`,
+		"big = 9223372036854775807\nmin = -9223372036854775808\n":          `1{big:{$int64:"9223372036854775807},min:{$int64:"-9223372036854775808}}`,
+		"inf = -inf\nexp = 6.626e-34\nneg = -0.5\n":                        `1{inf:d-Inf,exp:d6.626e-34,neg:d-0.5}`,
+		"a.b.c = 1\na.d = 2\n\"quoted key\" = 'lit\\n'\n":                  `1{a:{b:{c:i1},d:i2},quoted key:"lit\\n}`,
+		"[server]\nhost = \"x\"\n\n[server.tls]\non = true\n":              `1{server:{host:"x,tls:{on:b1}}}`,
+		"arr = [\n  1, # one\n  \"two\",\n  [3],\n  {four = 4},\n]\n":      `1{arr:[i1,"two,[i3],{four:i4}]}`,
+		"ml = \"\"\"\nRoses \\\n   are red\"\"\"\nraw = '''\nno \\n'''\n":  `1{ml:"Roses are red,raw:"no \\n}`,
+		"[[p]]\nname = \"a\"\n[p.x]\ny = 1\n[[p.v]]\nz = 2\n[[p]]\n":       `1{p:[{name:"a,x:{y:i1},v:[{z:i2}]},{}]}`,
+		"": `1{}`,
+	}
+
+	for input, expected := range tests {
+		result, err := ConvertTOML(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", input, err)
+			continue
+		}
+		if string(result) != expected {
+			t.Errorf("expected %v for %q but got %v", expected, input, string(result))
+		}
+	}
+}
+
+func TestConvertTOML_DateTimes(t *testing.T) {
+	input := `
+offset = 1979-05-27T07:32:00.5-08:00
+utc = 1979-05-27 07:32:00z
+local = 1979-05-27 07:32:00.999
+date = 1979-05-27
+time = 07:32:00
+`
+
+	result, err := ConvertTOML(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `1{offset:"1979-05-27T07:32:00.5-08:00,utc:"1979-05-27T07:32:00Z,local:"1979-05-27T07:32:00.999,date:"1979-05-27,time:"07:32:00}`
+	if string(result) != expected {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
+
+func TestConvertTOML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"a = 1\na = 2\n":       "line 2: duplicate key 'a'",
+		"[a]\n[a]\n":           "line 2: table 'a' is already defined",
+		"a = {x = 1}\n[a]\n":   "line 2: table 'a' is already defined",
+		"a = {x = 1}\n[a.b]\n": "line 2: table 'a.b' cannot be extended",
+		"a = 1\n[[a]]\n":       "line 2: 'a' is already defined",
+		"a = \n":               "line 1: expected a value",
+		"a = 0x_1\n":           "line 1: invalid value '0x_1'",
+		"a = \"open\n":         "line 1: unterminated string",
+		"a = 1 b = 2\n":        "line 1: unexpected 'b = 2'",
+		"a = [1,\n2\n":         "line 3: unterminated array",
+		"a = 1979-13-01\n":     "line 1: invalid value '1979-13-01'",
+		"a = \"\\q\"\n":        "line 1: invalid escape '\\q'",
+	}
+
+	for input, expected := range tests {
+		_, err := ConvertTOML(strings.NewReader(input))
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected error %v for %q but got %v", expected, input, err)
+		}
+	}
+}
+
+func TestMarshalTOML(t *testing.T) {
+	doc := NewOrderedMap()
+	doc.Set("title", "a \"quoted\"\ttitle")
+	doc.Set("server", map[string]any{"host": "x", "port": 80, "tls": map[string]any{"on": true}})
+	doc.Set("ratio", 2.0)
+	doc.Set("mixed", []any{1, "two", map[string]any{"three": float32(3)}})
+	doc.Set("parent", map[string]any{"child": map[string]any{"a": 1}})
+	doc.Set("items", []any{map[string]any{"a": 1}, map[string]any{}})
+	doc.Set("odd key", math.Inf(-1))
+
+	result, err := MarshalTOML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `title = "a \"quoted\"\ttitle"
+ratio = 2.0
+mixed = [1, "two", {three = 3.0}]
+"odd key" = -inf
+
+[server]
+host = "x"
+port = 80
+
+[server.tls]
+on = true
+
+[parent.child]
+a = 1
+
+[[items]]
+a = 1
+
+[[items]]
+`
+	if string(result) != expected {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
+
+func TestMarshalTOML_Errors(t *testing.T) {
+	_, err := MarshalTOML(map[string]any{"a": []any{1, nil}})
+	if err == nil || err.Error() != "<root>.a.1: TOML cannot represent a null" {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = MarshalTOML([]any{1})
+	if err == nil || err.Error() != "<root>: expected a map" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMarshalTOML_RoundTrip(t *testing.T) {
+	input := `1{a:i1,b:d2.5,c:"x\ny,l:{$int64:"9223372036854775807},d:[b1,[],{e:"f}],g:{h:{i:[{j:i1},{k:d-0.5}]}}}`
+
+	doc, err := ParseOrdered(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalTOML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ConvertTOML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error for %v: %v", string(data), err)
+	}
+	if string(result) != input {
+		t.Errorf("unexpected result %v from %v", string(result), string(data))
+	}
+}
@@ -0,0 +1,237 @@
+package cereal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Node is a value in the syntax tree of a document. Nodes keep the order of map entries, the
+// raw text of keys and scalars, and the text found between tokens so that a tree can be
+// printed back to exactly the input it was parsed from.
+type Node struct {
+	Type ValueType
+
+	// Key is the unescaped key of a map entry and RawKey is the key as written.
+	Key    string
+	RawKey string
+
+	// Value is the unescaped text of a scalar and Raw is the scalar as written, including its
+	// type marker.
+	Value string
+	Raw   string
+
+	// Children holds the entries of a map or the elements of an array, in document order.
+	Children []*Node
+
+	// Leading is the text between the previous token and the start of this node, such as a
+	// separating comma, whitespace and comments.
+	Leading string
+	// Separator is the text between the key of a map entry and its value.
+	Separator string
+	// Trailing is the text between the last child of a map or array and its closer.
+	Trailing string
+	// Tail is the text after the closer of the root map, such as a final line break.
+	Tail string
+
+	// KeyStart and KeyEnd locate the key of a map entry in the input.
+	KeyStart int64
+	KeyEnd   int64
+	// Start and End locate the value in the input, from its type marker or opening bracket to
+	// just past its last byte.
+	Start int64
+	End   int64
+}
+
+// ParseTree reads a document from the provided io.Reader and returns its syntax tree. The
+// root of the tree is always a map.
+func ParseTree(reader io.Reader) (*Node, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTokenizer(bytes.NewReader(data))
+	tok, err := t.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	root := &Node{
+		Type:    Map,
+		Leading: string(data[1:tok.Offset]),
+		Start:   tok.Offset,
+	}
+
+	last := tok.End
+	err = buildTreeV1(t, data, root, &last)
+	if err != nil {
+		return nil, err
+	}
+
+	// the text after the root map may only hold whitespace and comments
+	t.ReadToEnd()
+	for err == nil {
+		_, err = t.Next()
+	}
+	if err != io.EOF {
+		return nil, err
+	}
+
+	root.Tail = string(data[last:])
+	return root, nil
+}
+
+// buildTreeV1 adds the children of the map or array n to the tree, up to and including its
+// closer. The last argument tracks the end of the most recent token so that the text between
+// tokens can be kept.
+func buildTreeV1(t *Tokenizer, data []byte, n *Node, last *int64) error {
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return err
+		}
+
+		if tok.Kind == MapEnd || tok.Kind == ArrayEnd {
+			n.Trailing = string(data[*last:tok.Offset])
+			n.End = tok.End
+			*last = tok.End
+			return nil
+		}
+
+		child := &Node{Leading: string(data[*last:tok.Offset])}
+		if tok.Kind == Key {
+			child.Key = tok.Value
+			child.RawKey = tok.Raw
+			child.KeyStart = tok.Offset
+			child.KeyEnd = tok.End
+			*last = tok.End
+
+			tok, err = t.Next()
+			if err != nil {
+				return err
+			}
+
+			child.Separator = string(data[*last:tok.Offset])
+		}
+
+		child.Type = tok.Type
+		child.Start = tok.Offset
+		*last = tok.End
+		n.Children = append(n.Children, child)
+
+		if tok.Kind == Scalar {
+			child.Value = tok.Value
+			child.Raw = tok.Raw
+			child.End = tok.End
+			continue
+		}
+
+		err = buildTreeV1(t, data, child, last)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// PrintTree writes the document represented by the tree to the provided io.Writer. A tree
+// returned by ParseTree is printed exactly as it was read. Nodes without raw text, such as
+// those added by hand, are written from their Key and Value.
+func PrintTree(w io.Writer, root *Node) error {
+	buf := bytes.Buffer{}
+	buf.WriteByte('1')
+	buf.WriteString(root.Leading)
+	writeNodeV1(&buf, root)
+	buf.WriteString(root.Tail)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeNodeV1(buf *bytes.Buffer, n *Node) {
+	switch n.Type {
+	case Map:
+		buf.WriteByte('{')
+		writeChildrenV1(buf, n, true)
+		buf.WriteByte('}')
+	case Array:
+		buf.WriteByte('[')
+		writeChildrenV1(buf, n, false)
+		buf.WriteByte(']')
+	default:
+		if n.Raw != "" {
+			buf.WriteString(n.Raw)
+		} else {
+			buf.WriteByte(typeMarkers[n.Type])
+			buf.WriteString(escapeValue(n.Value))
+		}
+	}
+}
+
+func writeChildrenV1(buf *bytes.Buffer, n *Node, isMap bool) {
+	for i, child := range n.Children {
+		// a scalar is only terminated by a comma or a closer
+		if i > 0 && isScalar(n.Children[i-1].Type) && !strings.HasPrefix(child.Leading, ",") {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(child.Leading)
+
+		if isMap {
+			if child.RawKey != "" {
+				buf.WriteString(child.RawKey)
+			} else {
+				buf.WriteString(escapeKey(child.Key))
+			}
+
+			if child.Separator != "" {
+				buf.WriteString(child.Separator)
+			} else {
+				buf.WriteByte(':')
+			}
+		}
+
+		writeNodeV1(buf, child)
+	}
+
+	last := len(n.Children) - 1
+	if last >= 0 && isScalar(n.Children[last].Type) && n.Trailing != "" && !strings.HasPrefix(n.Trailing, ",") {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(n.Trailing)
+}
+
+// Comments returns the text of the comments that precede the node.
+func (n *Node) Comments() []string {
+	return parseComments(n.Leading)
+}
+
+// TrailingComments returns the text of the comments between the last child of a map or array
+// and its closer.
+func (n *Node) TrailingComments() []string {
+	return parseComments(n.Trailing)
+}
+
+// parseComments returns the text of the comments found in the text between two tokens,
+// which holds nothing but separators, whitespace and comments.
+func parseComments(s string) []string {
+	var comments []string
+	for {
+		start := strings.IndexByte(s, '#')
+		if start < 0 {
+			return comments
+		}
+
+		s = s[start+1:]
+		end := strings.IndexByte(s, '\n')
+		if end < 0 {
+			end = len(s)
+		}
+
+		comments = append(comments, strings.TrimSuffix(s[:end], "\r"))
+		s = s[end:]
+	}
+}
+
+func isScalar(valueType ValueType) bool {
+	return valueType != Map && valueType != Array
+}
@@ -0,0 +1,176 @@
+package cereal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseTree(t *testing.T) {
+	root, err := ParseTree(strings.NewReader("1{b:i2,a:[d1.50,{}],c\\::\"x\\,y}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.Type != Map || root.Start != 1 || root.End != 30 {
+		t.Errorf("unexpected root %+v", root)
+	}
+	if len(root.Children) != 3 {
+		t.Fatal("expected 3 children but got", len(root.Children))
+	}
+
+	b := root.Children[0]
+	if b.Key != "b" || b.Type != Int || b.Value != "2" || b.Raw != "i2" || b.KeyStart != 2 || b.KeyEnd != 3 || b.Start != 4 || b.End != 6 {
+		t.Errorf("unexpected node %+v", b)
+	}
+
+	a := root.Children[1]
+	if a.Key != "a" || a.Type != Array || a.Leading != "," || len(a.Children) != 2 {
+		t.Errorf("unexpected node %+v", a)
+	}
+	if a.Children[0].Raw != "d1.50" {
+		t.Error("expected raw number text to be kept but got", a.Children[0].Raw)
+	}
+
+	c := root.Children[2]
+	if c.Key != "c:" || c.RawKey != "c\\:" || c.Value != "x,y" || c.Raw != "\"x\\,y" {
+		t.Errorf("unexpected node %+v", c)
+	}
+}
+
+func TestParseTree_Error(t *testing.T) {
+	_, err := ParseTree(strings.NewReader("1{a:[i1"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	msg := err.Error()
+	if msg != "<root>.a: unexpected end of input" {
+		t.Error("expected error to be '<root>.a: unexpected end of input' but got", msg)
+	}
+
+	_, err = ParseTree(strings.NewReader("1{a:i1} # end\nGARBAGE"))
+	if err == nil || err.Error() != "unexpected data after the root map" {
+		t.Error("expected error to be 'unexpected data after the root map' but got", err)
+	}
+}
+
+func TestPrintTree_RoundTrip(t *testing.T) {
+	inputs := []string{
+		"1{}",
+		"1{a:i1}",
+		"1{,,a:i1,,b:b0,}",
+		"1{a:{}b:[]}",
+		"1{a:[i1,,i2,],b:[[],[{}]]}",
+		"1{\\}\\::\"\\\"hello\\, world\\\"}",
+		"1{:f1.0e3,x:d-0.00}",
+		"1{a:i1}\n",
+		"1{a:i1} # done\n",
+		"1{a:i1}\n\n# end\r\n# of file",
+		"1 # header\n{a:i1}\n",
+	}
+
+	for _, input := range inputs {
+		root, err := ParseTree(strings.NewReader(input))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		buf := bytes.Buffer{}
+		err = PrintTree(&buf, root)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		if buf.String() != input {
+			t.Errorf("expected '%v' but got '%v'", input, buf.String())
+		}
+	}
+}
+
+func TestPrintTree_NewNodes(t *testing.T) {
+	root, err := ParseTree(strings.NewReader("1{a:i1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root.Children = append(root.Children, &Node{Key: "b:", Type: String, Value: "x,y}"})
+	root.Children = append(root.Children, &Node{Key: "c", Type: Array, Children: []*Node{
+		{Type: Bool, Value: "1"},
+		{Type: String, Value: "]"},
+	}})
+
+	buf := bytes.Buffer{}
+	err = PrintTree(&buf, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{a:i1,b\\::\"x\\,y\\},c:[b1,\"\\]]}"
+	if buf.String() != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, buf.String())
+	}
+
+	m, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["b:"] != "x,y}" {
+		t.Error("expected 'b:' to be 'x,y}' but got", m["b:"])
+	}
+}
+
+func TestParseTree_Comments(t *testing.T) {
+	input := "1{\n  # the answer\n  a:i42, # inline\n  b:[\n    i1,\n    # end of list\n  ],\n}"
+
+	root, err := ParseTree(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments := root.Children[0].Comments()
+	if len(comments) != 1 || comments[0] != " the answer" {
+		t.Error("expected [' the answer'] but got", comments)
+	}
+
+	comments = root.Children[1].Comments()
+	if len(comments) != 1 || comments[0] != " inline" {
+		t.Error("expected [' inline'] but got", comments)
+	}
+
+	comments = root.Children[1].TrailingComments()
+	if len(comments) != 1 || comments[0] != " end of list" {
+		t.Error("expected [' end of list'] but got", comments)
+	}
+
+	buf := bytes.Buffer{}
+	err = PrintTree(&buf, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != input {
+		t.Errorf("expected '%v' but got '%v'", input, buf.String())
+	}
+}
+
+func TestPrintTree_NewComment(t *testing.T) {
+	root, err := ParseTree(strings.NewReader("1{a:i1}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root.Children = append(root.Children, &Node{Leading: "\n# added\n", Key: "b", Type: Bool, Value: "1"})
+
+	buf := bytes.Buffer{}
+	err = PrintTree(&buf, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "1{a:i1,\n# added\nb:b1}"
+	if buf.String() != expected {
+		t.Errorf("expected '%v' but got '%v'", expected, buf.String())
+	}
+}
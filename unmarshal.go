@@ -22,6 +22,9 @@ func Unmarshal(data []byte, v any) error {
 
 	versionByte := b[0]
 	if versionByte == '1' {
+		if u, ok := v.(Unmarshaler); ok {
+			return u.UnmarshalCereal(data[1:])
+		}
 		return unmarshalV1(reader, v)
 	}
 
@@ -29,36 +32,39 @@ func Unmarshal(data []byte, v any) error {
 }
 
 func unmarshalV1(reader io.Reader, v any) error {
-	b := make([]byte, 1)
-	n, err := reader.Read(b)
-	if err != nil && err != io.EOF {
+	t := newTokenizerV1(reader)
+	_, err := t.Next()
+	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return errors.New("<root>: unexpected end of input")
-	}
-
-	if b[0] != '{' {
-		return errors.New("<root>: expected '{'")
-	}
 
 	value := reflect.ValueOf(v)
 	if value.Kind() != reflect.Pointer || value.IsNil() {
 		return fmt.Errorf("Cannot unmarshal to non-pointer variable")
 	}
 
+	if m, ok := v.(*OrderedMap); ok {
+		result, err := decodeOrderedMapV1(t, []string{"<root>"})
+		if err != nil {
+			return err
+		}
+
+		*m = *result
+		return nil
+	}
+
 	elem := value.Elem()
 	k := elem.Kind()
 	switch k {
 	case reflect.Map:
-		m, err := parseMapV1(reader, []string{"<root>"})
+		m, err := decodeMapV1(t, []string{"<root>"})
 		if err != nil {
 			return err
 		}
 
 		elem.Set(reflect.ValueOf(m))
 	case reflect.Struct:
-		err := parseStructV1(reader, elem, []string{"<root>"})
+		err := decodeStructV1(t, elem, []string{"<root>"})
 		if err != nil {
 			return err
 		}
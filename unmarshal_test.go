@@ -2,6 +2,7 @@ package cereal
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
 )
 
@@ -172,6 +173,58 @@ func TestUnmarshalV1_NestedMapError(t *testing.T) {
 	}
 }
 
+func TestUnmarshalV1_TypedMap(t *testing.T) {
+	type Point struct {
+		X int
+	}
+	type Struct struct {
+		Labels map[string]string
+		Points map[string]Point
+		Nested map[string]map[string][]int
+		Counts []map[string]int
+	}
+	s := Struct{}
+	err := unmarshalV1(bytes.NewBufferString(`{Labels:{a:"x,b:n},Points:{p:{X:i1}},Nested:{n:{m:[i1,i2]}},Counts:[{c:i3}]}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Struct{
+		Labels: map[string]string{"a": "x"},
+		Points: map[string]Point{"p": {X: 1}},
+		Nested: map[string]map[string][]int{"n": {"m": {1, 2}}},
+		Counts: []map[string]int{{"c": 3}},
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("Expected %+v but got %+v", expected, s)
+	}
+}
+
+func TestUnmarshalV1_TypedMapInvalid(t *testing.T) {
+	type Struct struct {
+		Labels map[string]string
+		Keys   map[int]string
+		Counts []map[string]int
+	}
+	inputs := map[string]string{
+		`{Labels:{a:i1}}`:   "<root>.Labels: type int cannot be inserted into map of type map[string]string",
+		`{Labels:{a:[]}}`:   "<root>.Labels: an array cannot be inserted into map of type map[string]string",
+		`{Labels:{a:{}}}`:   "<root>.Labels: a struct or map cannot be inserted into map of type map[string]string",
+		`{Keys:{a:"x}}`:     "<root>.Keys: cannot decode a map into type map[int]string",
+		`{Counts:[{c:"x}]}`: "<root>.Counts.0: type string cannot be inserted into map of type map[string]int",
+	}
+
+	for input, expected := range inputs {
+		s := Struct{}
+		err := unmarshalV1(bytes.NewBufferString(input), &s)
+		if err == nil {
+			t.Errorf("Expected an error for %v", input)
+		} else if err.Error() != expected {
+			t.Errorf("Expected error %q for %v but got %q", expected, input, err.Error())
+		}
+	}
+}
+
 func TestUnmarshalV1_NestedStructArray(t *testing.T) {
 	type InnerStruct struct {
 		B bool
@@ -487,3 +540,71 @@ func TestUnmarshalV1_StructEndOfInput(t *testing.T) {
 		t.Errorf("Unexpected error message: %v", err.Error())
 	}
 }
+
+func TestUnmarshalV1_Null(t *testing.T) {
+	type Struct struct {
+		A string
+		B []int
+	}
+
+	s := Struct{A: "unchanged"}
+	err := Unmarshal([]byte("1{A:n,B:[i1]}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.A != "unchanged" {
+		t.Error("expected a null to leave the field unchanged but got", s.A)
+	}
+
+	err = Unmarshal([]byte("1{B:[n]}"), &s)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	msg := err.Error()
+	if msg != "<root>.B: a null cannot be inserted into slice of type []int" {
+		t.Error("expected error to be '<root>.B: a null cannot be inserted into slice of type []int' but got", msg)
+	}
+}
+
+func TestUnmarshalV1_Tags(t *testing.T) {
+	type Struct struct {
+		Name    string `cereal:"name"`
+		Port    int    `cereal:"port"`
+		Ignored string `cereal:"-"`
+		Other   bool
+	}
+
+	var s Struct
+	err := Unmarshal([]byte("1{name:\"svc,port:i80,Other:b1}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "svc" || s.Port != 80 || !s.Other {
+		t.Errorf("expected the tagged fields to be set but got %+v", s)
+	}
+
+	err = Unmarshal([]byte("1{Ignored:\"x}"), &s)
+	if err == nil || err.Error() != "<root>: unexpected field name 'Ignored'" {
+		t.Error("expected an ignored field to be unexpected but got", err)
+	}
+}
+
+func TestUnmarshalV1_AnyFields(t *testing.T) {
+	type Struct struct {
+		Value any
+		List  []any
+	}
+
+	var s Struct
+	err := Unmarshal([]byte("1{Value:{a:i1},List:[i1,\"x]}"), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m, ok := s.Value.(map[string]any); !ok || m["a"] != 1 {
+		t.Error("expected Value to hold a map but got", s.Value)
+	}
+	if len(s.List) != 2 || s.List[0] != 1 || s.List[1] != "x" {
+		t.Error("expected List to hold mixed values but got", s.List)
+	}
+}
@@ -0,0 +1,36 @@
+package cereal
+
+import (
+	"bytes"
+	"io"
+)
+
+// Valid reports whether data is a well-formed document.
+func Valid(data []byte) bool {
+	return Validate(bytes.NewReader(data)) == nil
+}
+
+// Validate reads a document from the provided io.Reader and checks that it is well-formed
+// without building its values. It returns the same errors that Parse would. Unlike Parse, it
+// reads the whole input and reports an error if anything but whitespace and comments follows
+// the root map.
+func Validate(reader io.Reader) error {
+	t := newBufferedTokenizer(reader)
+	t.ReadToEnd()
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if tok.Kind == Scalar {
+			_, err = parseValue(tok.Value, tok.Type, t.Path())
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
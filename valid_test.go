@@ -0,0 +1,73 @@
+package cereal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	inputs := []string{
+		"1{}",
+		"1{key:\"value,num:i42,flag:b1}",
+		"1{a:[d1.5,f2,{b:[]}],c:{}}",
+		"1{a:i1}\n",
+		"1{a:i1} # done\n\n# end of file",
+	}
+
+	for _, input := range inputs {
+		if !Valid([]byte(input)) {
+			t.Errorf("expected '%v' to be valid", input)
+		}
+	}
+}
+
+func TestValid_Invalid(t *testing.T) {
+	inputs := []string{
+		"",
+		"2{}",
+		"1[]",
+		"1{a:i1",
+		"1{a:[b2]}",
+		"1{a:x1}",
+		"1{a:i1}garbage",
+		"1{a:i1}\nGARBAGE\n",
+		"1{a:i1}1{b:i2}",
+		"1{a:i1}}",
+	}
+
+	for _, input := range inputs {
+		if Valid([]byte(input)) {
+			t.Errorf("expected '%v' to be invalid", input)
+		}
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	inputs := []string{
+		"",
+		"1",
+		"1{a:{b:[i1,ix]}}",
+		"1{a:{b:X1}}",
+		"1{a:[f1,d2",
+	}
+
+	for _, input := range inputs {
+		err := Validate(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected '%v' to be invalid", input)
+			continue
+		}
+
+		_, parseErr := Parse(strings.NewReader(input))
+		if parseErr == nil || err.Error() != parseErr.Error() {
+			t.Errorf("expected error for '%v' to be '%v' but got '%v'", input, parseErr, err)
+		}
+	}
+}
+
+func TestValidate_TrailingData(t *testing.T) {
+	err := Validate(strings.NewReader("1{a:i1} # end\nGARBAGE"))
+	if err == nil || err.Error() != "unexpected data after the root map" {
+		t.Error("expected error to be 'unexpected data after the root map' but got", err)
+	}
+}
@@ -0,0 +1,1093 @@
+package cereal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ConvertYAML reads a YAML document from the provided io.Reader and returns it as a
+// serialized document. Plain scalars are resolved as in the YAML 1.2 core schema, so integers
+// become int values, or are tagged as in {$int64:"5000000000} outside the 32 bit range of an
+// int, numbers with a fraction or an exponent become float64 values and null, ~ and empty
+// values become nulls.
+// The tags !!str, !!int, !!float, !!bool, !!null, !!timestamp and !!binary override the
+// resolution. Timestamps become strings in RFC 3339 format.
+//
+// A map must have string keys, but a map whose keys are all of one other type, such as
+// integers, has them written as strings. A root value other than a map is stored under the
+// key "value", as ConvertJSON does by default, and an empty document is an empty map.
+// Anchors, aliases, complex keys, other tags and streams of several documents cannot be
+// represented and are an error.
+func ConvertYAML(reader io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newYAMLParser(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseBlock(-1)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %v: unexpected content", p.lines[p.pos].number)
+	}
+
+	doc, ok := value.(*OrderedMap)
+	if !ok {
+		doc = NewOrderedMap()
+		if value != nil {
+			doc.Set("value", value)
+		}
+	}
+
+	return serializeParsed(doc)
+}
+
+// serializeParsed serializes a document decoded from another format, in which a null is nil.
+func serializeParsed(doc *OrderedMap) ([]byte, error) {
+	converted, err := convertJSONValue(doc, nil, []string{"<root>"}, JSONOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return Serialize(converted, "1")
+}
+
+// yamlLine is a line of a YAML document without its indentation.
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func newYAMLParser(data string) (*yamlParser, error) {
+	p := &yamlParser{}
+	started := false
+	for i, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		number := i + 1
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+
+		if indent == 0 {
+			switch {
+			case strings.HasPrefix(text, "%"):
+				if !strings.HasPrefix(text, "%YAML") {
+					return nil, fmt.Errorf("line %v: directives other than %%YAML are not supported", number)
+				}
+				continue
+			case text == "---" || strings.HasPrefix(text, "--- "):
+				if started || len(p.lines) > 0 && !p.blank() {
+					return nil, fmt.Errorf("line %v: streams of several documents are not supported", number)
+				}
+				started = true
+				text = strings.TrimSpace(strings.TrimPrefix(text, "---"))
+				if text == "" || text[0] == '#' {
+					continue
+				}
+			case text == "..." || strings.HasPrefix(text, "... "):
+				return p, p.checkEnd(i+1, strings.Split(data, "\n"))
+			}
+		}
+
+		if strings.HasPrefix(text, "\t") && strings.TrimSpace(text) != "" {
+			return nil, fmt.Errorf("line %v: tabs cannot be used for indentation", number)
+		}
+
+		p.lines = append(p.lines, yamlLine{number: number, indent: indent, text: strings.TrimRight(text, " \t")})
+	}
+
+	return p, nil
+}
+
+// blank reports whether every line read so far is blank.
+func (p *yamlParser) blank() bool {
+	for _, line := range p.lines {
+		if !isBlankYAML(line.text) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkEnd makes sure that nothing but comments follows the end of the document.
+func (p *yamlParser) checkEnd(start int, lines []string) error {
+	for i := start; i < len(lines); i++ {
+		if !isBlankYAML(strings.TrimSpace(lines[i])) {
+			return fmt.Errorf("line %v: streams of several documents are not supported", i+1)
+		}
+	}
+	return nil
+}
+
+func isBlankYAML(text string) bool {
+	return text == "" || text[0] == '#'
+}
+
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && isBlankYAML(p.lines[p.pos].text) {
+		p.pos++
+	}
+}
+
+// parseBlock parses the node on the following lines that are indented by more than
+// parentIndent, returning nil if there is none.
+func (p *yamlParser) parseBlock(parentIndent int) (any, error) {
+	p.skipBlank()
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= parentIndent {
+		return nil, nil
+	}
+
+	line := p.lines[p.pos]
+	if isYAMLSequenceEntry(line.text) {
+		return p.parseSequence(line.indent)
+	}
+	if line.text == "?" || strings.HasPrefix(line.text, "? ") {
+		return nil, fmt.Errorf("line %v: complex keys are not supported", line.number)
+	}
+	if _, _, ok := splitYAMLEntry(line.text); ok {
+		return p.parseMapping(line.indent)
+	}
+
+	p.pos++
+	return p.parseValue(line.text, parentIndent, line.number)
+}
+
+func isYAMLSequenceEntry(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// yamlEntry is an entry of a map whose key has been resolved but not yet checked.
+type yamlEntry struct {
+	key    any
+	value  any
+	number int
+}
+
+func (p *yamlParser) parseMapping(indent int) (any, error) {
+	entries := []yamlEntry{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.lines[p.pos].indent < indent {
+			break
+		}
+
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %v: unexpected indentation", line.number)
+		}
+		if line.text == "?" || strings.HasPrefix(line.text, "? ") {
+			return nil, fmt.Errorf("line %v: complex keys are not supported", line.number)
+		}
+
+		keyText, rest, ok := splitYAMLEntry(line.text)
+		if !ok {
+			if isYAMLSequenceEntry(line.text) {
+				break
+			}
+			return nil, fmt.Errorf("line %v: expected a map entry", line.number)
+		}
+
+		key, err := parseYAMLKey(keyText, line.number)
+		if err != nil {
+			return nil, err
+		}
+		p.pos++
+
+		var value any
+		if strings.TrimSpace(stripYAMLComment(rest)) == "" {
+			// a sequence may be indented as much as its key
+			p.skipBlank()
+			if p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceEntry(p.lines[p.pos].text) {
+				value, err = p.parseSequence(indent)
+			} else {
+				value, err = p.parseBlock(indent)
+			}
+		} else {
+			value, err = p.parseValue(rest, indent, line.number)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, yamlEntry{key: key, value: value, number: line.number})
+	}
+
+	return buildYAMLMap(entries)
+}
+
+func (p *yamlParser) parseSequence(indent int) (any, error) {
+	result := []any{}
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) || p.lines[p.pos].indent < indent {
+			break
+		}
+
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %v: unexpected indentation", line.number)
+		}
+		if !isYAMLSequenceEntry(line.text) {
+			break
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if isBlankYAML(rest) {
+			p.pos++
+		} else {
+			// the content of the entry is parsed as if it started its own line
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + len(line.text) - len(rest), text: rest}
+		}
+
+		value, err := p.parseBlock(indent)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// parseValue parses the value that starts with text, which may continue on the following
+// lines that are indented by more than parentIndent.
+func (p *yamlParser) parseValue(text string, parentIndent int, number int) (any, error) {
+	text = strings.TrimSpace(text)
+
+	tag := ""
+	if strings.HasPrefix(text, "!") {
+		tag, text, _ = strings.Cut(text, " ")
+		text = strings.TrimSpace(text)
+	}
+	if strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") {
+		return nil, fmt.Errorf("line %v: anchors and aliases are not supported", number)
+	}
+	if tag != "" && !yamlTags[tag] {
+		return nil, fmt.Errorf("line %v: unsupported tag '%v'", number, tag)
+	}
+
+	switch {
+	case isBlankYAML(text):
+		value, err := p.parseBlock(parentIndent)
+		if err != nil {
+			return nil, err
+		}
+
+		switch value.(type) {
+		case *OrderedMap, []any:
+			if tag != "" && tag != "!!map" && tag != "!!seq" {
+				return nil, fmt.Errorf("line %v: tag '%v' cannot be applied to a collection", number, tag)
+			}
+		case nil:
+			if tag != "" {
+				return resolveYAMLScalar("", tag, false, number)
+			}
+		}
+		return value, nil
+	case text[0] == '|' || text[0] == '>':
+		s, err := p.parseBlockScalar(text, parentIndent, number)
+		if err != nil {
+			return nil, err
+		}
+		return resolveYAMLScalar(s, tag, false, number)
+	case text[0] == '[' || text[0] == '{':
+		for !flowBalanced(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(stripYAMLComment(p.lines[p.pos].text))
+			p.pos++
+		}
+
+		f := &yamlFlow{s: text, number: number}
+		value, err := f.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] != '#' {
+			return nil, fmt.Errorf("line %v: unexpected '%v' after a flow collection", number, f.s[f.i:])
+		}
+		return value, nil
+	case text[0] == '"' || text[0] == '\'':
+		for !quoteClosed(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.lines[p.pos].text)
+			p.pos++
+		}
+
+		f := &yamlFlow{s: text, number: number}
+		s, err := f.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] != '#' {
+			return nil, fmt.Errorf("line %v: unexpected '%v' after a quoted string", number, f.s[f.i:])
+		}
+		return resolveYAMLScalar(s, tag, false, number)
+	}
+
+	// a plain scalar continues on more indented lines
+	text = strings.TrimSpace(stripYAMLComment(text))
+	if strings.Contains(text, ": ") || strings.Contains(text, ":\t") || strings.HasSuffix(text, ":") {
+		return nil, fmt.Errorf("line %v: a plain scalar cannot contain ': '", number)
+	}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > parentIndent && !isBlankYAML(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		if _, _, ok := splitYAMLEntry(line.text); ok {
+			return nil, fmt.Errorf("line %v: unexpected indentation", line.number)
+		}
+
+		text += " " + strings.TrimSpace(stripYAMLComment(line.text))
+		p.pos++
+	}
+
+	return resolveYAMLScalar(text, tag, true, number)
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) scalar along with its content lines.
+func (p *yamlParser) parseBlockScalar(header string, parentIndent int, number int) (string, error) {
+	header = strings.TrimSpace(stripYAMLComment(header))
+	folded := header[0] == '>'
+	chomp := byte(0)
+	contentIndent := -1
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			contentIndent = max(parentIndent, 0) + int(c-'0')
+		default:
+			return "", fmt.Errorf("line %v: invalid block scalar header '%v'", number, header)
+		}
+	}
+
+	lines := []string{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.text == "" {
+			lines = append(lines, "")
+			p.pos++
+			continue
+		}
+		if contentIndent < 0 {
+			if line.indent <= parentIndent {
+				break
+			}
+			contentIndent = line.indent
+		}
+		if line.indent < contentIndent {
+			break
+		}
+
+		lines = append(lines, strings.Repeat(" ", line.indent-contentIndent)+line.text)
+		p.pos++
+	}
+
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	b := strings.Builder{}
+	if folded {
+		breaks := 0
+		normal := false
+		for i, line := range lines {
+			if line == "" {
+				breaks++
+				continue
+			}
+
+			moreIndented := line[0] == ' '
+			switch {
+			case i == 0:
+			case breaks > 0 && normal && !moreIndented:
+				b.WriteString(strings.Repeat("\n", breaks))
+			case breaks > 0:
+				b.WriteString(strings.Repeat("\n", breaks+1))
+			case normal && !moreIndented:
+				b.WriteByte(' ')
+			default:
+				b.WriteByte('\n')
+			}
+			if i == 0 {
+				b.WriteString(strings.Repeat("\n", breaks))
+			}
+
+			b.WriteString(line)
+			breaks = 0
+			normal = !moreIndented
+		}
+	} else {
+		b.WriteString(strings.Join(lines, "\n"))
+	}
+
+	if len(lines) > 0 {
+		switch chomp {
+		case 0:
+			b.WriteByte('\n')
+		case '+':
+			b.WriteString(strings.Repeat("\n", trailing+1))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// splitYAMLEntry splits a line at the colon that ends the key of a map entry.
+func splitYAMLEntry(text string) (string, string, bool) {
+	if text == "" || strings.ContainsRune("[{#&*!|>%@`", rune(text[0])) {
+		return "", "", false
+	}
+
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{s: text}
+		_, err := f.parseQuoted()
+		if err != nil {
+			return "", "", false
+		}
+		start = f.i
+	}
+
+	for i := start; i < len(text); i++ {
+		if text[i] == '#' && i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+			return "", "", false
+		}
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			key := strings.TrimSpace(text[:i])
+			if key == "" || (start > 0 && key != strings.TrimSpace(text[:start])) {
+				return "", "", false
+			}
+			return key, text[i+1:], true
+		}
+	}
+
+	return "", "", false
+}
+
+// parseYAMLKey resolves the key of a block map entry.
+func parseYAMLKey(text string, number int) (any, error) {
+	if strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") {
+		return nil, fmt.Errorf("line %v: anchors and aliases are not supported", number)
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		f := &yamlFlow{s: text, number: number}
+		return f.parseQuoted()
+	}
+
+	return resolveYAMLScalar(text, "", true, number)
+}
+
+// buildYAMLMap checks the keys of a map and turns them into strings.
+func buildYAMLMap(entries []yamlEntry) (*OrderedMap, error) {
+	keyType := ""
+	for _, e := range entries {
+		t := "string"
+		switch e.key.(type) {
+		case int:
+			t = "int"
+		case float64:
+			t = "float"
+		case bool:
+			t = "bool"
+		case nil:
+			t = "null"
+		}
+
+		if keyType == "" {
+			keyType = t
+		} else if t != keyType {
+			return nil, fmt.Errorf("line %v: a map cannot mix keys of type %v and %v", e.number, keyType, t)
+		}
+	}
+
+	m := NewOrderedMap()
+	for _, e := range entries {
+		key := formatYAMLKey(e.key)
+		if _, ok := m.Get(key); ok {
+			return nil, fmt.Errorf("line %v: duplicate key '%v'", e.number, key)
+		}
+		m.Set(key, e.value)
+	}
+
+	return m, nil
+}
+
+func formatYAMLKey(key any) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case nil:
+		return "null"
+	case float64:
+		return formatYAMLFloat(k, 64)
+	}
+
+	return fmt.Sprint(key)
+}
+
+// stripYAMLComment removes a comment that starts outside of quotes.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			if quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++
+			} else {
+				quote = 0
+			}
+		case quote == 0 && (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" [{,:", rune(text[i-1]))):
+			quote = c
+		case quote == 0 && c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+
+	return text
+}
+
+// flowBalanced reports whether every bracket opened outside of quotes has been closed.
+func flowBalanced(text string) bool {
+	text = stripYAMLComment(text)
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+
+	return depth <= 0
+}
+
+// quoteClosed reports whether the quoted string that text starts with is closed.
+func quoteClosed(text string) bool {
+	f := &yamlFlow{s: text}
+	_, err := f.parseQuoted()
+	return err == nil
+}
+
+// yamlFlow parses a flow collection or a quoted string.
+type yamlFlow struct {
+	s      string
+	i      int
+	number int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.s) && (f.s[f.i] == ' ' || f.s[f.i] == '\t') {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %v: %v", f.number, fmt.Sprintf(format, args...))
+}
+
+func (f *yamlFlow) parseValue() (any, error) {
+	f.skipSpace()
+	if f.i >= len(f.s) {
+		return nil, f.errorf("unexpected end of a flow collection")
+	}
+
+	tag := ""
+	if f.s[f.i] == '!' {
+		start := f.i
+		for f.i < len(f.s) && f.s[f.i] != ' ' {
+			f.i++
+		}
+		tag = f.s[start:f.i]
+		if !yamlTags[tag] {
+			return nil, f.errorf("unsupported tag '%v'", tag)
+		}
+		f.skipSpace()
+	}
+
+	switch f.s[f.i] {
+	case '&', '*':
+		return nil, f.errorf("anchors and aliases are not supported")
+	case '[':
+		return f.parseSequence()
+	case '{':
+		return f.parseMapping()
+	case '"', '\'':
+		s, err := f.parseQuoted()
+		if err != nil {
+			return nil, err
+		}
+		return resolveYAMLScalar(s, tag, false, f.number)
+	}
+
+	start := f.i
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if c == ',' || c == ']' || c == '}' {
+			break
+		}
+		if c == ':' && (f.i+1 == len(f.s) || strings.ContainsRune(" ,]}", rune(f.s[f.i+1]))) {
+			break
+		}
+		f.i++
+	}
+
+	return resolveYAMLScalar(strings.TrimSpace(f.s[start:f.i]), tag, true, f.number)
+}
+
+func (f *yamlFlow) parseSequence() (any, error) {
+	f.i++
+	result := []any{}
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ']' {
+			f.i++
+			return result, nil
+		}
+
+		value, err := f.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ':' {
+			// a single pair such as [a: b] is a map
+			f.i++
+			pairValue, err := f.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			value, err = buildYAMLMap([]yamlEntry{{key: value, value: pairValue, number: f.number}})
+			if err != nil {
+				return nil, err
+			}
+			f.skipSpace()
+		}
+		result = append(result, value)
+
+		if f.i >= len(f.s) {
+			return nil, f.errorf("unterminated flow sequence")
+		}
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+		case ']':
+		default:
+			return nil, f.errorf("expected ',' or ']' but got '%c'", f.s[f.i])
+		}
+	}
+}
+
+func (f *yamlFlow) parseMapping() (any, error) {
+	f.i++
+	entries := []yamlEntry{}
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == '}' {
+			f.i++
+			return buildYAMLMap(entries)
+		}
+		if f.i < len(f.s) && f.s[f.i] == '?' {
+			return nil, f.errorf("complex keys are not supported")
+		}
+
+		key, err := f.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		switch key.(type) {
+		case *OrderedMap, []any:
+			return nil, f.errorf("complex keys are not supported")
+		}
+
+		var value any
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ':' {
+			f.i++
+			f.skipSpace()
+			if f.i < len(f.s) && f.s[f.i] != ',' && f.s[f.i] != '}' {
+				value, err = f.parseValue()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		entries = append(entries, yamlEntry{key: key, value: value, number: f.number})
+
+		f.skipSpace()
+		if f.i >= len(f.s) {
+			return nil, f.errorf("unterminated flow map")
+		}
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+		case '}':
+		default:
+			return nil, f.errorf("expected ',' or '}' but got '%c'", f.s[f.i])
+		}
+	}
+}
+
+// yamlEscapes maps the escapes of double quoted strings to the characters they stand for.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\", 'N': "\u0085",
+	'_': " ", 'L': " ", 'P': " ",
+}
+
+func (f *yamlFlow) parseQuoted() (string, error) {
+	quote := f.s[f.i]
+	f.i++
+
+	b := strings.Builder{}
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		f.i++
+
+		switch {
+		case c == quote && quote == '\'' && f.i < len(f.s) && f.s[f.i] == '\'':
+			b.WriteByte('\'')
+			f.i++
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if f.i >= len(f.s) {
+				return "", f.errorf("unterminated string")
+			}
+			e := f.s[f.i]
+			f.i++
+
+			if s, ok := yamlEscapes[e]; ok {
+				b.WriteString(s)
+				continue
+			}
+
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+			if size == 0 || f.i+size > len(f.s) {
+				return "", f.errorf("invalid escape '\\%c'", e)
+			}
+			r, err := strconv.ParseUint(f.s[f.i:f.i+size], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", f.errorf("invalid escape '\\%c%v'", e, f.s[f.i:f.i+size])
+			}
+			b.WriteRune(rune(r))
+			f.i += size
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", f.errorf("unterminated string")
+}
+
+// yamlTags are the tags that may be applied to a value.
+var yamlTags = map[string]bool{
+	"!!str":       true,
+	"!!int":       true,
+	"!!float":     true,
+	"!!bool":      true,
+	"!!null":      true,
+	"!!timestamp": true,
+	"!!binary":    true,
+	"!!map":       true,
+	"!!seq":       true,
+}
+
+var (
+	yamlIntPattern       = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloatPattern     = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlDatePattern      = regexp.MustCompile(`^[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}$`)
+	yamlTimestampPattern = regexp.MustCompile(`^([0-9]{4}-[0-9]{1,2}-[0-9]{1,2})(?:[Tt]|[ \t]+)([0-9]{1,2}:[0-9]{2}:[0-9]{2}(?:\.[0-9]*)?)(?:[ \t]*(Z|[-+][0-9]{1,2}(?::[0-9]{2})?))?$`)
+)
+
+// resolveYAMLScalar returns the value of a scalar, resolving the type of a plain scalar
+// without a tag from its text.
+func resolveYAMLScalar(text string, tag string, plain bool, number int) (any, error) {
+	if tag == "" && !plain {
+		return text, nil
+	}
+
+	switch tag {
+	case "!!str", "!!map", "!!seq":
+		return text, nil
+	case "!!binary":
+		return strings.Join(strings.Fields(text), ""), nil
+	}
+
+	if tag == "" || tag == "!!null" {
+		switch text {
+		case "", "~", "null", "Null", "NULL":
+			return nil, nil
+		}
+	}
+	if tag == "" || tag == "!!bool" {
+		switch text {
+		case "true", "True", "TRUE":
+			return true, nil
+		case "false", "False", "FALSE":
+			return false, nil
+		}
+	}
+	if tag == "" || tag == "!!int" || tag == "!!float" {
+		value, ok := parseYAMLNumber(text, tag == "!!float")
+		if ok {
+			return value, nil
+		}
+	}
+	if tag == "" || tag == "!!timestamp" {
+		value, ok := parseYAMLTimestamp(text)
+		if ok {
+			return value, nil
+		}
+	}
+
+	if tag != "" {
+		return nil, fmt.Errorf("line %v: invalid %v '%v'", number, strings.TrimPrefix(tag, "!!"), text)
+	}
+	return text, nil
+}
+
+func parseYAMLNumber(text string, float bool) (any, bool) {
+	var i int64
+	var err error
+	switch {
+	case yamlIntPattern.MatchString(text):
+		i, err = strconv.ParseInt(text, 10, 64)
+	case strings.HasPrefix(text, "0x"):
+		i, err = strconv.ParseInt(text[2:], 16, 64)
+	case strings.HasPrefix(text, "0o"):
+		i, err = strconv.ParseInt(text[2:], 8, 64)
+	default:
+		switch strings.TrimLeft(text, "+-") {
+		case ".inf", ".Inf", ".INF":
+			if text[0] == '-' {
+				return math.Inf(-1), true
+			}
+			return math.Inf(1), true
+		case ".nan", ".NaN", ".NAN":
+			return math.NaN(), text[0] == '.'
+		}
+
+		if !yamlFloatPattern.MatchString(text) {
+			return nil, false
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		return f, err == nil
+	}
+
+	if err != nil {
+		// integers too large even for 64 bits are read as floats
+		f, err := strconv.ParseFloat(text, 64)
+		return f, err == nil && !strings.HasPrefix(text, "0x") && !strings.HasPrefix(text, "0o")
+	}
+	if float {
+		return float64(i), true
+	}
+	return wideInt(i), true
+}
+
+// parseYAMLTimestamp returns a timestamp in RFC 3339 format, or a date alone as YYYY-MM-DD.
+func parseYAMLTimestamp(text string) (string, bool) {
+	if yamlDatePattern.MatchString(text) {
+		t, err := time.Parse("2006-1-2", text)
+		return t.Format(time.DateOnly), err == nil
+	}
+
+	m := yamlTimestampPattern.FindStringSubmatch(text)
+	if m == nil {
+		return "", false
+	}
+
+	zone := m[3]
+	switch {
+	case zone == "":
+		zone = "Z"
+	case zone != "Z":
+		hours, minutes, _ := strings.Cut(zone[1:], ":")
+		if minutes == "" {
+			minutes = "00"
+		}
+		zone = fmt.Sprintf("%c%02v:%v", zone[0], hours, minutes)
+	}
+
+	t, err := time.Parse("2006-1-2T15:4:5.999999999Z07:00", m[1]+"T"+m[2]+zone)
+	if err != nil {
+		return "", false
+	}
+	return t.Format(time.RFC3339Nano), true
+}
+
+// MarshalYAML returns a document, such as the result of ParseOrdered, as a YAML document in
+// block style. Strings are quoted whenever they would otherwise be read as another type, and
+// floats always have a fraction or an exponent, so ConvertYAML reads the result back with the
+// same types, except that float32 values are read back as float64 values. Integers tagged as
+// ConvertYAML does are written as integers.
+func MarshalYAML(doc any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	err := writeYAML(&buf, doc, 0, []string{"<root>"})
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeYAML writes a value followed by a newline. A non-empty map or array starts on the
+// current line and continues on lines indented by indent.
+func writeYAML(buf *bytes.Buffer, value any, indent int, path []string) error {
+	valueType, ok := valueTypeOf(value)
+	if !ok {
+		return fmt.Errorf("%v: unsupported value of type %T", strings.Join(path, "."), value)
+	}
+
+	if i, ok := taggedInt64(value); ok {
+		buf.WriteString(strconv.FormatInt(i, 10))
+		buf.WriteByte('\n')
+		return nil
+	}
+
+	children := queryChildren(value)
+	if (valueType != Map && valueType != Array) || len(children) == 0 {
+		s, err := formatYAMLScalar(value, valueType)
+		if err != nil {
+			return fmt.Errorf("%v: %w", strings.Join(path, "."), err)
+		}
+		buf.WriteString(s)
+		buf.WriteByte('\n')
+		return nil
+	}
+
+	for i, c := range children {
+		if i > 0 {
+			buf.WriteString(strings.Repeat(" ", indent))
+		}
+
+		childType, _ := valueTypeOf(c.value)
+		nested := (childType == Map || childType == Array) && len(queryChildren(c.value)) > 0
+
+		if valueType == Array {
+			buf.WriteByte('-')
+			if nested && childType == Array {
+				buf.WriteString("\n" + strings.Repeat(" ", indent+2))
+			} else {
+				buf.WriteByte(' ')
+			}
+		} else {
+			buf.WriteString(quoteYAMLString(c.key))
+			buf.WriteByte(':')
+			if nested {
+				buf.WriteString("\n" + strings.Repeat(" ", indent+2))
+			} else {
+				buf.WriteByte(' ')
+			}
+		}
+
+		err := writeYAML(buf, c.value, indent+2, appendPath(path, c.key))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func formatYAMLScalar(value any, valueType ValueType) (string, error) {
+	if _, ok := value.(RawValue); ok && valueType != Null {
+		return "", fmt.Errorf("unsupported value %v", value)
+	}
+
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	switch valueType {
+	case Null:
+		return "null", nil
+	case Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case Int:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case Float32:
+		return formatYAMLFloat(rv.Float(), 32), nil
+	case Float64:
+		return formatYAMLFloat(rv.Float(), 64), nil
+	case String:
+		return quoteYAMLString(rv.String()), nil
+	case Map:
+		return "{}", nil
+	case Array:
+		return "[]", nil
+	}
+
+	return "", fmt.Errorf("unsupported value %v", value)
+}
+
+func formatYAMLFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// quoteYAMLString returns a string as a plain scalar if it would be read back unchanged, and
+// as a double quoted string otherwise.
+func quoteYAMLString(s string) string {
+	plain := s != "" && s == strings.TrimSpace(s) &&
+		!strings.ContainsRune("-?:,[]{}#&*!|>'\"%@`", rune(s[0])) &&
+		!strings.Contains(s, ": ") && !strings.Contains(s, " #") && !strings.HasSuffix(s, ":") &&
+		!strings.ContainsFunc(s, func(r rune) bool { return r < ' ' || r == 0x7f || r > 0x7e })
+	if plain {
+		value, err := resolveYAMLScalar(s, "", true, 0)
+		plain = err == nil && value == s
+	}
+	if plain {
+		return s
+	}
+
+	return strconv.Quote(s)
+}
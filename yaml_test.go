@@ -0,0 +1,143 @@
+package cereal
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestConvertYAML(t *testing.T) {
+	tests := map[string]string{
+		"a: 1\nb: 2.5\nc: hello world\nd: ~\ne: true\n":                    `1{a:i1,b:d2.5,c:"hello world,d:n,e:b1}`,
+		"big: 9223372036854775807\nmin: -9223372036854775808\n":            `1{big:{$int64:"9223372036854775807},min:{$int64:"-9223372036854775808}}`,
+		"hex: 0x1F\noct: 0o17\nbig: 3000000000\nexp: 1e3\n":                `1{hex:i31,oct:i15,big:{$int64:"3000000000},exp:d1000}`,
+		"inf: -.inf\nempty:\nquoted: \"42\"\nsingle: 'it''s'\n":            `1{inf:d-Inf,empty:n,quoted:"42,single:"it's}`,
+		"list:\n- a\n- b\nnested:\n  x: 1\n  y:\n    - z: 2\n      w: 3\n": `1{list:["a,"b],nested:{x:i1,y:[{z:i2,w:i3}]}}`,
+		"# comment\n---\nflow: [1, \"x\", {a: b, c: []}] # end\n":          `1{flow:[i1,"x,{a:"b,c:[]}]}`,
+		"lit: |\n  one\n  two\nfold: >-\n  a\n  b\n\n  c\nnext: 1\n":       `1{lit:"one\ntwo\n,fold:"a b\nc,next:i1}`,
+		"key: this is\n  continued # comment\n":                            `1{key:"this is continued}`,
+		"ints:\n  1: one\n  2: two\n":                                      `1{ints:{1:"one,2:"two}}`,
+		"- 1\n- 2\n":                                                       `1{value:[i1,i2]}`,
+		"":                                                                 `1{}`,
+	}
+
+	for input, expected := range tests {
+		result, err := ConvertYAML(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", input, err)
+			continue
+		}
+		if string(result) != expected {
+			t.Errorf("expected %v for %q but got %v", expected, input, string(result))
+		}
+	}
+}
+
+func TestConvertYAML_Tags(t *testing.T) {
+	input := `
+str: !!str 123
+float: !!float 1
+int: !!int "7"
+nothing: !!null ""
+ts: 2001-12-14t21:59:43.10-05:00
+spaced: 2001-12-14 21:59:43.10 -5
+date: 2002-12-14
+`
+
+	result, err := ConvertYAML(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `1{str:"123,float:d1,int:i7,nothing:n,ts:"2001-12-14T21:59:43.1-05:00,spaced:"2001-12-14T21:59:43.1-05:00,date:"2002-12-14}`
+	if string(result) != expected {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
+
+func TestConvertYAML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"a: &x 1\nb: *x\n":       "line 1: anchors and aliases are not supported",
+		"a:\n  <<: *base\n":      "line 2: anchors and aliases are not supported",
+		"a:\n  1: one\n  b: 2\n": "line 3: a map cannot mix keys of type int and string",
+		"a: {1: x, y: z}\n":      "line 1: a map cannot mix keys of type int and string",
+		"? complex\n: key\n":     "line 1: complex keys are not supported",
+		"a: !custom 1\n":         "line 1: unsupported tag '!custom'",
+		"a: !!int x\n":           "line 1: invalid int 'x'",
+		"a: 1\n---\nb: 2\n":      "line 2: streams of several documents are not supported",
+		"a: 1\na: 2\n":           "line 2: duplicate key 'a'",
+		"a: [1, 2\n":             "line 1: unterminated flow sequence",
+		"a: 1\n  b: 2\n":         "line 2: unexpected indentation",
+		"a: b: c\n":              "line 1: a plain scalar cannot contain ': '",
+		"- a: b:\n":              "line 1: a plain scalar cannot contain ': '",
+	}
+
+	for input, expected := range tests {
+		_, err := ConvertYAML(strings.NewReader(input))
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected error %v for %q but got %v", expected, input, err)
+		}
+	}
+}
+
+func TestMarshalYAML(t *testing.T) {
+	doc := NewOrderedMap()
+	doc.Set("name", "app")
+	doc.Set("port", 8080)
+	doc.Set("ratio", 2.0)
+	doc.Set("ok", true)
+	doc.Set("none", nil)
+	doc.Set("number", "42")
+	doc.Set("text", "a: b\nc")
+	doc.Set("list", []any{1, []any{"x"}, map[string]any{"k": "v", "l": float32(0.5)}})
+	doc.Set("empty", []any{})
+	doc.Set("nan", math.NaN())
+
+	result, err := MarshalYAML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `name: app
+port: 8080
+ratio: 2.0
+ok: true
+none: null
+number: "42"
+text: "a: b\nc"
+list:
+  - 1
+  -
+    - x
+  - k: v
+    l: 0.5
+empty: []
+nan: .nan
+`
+	if string(result) != expected {
+		t.Errorf("unexpected result %v", string(result))
+	}
+}
+
+func TestMarshalYAML_RoundTrip(t *testing.T) {
+	input := `1{a:i1,b:d2.5,c:"x: y,d:n,e:["true,"-1,"",{f:[[]]}],g:{"0:"zero},h:"line\nbreak,i:{$int64:"9223372036854775807}}`
+
+	doc, err := ParseOrdered(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := MarshalYAML(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ConvertYAML(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error for %v: %v", string(data), err)
+	}
+	if string(result) != input {
+		t.Errorf("unexpected result %v from %v", string(result), string(data))
+	}
+}